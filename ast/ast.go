@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
 	"strings"
 
 	"example/sawan/goInterpreter/token"
@@ -81,9 +82,43 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// Marks a top level let binding as part of the module's exports.
+type ExportStatement struct {
+	Token     token.Token
+	Statement *LetStatement
+}
+
+func (es *ExportStatement) statementNode()       {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
+
+// Raises the value of Value as an error, to be caught by a try expression.
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
 type Identifier struct {
 	Token token.Token
 	Value string
+
+	// The annotation of a let name or parameter, nil when there is none.
+	Type TypeExpression
+
+	// Set by the resolver. Index is the slot of local and the cell of free
+	// identifiers. Outer is where to look instead while the binding is not
+	// made yet.
+	Scope SymbolScope
+	Index int
+	Outer *Identifier
 }
 
 // Currently does nothing specific.
@@ -137,18 +172,34 @@ func (es *ExpressionStatement) String() string {
 }
 
 // just returns the value of the identifier
-func (i *Identifier) String() string { return i.Value }
+func (i *Identifier) String() string {
+	if i.Type != nil {
+		return i.Value + ": " + i.Type.String()
+	}
+	return i.Value
+}
 
-// Basic Integer Type to hold integer values
+// Basic Integer Type to hold integer values. Literals too large for an int64
+// keep their value in Big instead.
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -229,9 +280,39 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// Evaluates Block, and Catch with the error bound to Param when Block raises
+// one. Finally runs last in any case. Catch or Finally can be left out, but
+// not both.
+type TryExpression struct {
+	Token   token.Token
+	Block   *BlockStatement
+	Param   *Identifier
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (te *TryExpression) expressionNode()      {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+	if te.Catch != nil {
+		out.WriteString(" catch (" + te.Param.String() + ") ")
+		out.WriteString(te.Catch.String())
+	}
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+	return out.String()
+}
+
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+	Rbrace     token.Token // the closing }
 }
 
 func (bs *BlockStatement) statementNode()       {}
@@ -248,7 +329,9 @@ func (bs *BlockStatement) String() string {
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	ReturnType TypeExpression // nil when not annotated
 	Body       *BlockStatement
+	Frame      *FrameLayout // nil until resolved
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -265,15 +348,46 @@ func (fl *FunctionLiteral) String() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(")")
+	if fl.ReturnType != nil {
+		out.WriteString(": " + fl.ReturnType.String())
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// Like a FunctionLiteral, but bound by a top level let it defines a macro
+// that is expanded before the program runs.
+type MacroLiteral struct {
+	Token      token.Token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	out.WriteString(ml.Body.String())
+
+	return out.String()
+}
+
 type CallExpression struct {
 	Token     token.Token
 	Function  Expression
 	Arguments []Expression
+	Rparen    token.Token // the closing )
 }
 
 func (ce *CallExpression) expressionNode()      {}
@@ -305,6 +419,7 @@ func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 type ArrayLiteral struct {
 	Token    token.Token
 	Elements []Expression
+	Rbracket token.Token // the closing ]
 }
 
 func (al *ArrayLiteral) expressionNode()      {}
@@ -344,10 +459,57 @@ func (ie *IndexExpression) String() string {
   return out.String()
 }
 
+// Evaluates to a hash of the bindings exported by the module at Path.
+type ImportExpression struct {
+  Token token.Token
+  Path  *StringLiteral
+}
+
+func (ie *ImportExpression) expressionNode() {}
+func (ie *ImportExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *ImportExpression) String() string {
+  return ie.TokenLiteral() + " \"" + ie.Path.String() + "\""
+}
+
+// Start and End are nil when they are left out, as in arr[:2] or arr[1:].
+type SliceExpression struct {
+  Token token.Token
+  Left  Expression
+  Start Expression
+  End   Expression
+}
+
+func (se *SliceExpression) expressionNode() {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+  var out bytes.Buffer
+
+  out.WriteString("(")
+  out.WriteString(se.Left.String())
+  out.WriteString("[")
+  if se.Start != nil {
+    out.WriteString(se.Start.String())
+  }
+  out.WriteString(":")
+  if se.End != nil {
+    out.WriteString(se.End.String())
+  }
+  out.WriteString("])")
+
+  return out.String()
+}
 
 type HashLiteral struct {
   Token token.Token
-  Pairs map[Expression]Expression
+  // kept in source order so that evaluation, and with it the order of the
+  // resulting hash, is deterministic
+  Pairs []HashPair
+  Rbrace token.Token // the closing }
+}
+
+type HashPair struct {
+  Key   Expression
+  Value Expression
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -356,8 +518,8 @@ func (hl *HashLiteral) String() string {
   var out bytes.Buffer
 
   pairs := []string{}
-  for key, value := range hl.Pairs {
-    pairs = append(pairs, key.String()+":"+value.String())
+  for _, pair := range hl.Pairs {
+    pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
   }
 
   out.WriteString("{")
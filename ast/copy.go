@@ -0,0 +1,239 @@
+package ast
+
+// Returns a deep copy of node, so it can be modified without changing the
+// tree it came from. The frames of resolved functions are shared with the
+// original, as nothing modifies them.
+func Copy(node Node) Node {
+	switch node := node.(type) {
+
+	case *Program:
+		copied := *node
+		copied.Statements = copyStatements(node.Statements)
+		return &copied
+
+	case *ExpressionStatement:
+		copied := *node
+		copied.Expression = copyExpression(node.Expression)
+		return &copied
+
+	case *LetStatement:
+		copied := *node
+		copied.Name = copyIdentifier(node.Name)
+		copied.Value = copyExpression(node.Value)
+		return &copied
+
+	case *ExportStatement:
+		copied := *node
+		if node.Statement != nil {
+			copied.Statement = Copy(node.Statement).(*LetStatement)
+		}
+		return &copied
+
+	case *ReturnStatement:
+		copied := *node
+		copied.ReturnValue = copyExpression(node.ReturnValue)
+		return &copied
+
+	case *ThrowStatement:
+		copied := *node
+		copied.Value = copyExpression(node.Value)
+		return &copied
+
+	case *BlockStatement:
+		return copyBlock(node)
+
+	case *Identifier:
+		return copyIdentifier(node)
+
+	case *IntegerLiteral:
+		copied := *node
+		return &copied
+
+	case *FloatLiteral:
+		copied := *node
+		return &copied
+
+	case *StringLiteral:
+		copied := *node
+		return &copied
+
+	case *Boolean:
+		copied := *node
+		return &copied
+
+	case *PrefixExpression:
+		copied := *node
+		copied.Right = copyExpression(node.Right)
+		return &copied
+
+	case *InfixExpression:
+		copied := *node
+		copied.Left = copyExpression(node.Left)
+		copied.Right = copyExpression(node.Right)
+		return &copied
+
+	case *IfExpression:
+		copied := *node
+		copied.Condition = copyExpression(node.Condition)
+		copied.Consequence = copyBlock(node.Consequence)
+		copied.Alternative = copyBlock(node.Alternative)
+		return &copied
+
+	case *TryExpression:
+		copied := *node
+		copied.Block = copyBlock(node.Block)
+		copied.Param = copyIdentifier(node.Param)
+		copied.Catch = copyBlock(node.Catch)
+		copied.Finally = copyBlock(node.Finally)
+		return &copied
+
+	case *FunctionLiteral:
+		copied := *node
+		copied.Parameters = copyIdentifiers(node.Parameters)
+		copied.ReturnType = copyType(node.ReturnType)
+		copied.Body = copyBlock(node.Body)
+		return &copied
+
+	case *MacroLiteral:
+		copied := *node
+		copied.Parameters = copyIdentifiers(node.Parameters)
+		copied.Body = copyBlock(node.Body)
+		return &copied
+
+	case *CallExpression:
+		copied := *node
+		copied.Function = copyExpression(node.Function)
+		copied.Arguments = copyExpressions(node.Arguments)
+		return &copied
+
+	case *ArrayLiteral:
+		copied := *node
+		copied.Elements = copyExpressions(node.Elements)
+		return &copied
+
+	case *IndexExpression:
+		copied := *node
+		copied.Left = copyExpression(node.Left)
+		copied.Index = copyExpression(node.Index)
+		return &copied
+
+	case *SliceExpression:
+		copied := *node
+		copied.Left = copyExpression(node.Left)
+		copied.Start = copyExpression(node.Start)
+		copied.End = copyExpression(node.End)
+		return &copied
+
+	case *HashLiteral:
+		copied := *node
+		if node.Pairs != nil {
+			copied.Pairs = make([]HashPair, len(node.Pairs))
+			for i, pair := range node.Pairs {
+				copied.Pairs[i] = HashPair{Key: copyExpression(pair.Key), Value: copyExpression(pair.Value)}
+			}
+		}
+		return &copied
+
+	case *ImportExpression:
+		copied := *node
+		if node.Path != nil {
+			copied.Path = Copy(node.Path).(*StringLiteral)
+		}
+		return &copied
+
+	case *NamedType:
+		copied := *node
+		return &copied
+
+	case *ArrayType:
+		copied := *node
+		copied.Element = copyType(node.Element)
+		return &copied
+
+	case *HashType:
+		copied := *node
+		copied.Key = copyType(node.Key)
+		copied.Value = copyType(node.Value)
+		return &copied
+
+	case *FunctionType:
+		copied := *node
+		if node.Parameters != nil {
+			copied.Parameters = make([]TypeExpression, len(node.Parameters))
+			for i, param := range node.Parameters {
+				copied.Parameters[i] = copyType(param)
+			}
+		}
+		copied.Return = copyType(node.Return)
+		return &copied
+	}
+
+	return node
+}
+
+func copyStatements(statements []Statement) []Statement {
+	if statements == nil {
+		return nil
+	}
+	copied := make([]Statement, len(statements))
+	for i, stmt := range statements {
+		if stmt != nil {
+			copied[i] = Copy(stmt).(Statement)
+		}
+	}
+	return copied
+}
+
+func copyExpressions(expressions []Expression) []Expression {
+	if expressions == nil {
+		return nil
+	}
+	copied := make([]Expression, len(expressions))
+	for i, exp := range expressions {
+		copied[i] = copyExpression(exp)
+	}
+	return copied
+}
+
+func copyExpression(exp Expression) Expression {
+	if exp == nil {
+		return nil
+	}
+	return Copy(exp).(Expression)
+}
+
+func copyBlock(block *BlockStatement) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	copied := *block
+	copied.Statements = copyStatements(block.Statements)
+	return &copied
+}
+
+func copyIdentifiers(idents []*Identifier) []*Identifier {
+	if idents == nil {
+		return nil
+	}
+	copied := make([]*Identifier, len(idents))
+	for i, ident := range idents {
+		copied[i] = copyIdentifier(ident)
+	}
+	return copied
+}
+
+func copyIdentifier(ident *Identifier) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	copied := *ident
+	copied.Type = copyType(ident.Type)
+	return &copied
+}
+
+func copyType(t TypeExpression) TypeExpression {
+	if t == nil {
+		return nil
+	}
+	return Copy(t).(TypeExpression)
+}
@@ -0,0 +1,176 @@
+package ast
+
+type ModifierFunc func(Node) Node
+
+// Walks the tree depth first, replacing every node with what modifier
+// returns for it. Children are modified before their parent, so modifier
+// always sees the parent with its children already replaced.
+//
+// A child is only replaced when the result has the type the parent expects
+// in that position. Missing children (nil) are skipped.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+
+	case *Program:
+		for i, statement := range node.Statements {
+			node.Statements[i] = modifyStatement(statement, modifier)
+		}
+
+	case *ExpressionStatement:
+		node.Expression = modifyExpression(node.Expression, modifier)
+
+	case *LetStatement:
+		node.Name = modifyIdentifier(node.Name, modifier)
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *ExportStatement:
+		if node.Statement != nil {
+			if stmt, ok := Modify(node.Statement, modifier).(*LetStatement); ok {
+				node.Statement = stmt
+			}
+		}
+
+	case *ReturnStatement:
+		node.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+
+	case *ThrowStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *BlockStatement:
+		for i, statement := range node.Statements {
+			node.Statements[i] = modifyStatement(statement, modifier)
+		}
+
+	case *Identifier:
+		node.Type = modifyType(node.Type, modifier)
+
+	case *PrefixExpression:
+		node.Right = modifyExpression(node.Right, modifier)
+
+	case *InfixExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Right = modifyExpression(node.Right, modifier)
+
+	case *IfExpression:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Consequence = modifyBlock(node.Consequence, modifier)
+		node.Alternative = modifyBlock(node.Alternative, modifier)
+
+	case *TryExpression:
+		node.Block = modifyBlock(node.Block, modifier)
+		node.Param = modifyIdentifier(node.Param, modifier)
+		node.Catch = modifyBlock(node.Catch, modifier)
+		node.Finally = modifyBlock(node.Finally, modifier)
+
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i] = modifyIdentifier(param, modifier)
+		}
+		node.ReturnType = modifyType(node.ReturnType, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *MacroLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i] = modifyIdentifier(param, modifier)
+		}
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *CallExpression:
+		node.Function = modifyExpression(node.Function, modifier)
+		for i, arg := range node.Arguments {
+			node.Arguments[i] = modifyExpression(arg, modifier)
+		}
+
+	case *ArrayLiteral:
+		for i, el := range node.Elements {
+			node.Elements[i] = modifyExpression(el, modifier)
+		}
+
+	case *IndexExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Index = modifyExpression(node.Index, modifier)
+
+	case *SliceExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Start = modifyExpression(node.Start, modifier)
+		node.End = modifyExpression(node.End, modifier)
+
+	case *HashLiteral:
+		for i, pair := range node.Pairs {
+			node.Pairs[i].Key = modifyExpression(pair.Key, modifier)
+			node.Pairs[i].Value = modifyExpression(pair.Value, modifier)
+		}
+
+	case *ImportExpression:
+		if node.Path != nil {
+			if path, ok := Modify(node.Path, modifier).(*StringLiteral); ok {
+				node.Path = path
+			}
+		}
+
+	case *ArrayType:
+		node.Element = modifyType(node.Element, modifier)
+
+	case *HashType:
+		node.Key = modifyType(node.Key, modifier)
+		node.Value = modifyType(node.Value, modifier)
+
+	case *FunctionType:
+		for i, param := range node.Parameters {
+			node.Parameters[i] = modifyType(param, modifier)
+		}
+		node.Return = modifyType(node.Return, modifier)
+	}
+
+	return modifier(node)
+}
+
+func modifyStatement(stmt Statement, modifier ModifierFunc) Statement {
+	if stmt == nil {
+		return nil
+	}
+	if modified, ok := Modify(stmt, modifier).(Statement); ok {
+		return modified
+	}
+	return stmt
+}
+
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	if modified, ok := Modify(exp, modifier).(Expression); ok {
+		return modified
+	}
+	return exp
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	if modified, ok := Modify(block, modifier).(*BlockStatement); ok {
+		return modified
+	}
+	return block
+}
+
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	if modified, ok := Modify(ident, modifier).(*Identifier); ok {
+		return modified
+	}
+	return ident
+}
+
+func modifyType(t TypeExpression, modifier ModifierFunc) TypeExpression {
+	if t == nil {
+		return nil
+	}
+	if modified, ok := Modify(t, modifier).(TypeExpression); ok {
+		return modified
+	}
+	return t
+}
@@ -0,0 +1,215 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+
+		if integer.Value != 1 {
+			return node
+		}
+
+		integer.Value = 2
+		return integer
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{
+			one(),
+			two(),
+		},
+		{
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: one()},
+				},
+			},
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: two()},
+				},
+			},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&InfixExpression{Left: two(), Operator: "+", Right: one()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&SliceExpression{Left: one(), Start: one()},
+			&SliceExpression{Left: two(), Start: two()},
+		},
+		{
+			&IfExpression{
+				Condition: one(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&IfExpression{
+				Condition: two(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&LetStatement{Value: one()},
+			&LetStatement{Value: two()},
+		},
+		{
+			&ExportStatement{Statement: &LetStatement{Value: one()}},
+			&ExportStatement{Statement: &LetStatement{Value: two()}},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&CallExpression{Function: one(), Arguments: []Expression{one(), two()}},
+			&CallExpression{Function: two(), Arguments: []Expression{two(), two()}},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+		{
+			&HashLiteral{Pairs: []HashPair{{Key: one(), Value: one()}, {Key: two(), Value: one()}}},
+			&HashLiteral{Pairs: []HashPair{{Key: two(), Value: two()}, {Key: two(), Value: two()}}},
+		},
+	}
+
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+
+		if !reflect.DeepEqual(modified, tt.expected) {
+			t.Errorf("not equal. got=%#v, want=%#v", modified, tt.expected)
+		}
+	}
+}
+
+func TestModifyTypes(t *testing.T) {
+	intToFloat := func(node Node) Node {
+		if named, ok := node.(*NamedType); ok && named.Name == "int" {
+			return &NamedType{Name: "float"}
+		}
+		return node
+	}
+
+	fn := &FunctionLiteral{
+		Parameters: []*Identifier{{Value: "a", Type: &ArrayType{Element: &NamedType{Name: "int"}}}},
+		ReturnType: &FunctionType{
+			Parameters: []TypeExpression{&NamedType{Name: "int"}},
+			Return:     &HashType{Key: &NamedType{Name: "string"}, Value: &NamedType{Name: "int"}},
+		},
+		Body: &BlockStatement{},
+	}
+	Modify(fn, intToFloat)
+
+	if got := fn.Parameters[0].Type.String(); got != "[float]" {
+		t.Errorf("parameter type not modified. got=%s", got)
+	}
+	if got := fn.ReturnType.String(); got != "fn(float): {string: float}" {
+		t.Errorf("return type not modified. got=%s", got)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	original := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{Expression: &InfixExpression{
+				Left:     &IntegerLiteral{Value: 1},
+				Operator: "+",
+				Right: &CallExpression{
+					Function:  &Identifier{Value: "f"},
+					Arguments: []Expression{&IntegerLiteral{Value: 1}},
+				},
+			}},
+			&LetStatement{Name: &Identifier{Value: "h", Type: &HashType{Key: &NamedType{Name: "int"}, Value: &NamedType{Name: "int"}}}, Value: &HashLiteral{Pairs: []HashPair{
+				{Key: &IntegerLiteral{Value: 1}, Value: &IfExpression{
+					Condition:   &Boolean{Value: true},
+					Consequence: &BlockStatement{Statements: []Statement{&ReturnStatement{ReturnValue: &IntegerLiteral{Value: 1}}}},
+				}},
+			}}},
+		},
+	}
+	copied := Copy(original)
+	if !reflect.DeepEqual(copied, original) {
+		t.Fatalf("copy differs. got=%s", copied.String())
+	}
+
+	Modify(copied, func(node Node) Node {
+		if integer, ok := node.(*IntegerLiteral); ok {
+			integer.Value = 2
+		}
+		if named, ok := node.(*NamedType); ok {
+			named.Name = "float"
+		}
+		return node
+	})
+	Inspect(original, func(node Node) bool {
+		if integer, ok := node.(*IntegerLiteral); ok && integer.Value != 1 {
+			t.Errorf("modifying the copy changed the original")
+		}
+		if named, ok := node.(*NamedType); ok && named.Name != "int" {
+			t.Errorf("modifying the copy changed the types of the original")
+		}
+		return true
+	})
+}
@@ -0,0 +1,95 @@
+package ast
+
+import "example/sawan/goInterpreter/token"
+
+// Returns the token a node was parsed from: the keyword of statements and
+// literals, the operator of infix expressions and the opening bracket of
+// calls and index expressions.
+func TokenOf(node Node) token.Token {
+	switch node := node.(type) {
+	case *LetStatement:
+		return node.Token
+	case *ExportStatement:
+		return node.Token
+	case *ReturnStatement:
+		return node.Token
+	case *ThrowStatement:
+		return node.Token
+	case *ExpressionStatement:
+		return node.Token
+	case *BlockStatement:
+		return node.Token
+	case *Identifier:
+		return node.Token
+	case *IntegerLiteral:
+		return node.Token
+	case *FloatLiteral:
+		return node.Token
+	case *StringLiteral:
+		return node.Token
+	case *Boolean:
+		return node.Token
+	case *PrefixExpression:
+		return node.Token
+	case *InfixExpression:
+		return node.Token
+	case *IfExpression:
+		return node.Token
+	case *TryExpression:
+		return node.Token
+	case *FunctionLiteral:
+		return node.Token
+	case *MacroLiteral:
+		return node.Token
+	case *CallExpression:
+		return node.Token
+	case *ArrayLiteral:
+		return node.Token
+	case *HashLiteral:
+		return node.Token
+	case *IndexExpression:
+		return node.Token
+	case *SliceExpression:
+		return node.Token
+	case *ImportExpression:
+		return node.Token
+	case *NamedType:
+		return node.Token
+	case *ArrayType:
+		return node.Token
+	case *HashType:
+		return node.Token
+	case *FunctionType:
+		return node.Token
+	}
+	return token.Token{}
+}
+
+// Returns the token of node or of one of its children that comes last in
+// the source, counting the closing brackets of blocks, calls and literals.
+func LastToken(node Node) token.Token {
+	var last token.Token
+	Inspect(node, func(n Node) bool {
+		if n == nil {
+			return false
+		}
+		tokens := []token.Token{TokenOf(n)}
+		switch n := n.(type) {
+		case *BlockStatement:
+			tokens = append(tokens, n.Rbrace)
+		case *CallExpression:
+			tokens = append(tokens, n.Rparen)
+		case *ArrayLiteral:
+			tokens = append(tokens, n.Rbracket)
+		case *HashLiteral:
+			tokens = append(tokens, n.Rbrace)
+		}
+		for _, tok := range tokens {
+			if tok.Line > last.Line || tok.Line == last.Line && tok.Column > last.Column {
+				last = tok
+			}
+		}
+		return true
+	})
+	return last
+}
@@ -0,0 +1,39 @@
+package ast
+
+// Where the value of an identifier is kept, as decided by the resolver in
+// the evaluator. Identifiers it has not seen are looked up by name.
+type SymbolScope int
+
+const (
+	UnresolvedScope SymbolScope = iota
+	GlobalScope
+	LocalScope
+	FreeScope
+)
+
+func (s SymbolScope) String() string {
+	switch s {
+	case GlobalScope:
+		return "GLOBAL"
+	case LocalScope:
+		return "LOCAL"
+	case FreeScope:
+		return "FREE"
+	}
+	return "UNRESOLVED"
+}
+
+/*
+What a call of a resolved function needs, filled in by the resolver.
+
+Locals: The number of slots for bindings no closure uses
+Cells: The number of bindings shared with closures. The cells captured from
+the enclosing function follow them, so free variables are indexed after these.
+Free: For every captured variable, the index of its cell in the enclosing
+function.
+*/
+type FrameLayout struct {
+	Locals int
+	Cells  int
+	Free   []int
+}
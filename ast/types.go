@@ -0,0 +1,70 @@
+package ast
+
+import (
+	"strings"
+
+	"example/sawan/goInterpreter/token"
+)
+
+// A type written in an annotation, like `int`, `[string]`, `{string: int}`
+// or `fn(int, int): bool`. The evaluator ignores them; they are only read by
+// the type checker.
+type TypeExpression interface {
+	Node
+	typeNode()
+}
+
+// A type given by name, such as int or any.
+type NamedType struct {
+	Token token.Token
+	Name  string
+}
+
+func (nt *NamedType) typeNode()            {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string       { return nt.Name }
+
+// `[element]`, an array whose elements all have the element type.
+type ArrayType struct {
+	Token   token.Token // the [
+	Element TypeExpression
+}
+
+func (at *ArrayType) typeNode()            {}
+func (at *ArrayType) TokenLiteral() string { return at.Token.Literal }
+func (at *ArrayType) String() string       { return "[" + at.Element.String() + "]" }
+
+// `{key: value}`, a hash with keys and values of one type each.
+type HashType struct {
+	Token token.Token // the {
+	Key   TypeExpression
+	Value TypeExpression
+}
+
+func (ht *HashType) typeNode()            {}
+func (ht *HashType) TokenLiteral() string { return ht.Token.Literal }
+func (ht *HashType) String() string {
+	return "{" + ht.Key.String() + ": " + ht.Value.String() + "}"
+}
+
+// `fn(parameters): return`. Return is nil when it is left out.
+type FunctionType struct {
+	Token      token.Token // the fn
+	Parameters []TypeExpression
+	Return     TypeExpression
+}
+
+func (ft *FunctionType) typeNode()            {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) String() string {
+	params := []string{}
+	for _, p := range ft.Parameters {
+		params = append(params, p.String())
+	}
+
+	out := "fn(" + strings.Join(params, ", ") + ")"
+	if ft.Return != nil {
+		out += ": " + ft.Return.String()
+	}
+	return out
+}
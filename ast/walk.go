@@ -0,0 +1,228 @@
+package ast
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walks the tree depth first in source order, in the style of go/ast.
+// Missing children (nil) are skipped.
+func Walk(node Node, v Visitor) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch node := node.(type) {
+
+	case *Program:
+		walkStatements(node.Statements, v)
+
+	case *ExpressionStatement:
+		walkExpression(node.Expression, v)
+
+	case *LetStatement:
+		if node.Name != nil {
+			Walk(node.Name, v)
+		}
+		walkExpression(node.Value, v)
+
+	case *ExportStatement:
+		if node.Statement != nil {
+			Walk(node.Statement, v)
+		}
+
+	case *ReturnStatement:
+		walkExpression(node.ReturnValue, v)
+
+	case *ThrowStatement:
+		walkExpression(node.Value, v)
+
+	case *BlockStatement:
+		walkStatements(node.Statements, v)
+
+	case *Identifier:
+		walkType(node.Type, v)
+
+	case *PrefixExpression:
+		walkExpression(node.Right, v)
+
+	case *InfixExpression:
+		walkExpression(node.Left, v)
+		walkExpression(node.Right, v)
+
+	case *IfExpression:
+		walkExpression(node.Condition, v)
+		if node.Consequence != nil {
+			Walk(node.Consequence, v)
+		}
+		if node.Alternative != nil {
+			Walk(node.Alternative, v)
+		}
+
+	case *TryExpression:
+		if node.Block != nil {
+			Walk(node.Block, v)
+		}
+		if node.Param != nil {
+			Walk(node.Param, v)
+		}
+		if node.Catch != nil {
+			Walk(node.Catch, v)
+		}
+		if node.Finally != nil {
+			Walk(node.Finally, v)
+		}
+
+	case *FunctionLiteral:
+		walkIdentifiers(node.Parameters, v)
+		walkType(node.ReturnType, v)
+		if node.Body != nil {
+			Walk(node.Body, v)
+		}
+
+	case *MacroLiteral:
+		walkIdentifiers(node.Parameters, v)
+		if node.Body != nil {
+			Walk(node.Body, v)
+		}
+
+	case *CallExpression:
+		walkExpression(node.Function, v)
+		walkExpressions(node.Arguments, v)
+
+	case *ArrayLiteral:
+		walkExpressions(node.Elements, v)
+
+	case *IndexExpression:
+		walkExpression(node.Left, v)
+		walkExpression(node.Index, v)
+
+	case *SliceExpression:
+		walkExpression(node.Left, v)
+		walkExpression(node.Start, v)
+		walkExpression(node.End, v)
+
+	case *HashLiteral:
+		for _, pair := range node.Pairs {
+			walkExpression(pair.Key, v)
+			walkExpression(pair.Value, v)
+		}
+
+	case *ImportExpression:
+		if node.Path != nil {
+			Walk(node.Path, v)
+		}
+
+	case *ArrayType:
+		walkType(node.Element, v)
+
+	case *HashType:
+		walkType(node.Key, v)
+		walkType(node.Value, v)
+
+	case *FunctionType:
+		for _, param := range node.Parameters {
+			walkType(param, v)
+		}
+		walkType(node.Return, v)
+	}
+
+	v.Visit(nil)
+}
+
+func walkStatements(statements []Statement, v Visitor) {
+	for _, stmt := range statements {
+		if stmt != nil {
+			Walk(stmt, v)
+		}
+	}
+}
+
+func walkExpression(exp Expression, v Visitor) {
+	if exp != nil {
+		Walk(exp, v)
+	}
+}
+
+func walkExpressions(exps []Expression, v Visitor) {
+	for _, exp := range exps {
+		walkExpression(exp, v)
+	}
+}
+
+func walkType(t TypeExpression, v Visitor) {
+	if t != nil {
+		Walk(t, v)
+	}
+}
+
+func walkIdentifiers(idents []*Identifier, v Visitor) {
+	for _, ident := range idents {
+		if ident != nil {
+			Walk(ident, v)
+		}
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Traverses the tree like Walk, calling f for every node. Children are
+// only visited when f returns true, and f(nil) is called after them.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(node, inspector(f))
+}
+
+// Maps every node below root to its parent. Root itself has no entry.
+func Parents(root Node) map[Node]Node {
+	parents := map[Node]Node{}
+	stack := []Node{}
+
+	Inspect(root, func(node Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		if len(stack) > 0 {
+			parents[node] = stack[len(stack)-1]
+		}
+		stack = append(stack, node)
+		return true
+	})
+
+	return parents
+}
+
+// Returns the chain of nodes from root down to target, both included,
+// or nil when target is not part of the tree.
+func PathTo(root, target Node) []Node {
+	var path []Node
+	stack := []Node{}
+	found := false
+
+	Inspect(root, func(node Node) bool {
+		if found {
+			return false
+		}
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		stack = append(stack, node)
+		if node == target {
+			path = append([]Node{}, stack...)
+			found = true
+		}
+		return true
+	})
+
+	return path
+}
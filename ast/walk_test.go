@@ -0,0 +1,108 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	one := &IntegerLiteral{Value: 1}
+	two := &IntegerLiteral{Value: 2}
+	x := &Identifier{Value: "x"}
+	key := &StringLiteral{Value: "k"}
+	body := &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: x}}}
+	typed := &Identifier{Value: "y", Type: &ArrayType{Element: &NamedType{Name: "int"}}}
+	returns := &FunctionType{
+		Parameters: []TypeExpression{&NamedType{Name: "int"}},
+		Return:     &HashType{Key: &NamedType{Name: "string"}, Value: &NamedType{Name: "int"}},
+	}
+	fn := &FunctionLiteral{Parameters: []*Identifier{x, typed}, ReturnType: returns, Body: body}
+	hash := &HashLiteral{Pairs: []HashPair{{Key: key, Value: one}}}
+	slice := &SliceExpression{Left: x, End: two}
+	ifExp := &IfExpression{Condition: one, Consequence: &BlockStatement{}}
+
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{Name: x, Value: fn},
+			&ExpressionStatement{Expression: hash},
+			&ReturnStatement{ReturnValue: slice},
+			&ExpressionStatement{Expression: ifExp},
+		},
+	}
+
+	var visited []string
+	Inspect(program, func(node Node) bool {
+		if node != nil {
+			visited = append(visited, reflect.TypeOf(node).Elem().Name())
+		}
+		return true
+	})
+
+	expected := []string{
+		"Program",
+		"LetStatement", "Identifier", "FunctionLiteral", "Identifier",
+		"Identifier", "ArrayType", "NamedType",
+		"FunctionType", "NamedType", "HashType", "NamedType", "NamedType",
+		"BlockStatement", "ExpressionStatement", "Identifier",
+		"ExpressionStatement", "HashLiteral", "StringLiteral", "IntegerLiteral",
+		"ReturnStatement", "SliceExpression", "Identifier", "IntegerLiteral",
+		"ExpressionStatement", "IfExpression", "IntegerLiteral", "BlockStatement",
+	}
+
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("wrong visit order.\ngot=%v\nwant=%v", visited, expected)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{Expression: &FunctionLiteral{
+				Body: &BlockStatement{Statements: []Statement{
+					&ExpressionStatement{Expression: &IntegerLiteral{Value: 1}},
+				}},
+			}},
+			&ExpressionStatement{Expression: &IntegerLiteral{Value: 2}},
+		},
+	}
+
+	var integers []int64
+	Inspect(program, func(node Node) bool {
+		if _, ok := node.(*FunctionLiteral); ok {
+			return false
+		}
+		if integer, ok := node.(*IntegerLiteral); ok {
+			integers = append(integers, integer.Value)
+		}
+		return true
+	})
+
+	if !reflect.DeepEqual(integers, []int64{2}) {
+		t.Errorf("function body was not skipped. got=%v", integers)
+	}
+}
+
+func TestParentsAndPathTo(t *testing.T) {
+	target := &IntegerLiteral{Value: 2}
+	infix := &InfixExpression{Left: &IntegerLiteral{Value: 1}, Operator: "+", Right: target}
+	stmt := &ExpressionStatement{Expression: infix}
+	program := &Program{Statements: []Statement{stmt}}
+
+	parents := Parents(program)
+	if parents[target] != infix || parents[infix] != stmt || parents[stmt] != program {
+		t.Errorf("wrong parents. got=%v", parents)
+	}
+	if _, ok := parents[program]; ok {
+		t.Errorf("root should not have a parent")
+	}
+
+	path := PathTo(program, target)
+	expected := []Node{program, stmt, infix, target}
+	if !reflect.DeepEqual(path, expected) {
+		t.Errorf("wrong path. got=%v", path)
+	}
+
+	if path := PathTo(program, &Identifier{Value: "missing"}); path != nil {
+		t.Errorf("expected nil path for missing node. got=%v", path)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/parser"
+	"example/sawan/goInterpreter/types"
+)
+
+// Implements `monkey check`: reports the type errors of each file and exits
+// with 1 when there are any.
+func checkCommand(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: monkey check script.mk ...\n")
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		p := parser.New(lexer.New(string(source)))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			for _, e := range p.ParseErrors() {
+				fmt.Printf("%s:%d:%d: %s\n", path, e.Token.Line, e.Token.Column, e.Message)
+			}
+			status = 1
+			continue
+		}
+
+		for _, d := range types.Check(program) {
+			fmt.Printf("%s:%s\n", path, d)
+			status = 1
+		}
+	}
+	return status
+}
@@ -0,0 +1,241 @@
+// Package coverage records which statements and if branches of Monkey
+// programs run, and reports it by line as a summary, as HTML and as LCOV.
+package coverage
+
+import (
+	"sort"
+
+	"example/sawan/goInterpreter/ast"
+)
+
+// Where a statement or if expression starts.
+type Position struct {
+	Line   int
+	Column int
+}
+
+/*
+An if expression and how often each of its branches ran.
+
+Alternative: Runs of the else block or, without one, of the if falling
+through
+*/
+type Branch struct {
+	Position
+	Consequence int64
+	Alternative int64
+}
+
+/*
+A program that was covered.
+
+Path: The script, or the name of the module as its loader gave it
+Statements: How often each statement ran, by where it starts
+*/
+type File struct {
+	Path       string
+	Source     string
+	Statements map[Position]int64
+	Branches   map[Position]*Branch
+}
+
+/*
+Records the coverage of the programs added to it while they run, through
+the coverage hooks of the evaluator. Modules they import are added as they
+are.
+
+statements, branches: Where the nodes of every program added are counted. A
+program added again, as when a module is imported by two scripts, is counted
+with the one added first.
+*/
+type Profile struct {
+	files      map[string]*File
+	order      []*File
+	statements map[ast.Statement]counter
+	branches   map[*ast.IfExpression]*Branch
+}
+
+type counter struct {
+	file *File
+	pos  Position
+}
+
+func New() *Profile {
+	return &Profile{
+		files:      map[string]*File{},
+		statements: map[ast.Statement]counter{},
+		branches:   map[*ast.IfExpression]*Branch{},
+	}
+}
+
+// Adds program, parsed from source at path, to the programs covered. Its
+// statements count as not run until they do.
+func (p *Profile) Add(path string, source string, program *ast.Program) {
+	file, ok := p.files[path]
+	if !ok {
+		file = &File{Path: path, Source: source, Statements: map[Position]int64{}, Branches: map[Position]*Branch{}}
+		p.files[path] = file
+		p.order = append(p.order, file)
+	}
+
+	add := func(statements []ast.Statement) {
+		for _, stmt := range statements {
+			pos := position(stmt)
+			if _, ok := file.Statements[pos]; !ok {
+				file.Statements[pos] = 0
+			}
+			p.statements[stmt] = counter{file, pos}
+		}
+	}
+
+	// only the statements of programs and blocks run one by one; the ones
+	// wrapped by export statements run as part of them
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Program:
+			add(node.Statements)
+		case *ast.BlockStatement:
+			add(node.Statements)
+		case *ast.IfExpression:
+			pos := position(node)
+			if file.Branches[pos] == nil {
+				file.Branches[pos] = &Branch{Position: pos}
+			}
+			p.branches[node] = file.Branches[pos]
+		}
+		return true
+	})
+}
+
+func position(node ast.Node) Position {
+	tok := ast.TokenOf(node)
+	return Position{tok.Line, tok.Column}
+}
+
+// Returns the programs covered, in the order they were added.
+func (p *Profile) Files() []*File {
+	return p.order
+}
+
+func (p *Profile) Module(name string, source string, program *ast.Program) {
+	p.Add(name, source, program)
+}
+
+func (p *Profile) Statement(stmt ast.Statement) {
+	if c, ok := p.statements[stmt]; ok {
+		c.file.Statements[c.pos]++
+	}
+}
+
+func (p *Profile) Branch(ie *ast.IfExpression, taken bool) {
+	b, ok := p.branches[ie]
+	if !ok {
+		return
+	}
+	if taken {
+		b.Consequence++
+	} else {
+		b.Alternative++
+	}
+}
+
+/*
+The coverage of one line of a file.
+
+Hits: How often the statement that ran most on the line ran
+Statements, Covered: How many statements start on the line and how many of
+them ran
+Branches, Taken: How many branches the if expressions starting on the line
+have and how many of them ran
+*/
+type Line struct {
+	Number     int
+	Hits       int64
+	Statements int
+	Covered    int
+	Branches   int
+	Taken      int
+}
+
+// Reports whether everything starting on the line ran.
+func (l Line) Complete() bool {
+	return l.Covered == l.Statements && l.Taken == l.Branches
+}
+
+// Returns the lines with statements or branches starting on them, in order.
+func (f *File) Lines() []Line {
+	lines := map[int]*Line{}
+	get := func(n int) *Line {
+		if lines[n] == nil {
+			lines[n] = &Line{Number: n}
+		}
+		return lines[n]
+	}
+
+	for pos, count := range f.Statements {
+		l := get(pos.Line)
+		l.Statements++
+		if count > 0 {
+			l.Covered++
+		}
+		if count > l.Hits {
+			l.Hits = count
+		}
+	}
+	for pos, b := range f.Branches {
+		l := get(pos.Line)
+		l.Branches += 2
+		for _, count := range []int64{b.Consequence, b.Alternative} {
+			if count > 0 {
+				l.Taken++
+			}
+		}
+	}
+
+	sorted := []Line{}
+	for _, l := range lines {
+		sorted = append(sorted, *l)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Number < sorted[j].Number })
+	return sorted
+}
+
+// Returns how many statements the file has and how many of them ran.
+func (f *File) StatementsCovered() (covered, total int) {
+	for _, count := range f.Statements {
+		total++
+		if count > 0 {
+			covered++
+		}
+	}
+	return covered, total
+}
+
+// Returns how many branches the file has and how many of them ran.
+func (f *File) BranchesTaken() (taken, total int) {
+	for _, b := range f.Branches {
+		total += 2
+		for _, count := range []int64{b.Consequence, b.Alternative} {
+			if count > 0 {
+				taken++
+			}
+		}
+	}
+	return taken, total
+}
+
+// Returns the branches of the file in source order.
+func (f *File) SortedBranches() []*Branch {
+	branches := []*Branch{}
+	for _, b := range f.Branches {
+		branches = append(branches, b)
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		a, b := branches[i].Position, branches[j].Position
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return branches
+}
@@ -0,0 +1,185 @@
+package coverage
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/object"
+	"example/sawan/goInterpreter/parser"
+)
+
+const libSource = `export let sign = fn(x) {
+  if (x < 0) { return -1; }
+  if (x > 0) { 1 } else { 0 }
+};
+export let unused = fn() { 1 };
+`
+
+const mainSource = `let lib = import "lib.mk";
+let a = lib["sign"](5);
+if (a == 1) { puts("one"); }
+let twice = fn(f, x) { f(f(x)) };
+twice(fn(x) { x + 1 }, a);
+`
+
+// Runs mainSource, which imports libSource, while recording its coverage.
+func run(t *testing.T) *Profile {
+	t.Helper()
+
+	p := parser.New(lexer.New(mainSource))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	evaluator.SetModuleLoader(evaluator.MemoryLoader{"lib.mk": libSource})
+	profile := New()
+	profile.Add("main.mk", mainSource, program)
+	evaluator.SetCoverage(profile)
+	defer evaluator.SetCoverage(nil)
+
+	stdout := evaluator.Stdout
+	evaluator.Stdout = &bytes.Buffer{}
+	defer func() { evaluator.Stdout = stdout }()
+	result := evaluator.Eval(program, object.NewEnvironment())
+	if _, ok := result.(*object.Error); ok {
+		t.Fatalf("program failed: %s", result.Inspect())
+	}
+	return profile
+}
+
+func TestStatementsAndBranches(t *testing.T) {
+	files := run(t).Files()
+	if len(files) != 2 || files[0].Path != "main.mk" || files[1].Path != "lib.mk" {
+		t.Fatalf("wrong files: %v", files)
+	}
+
+	tests := []struct {
+		file     *File
+		lines    string
+		covered  int
+		total    int
+		branches []Branch
+	}{
+		{
+			file: files[0],
+			// line: hits statements/covered branches/taken
+			lines:    "1:1 1/1 0/0, 2:1 1/1 0/0, 3:1 2/2 2/1, 4:1 2/2 0/0, 5:2 2/2 0/0",
+			covered:  8,
+			total:    8,
+			branches: []Branch{{Position{3, 1}, 1, 0}},
+		},
+		{
+			file:    files[1],
+			lines:   "1:1 1/1 0/0, 2:1 2/1 2/1, 3:1 3/2 2/1, 5:1 2/1 0/0",
+			covered: 5,
+			total:   8,
+			// the second if is in tail position
+			branches: []Branch{{Position{2, 3}, 0, 1}, {Position{3, 3}, 1, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		lines := []string{}
+		for _, l := range tt.file.Lines() {
+			lines = append(lines, fmt.Sprintf("%d:%d %d/%d %d/%d", l.Number, l.Hits, l.Statements, l.Covered, l.Branches, l.Taken))
+		}
+		if got := strings.Join(lines, ", "); got != tt.lines {
+			t.Errorf("%s: wrong lines.\nwant=%s\ngot= %s", tt.file.Path, tt.lines, got)
+		}
+
+		if covered, total := tt.file.StatementsCovered(); covered != tt.covered || total != tt.total {
+			t.Errorf("%s: wrong statements. want=%d/%d, got=%d/%d", tt.file.Path, tt.covered, tt.total, covered, total)
+		}
+
+		branches := tt.file.SortedBranches()
+		if len(branches) != len(tt.branches) {
+			t.Fatalf("%s: wrong number of branches. want=%d, got=%d", tt.file.Path, len(tt.branches), len(branches))
+		}
+		for i, b := range branches {
+			if *b != tt.branches[i] {
+				t.Errorf("%s: branches[%d] wrong. want=%+v, got=%+v", tt.file.Path, i, tt.branches[i], *b)
+			}
+		}
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(t).WriteSummary(&out); err != nil {
+		t.Fatalf("WriteSummary failed: %s", err)
+	}
+
+	want := []string{
+		"main.mk      8/8     statements  100.0%      1/2     branches   50.0%",
+		"lib.mk       5/8     statements   62.5%      2/4     branches   50.0%",
+		"total       13/16    statements   81.2%      3/6     branches   50.0%",
+	}
+	if got := strings.TrimSuffix(out.String(), "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("wrong summary.\nwant=\n%s\ngot=\n%s", strings.Join(want, "\n"), got)
+	}
+}
+
+func TestWriteLCOV(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(t).WriteLCOV(&out); err != nil {
+		t.Fatalf("WriteLCOV failed: %s", err)
+	}
+
+	want := `TN:
+SF:main.mk
+BRDA:3,0,0,1
+BRDA:3,0,1,0
+BRF:2
+BRH:1
+DA:1,1
+DA:2,1
+DA:3,1
+DA:4,1
+DA:5,2
+LF:5
+LH:5
+end_of_record
+TN:
+SF:lib.mk
+BRDA:2,0,0,0
+BRDA:2,0,1,1
+BRDA:3,1,0,1
+BRDA:3,1,1,0
+BRF:4
+BRH:2
+DA:1,1
+DA:2,1
+DA:3,1
+DA:5,1
+LF:4
+LH:4
+end_of_record
+`
+	if out.String() != want {
+		t.Errorf("wrong LCOV.\nwant=\n%s\ngot=\n%s", want, out.String())
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(t).WriteHTML(&out); err != nil {
+		t.Fatalf("WriteHTML failed: %s", err)
+	}
+
+	html := out.String()
+	for _, want := range []string{
+		`<li><a href="#file1">lib.mk</a>: 62.5% of statements, 50.0% of branches</li>`,
+		`<tr class="covered"><td class="number">1</td><td class="hits">1</td><td class="source">let lib = import &#34;lib.mk&#34;;</td></tr>`,
+		`<tr class="partial"><td class="number">2</td><td class="hits">1</td><td class="source">  if (x &lt; 0) { return -1; }</td></tr>`,
+		`<tr><td class="number">4</td><td class="hits"></td><td class="source">};</td></tr>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML is missing %s", want)
+		}
+	}
+}
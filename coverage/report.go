@@ -0,0 +1,187 @@
+package coverage
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
+
+// Writes how many of the statements and branches of each file ran, and of
+// all of them together.
+func (p *Profile) WriteSummary(w io.Writer) error {
+	var out bytes.Buffer
+	width := len("total")
+	for _, f := range p.order {
+		if len(f.Path) > width {
+			width = len(f.Path)
+		}
+	}
+
+	row := func(name string, covered, statements, taken, branches int) {
+		fmt.Fprintf(&out, "%-*s  %5d/%-5d statements %7s  %5d/%-5d branches %7s\n", width, name,
+			covered, statements, percent(covered, statements), taken, branches, percent(taken, branches))
+	}
+
+	var covered, statements, taken, branches int
+	for _, f := range p.order {
+		c, s := f.StatementsCovered()
+		t, b := f.BranchesTaken()
+		row(f.Path, c, s, t, b)
+		covered, statements, taken, branches = covered+c, statements+s, taken+t, branches+b
+	}
+	if len(p.order) > 1 {
+		row("total", covered, statements, taken, branches)
+	}
+
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// Writes the coverage in the LCOV trace file format, with the hits of every
+// line that has statements and both branches of every if expression.
+func (p *Profile) WriteLCOV(w io.Writer) error {
+	var out bytes.Buffer
+	for _, f := range p.order {
+		fmt.Fprintf(&out, "TN:\nSF:%s\n", f.Path)
+
+		for i, b := range f.SortedBranches() {
+			for branch, count := range []int64{b.Consequence, b.Alternative} {
+				taken := fmt.Sprint(count)
+				// neither branch counts when the if never ran
+				if b.Consequence+b.Alternative == 0 {
+					taken = "-"
+				}
+				fmt.Fprintf(&out, "BRDA:%d,%d,%d,%s\n", b.Line, i, branch, taken)
+			}
+		}
+		taken, branches := f.BranchesTaken()
+		fmt.Fprintf(&out, "BRF:%d\nBRH:%d\n", branches, taken)
+
+		found, hit := 0, 0
+		for _, l := range f.Lines() {
+			if l.Statements == 0 {
+				continue
+			}
+			found++
+			if l.Hits > 0 {
+				hit++
+			}
+			fmt.Fprintf(&out, "DA:%d,%d\n", l.Number, l.Hits)
+		}
+		fmt.Fprintf(&out, "LF:%d\nLH:%d\nend_of_record\n", found, hit)
+	}
+
+	_, err := out.WriteTo(w)
+	return err
+}
+
+var htmlReport = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; font-family: monospace; }
+td { padding: 0 0.5em; white-space: pre; vertical-align: top; }
+td.number, td.hits { color: #888; text-align: right; }
+tr.covered td.source { background: #d9f2d9; }
+tr.partial td.source { background: #fbefc8; }
+tr.uncovered td.source { background: #f7d4d4; }
+</style>
+</head>
+<body>
+<h1>Coverage</h1>
+<ul>
+{{- range .}}
+<li><a href="#{{.ID}}">{{.Path}}</a>: {{.Statements}} of statements, {{.Branches}} of branches</li>
+{{- end}}
+</ul>
+{{- range .}}
+<h2 id="{{.ID}}">{{.Path}}</h2>
+<table>
+{{- range .Lines}}
+<tr{{with .Class}} class="{{.}}"{{end}}><td class="number">{{.Number}}</td><td class="hits">{{.Hits}}</td><td class="source">{{.Source}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+type htmlFile struct {
+	ID         string
+	Path       string
+	Statements string
+	Branches   string
+	Lines      []htmlLine
+}
+
+/*
+A line of source in the HTML report.
+
+Hits: Blank for lines without statements
+Class: covered, partial or uncovered, or blank for lines without statements
+or branches
+*/
+type htmlLine struct {
+	Number int
+	Hits   string
+	Class  string
+	Source string
+}
+
+// Writes an HTML page with the source of every file, its lines marked by
+// whether everything starting on them ran, some of it or nothing.
+func (p *Profile) WriteHTML(w io.Writer) error {
+	files := []htmlFile{}
+	for i, f := range p.order {
+		covered, statements := f.StatementsCovered()
+		taken, branches := f.BranchesTaken()
+		file := htmlFile{
+			ID:         fmt.Sprintf("file%d", i),
+			Path:       f.Path,
+			Statements: percent(covered, statements),
+			Branches:   percent(taken, branches),
+		}
+
+		lines := map[int]Line{}
+		for _, l := range f.Lines() {
+			lines[l.Number] = l
+		}
+		for n, source := range strings.Split(strings.TrimSuffix(f.Source, "\n"), "\n") {
+			line := htmlLine{Number: n + 1, Source: source}
+			if l, ok := lines[n+1]; ok {
+				switch {
+				case l.Complete():
+					line.Class = "covered"
+				case l.Covered > 0 || l.Taken > 0:
+					line.Class = "partial"
+				default:
+					line.Class = "uncovered"
+				}
+				if l.Statements > 0 {
+					line.Hits = fmt.Sprint(l.Hits)
+				}
+			}
+			file.Lines = append(file.Lines, line)
+		}
+		files = append(files, file)
+	}
+
+	var out bytes.Buffer
+	if err := htmlReport.Execute(&out, files); err != nil {
+		return err
+	}
+	_, err := out.WriteTo(w)
+	return err
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/debugger"
+	"example/sawan/goInterpreter/evaluator"
+)
+
+// Implements `monkey debug`: runs a script under the command line debugger,
+// or serves the Debug Adapter Protocol on stdin and stdout with -dap.
+func debugCommand(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: monkey debug [flags] script.mk\n")
+		fmt.Fprintf(os.Stderr, "       monkey debug -dap\n\n")
+		flags.PrintDefaults()
+	}
+	dap := flags.Bool("dap", false, "speak the Debug Adapter Protocol on stdin and stdout; the script comes with the launch request")
+	searchPath := flags.String("path", "", "directories searched by import, as for running scripts")
+	flags.Parse(args)
+
+	// imports are found next to the script being debugged
+	loadScript := func(path string) (*ast.Program, error) {
+		evaluator.SetModuleLoader(&evaluator.FileLoader{SearchPath: importPath(filepath.Dir(path), *searchPath)})
+		return load(path, nil)
+	}
+
+	if *dap {
+		return debugger.ServeDAP(os.Stdin, os.Stdout, loadScript)
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	program, err := loadScript(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	source, _ := os.ReadFile(path)
+	return debugger.RunCLI(program, string(source), os.Stdin, os.Stdout)
+}
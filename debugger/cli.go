@@ -0,0 +1,202 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/object"
+)
+
+const PROMPT = "(monkey) "
+
+const cliHelp = `break LINE (b)    stop at LINE
+clear LINE        remove the breakpoint at LINE
+breakpoints       list the breakpoints
+step (s)          run to the next line, following calls
+next (n)          run to the next line of this call
+finish            run until this call returns
+continue (c)      run until the next breakpoint
+print EXPR (p)    evaluate EXPR in the selected call
+locals            list the bindings of the selected call
+backtrace (bt)    show the calls leading here
+frame N (f)       select call N of the backtrace
+list (l)          show the source around the current line
+quit (q)          end the program
+`
+
+/*
+A debugger driven by commands typed at a prompt.
+
+source: Lines of the program's source, to show where it stopped
+frame: The call print and locals look at, numbered as in the backtrace
+last: The last command, repeated by an empty line
+*/
+type cli struct {
+	session *Session
+	source  []string
+	in      *bufio.Scanner
+	out     io.Writer
+	frame   int
+	last    string
+}
+
+// Runs program, whose source is source, under a debugger reading commands
+// from in. It stops before the first statement so breakpoints can be set.
+// Returns the exit status of the program.
+func RunCLI(program *ast.Program, source string, in io.Reader, out io.Writer) int {
+	c := &cli{source: strings.Split(source, "\n"), in: bufio.NewScanner(in), out: out}
+	c.session = New(program, c.stopped)
+
+	result, err := c.session.Run(object.NewEnvironment(), true)
+	if err == ErrQuit {
+		return 1
+	}
+	if errObj, ok := result.(*object.Error); ok {
+		fmt.Fprintf(out, "error: %s\n", errObj.Message)
+		return 1
+	}
+	fmt.Fprintln(out, "program finished")
+	return 0
+}
+
+// Reads commands until one resumes the program. The end of the input quits
+// it.
+func (c *cli) stopped(reason string) {
+	c.frame = 0
+	top := c.session.Stack()[0]
+	fmt.Fprintf(c.out, "stopped at line %d (%s)\n", top.Line, reason)
+	c.showLine(top.Line)
+
+	for {
+		fmt.Fprint(c.out, PROMPT)
+		if !c.in.Scan() {
+			fmt.Fprintln(c.out)
+			c.session.Quit()
+			return
+		}
+
+		line := strings.TrimSpace(c.in.Text())
+		if line == "" {
+			line = c.last
+		}
+		c.last = line
+
+		if c.command(line) {
+			return
+		}
+	}
+}
+
+// Runs one command and reports whether it resumed the program.
+func (c *cli) command(line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "":
+	case "break", "b":
+		if n, ok := c.lineArg(arg); ok {
+			if c.session.SetBreakpoint(n) {
+				fmt.Fprintf(c.out, "breakpoint at line %d\n", n)
+			} else {
+				fmt.Fprintf(c.out, "no statement starts on line %d\n", n)
+			}
+		}
+	case "clear":
+		if n, ok := c.lineArg(arg); ok {
+			c.session.ClearBreakpoint(n)
+		}
+	case "breakpoints":
+		for _, n := range c.session.Breakpoints() {
+			fmt.Fprintf(c.out, "line %d\n", n)
+		}
+
+	case "step", "s":
+		c.session.StepIn()
+		return true
+	case "next", "n":
+		c.session.StepOver()
+		return true
+	case "finish":
+		c.session.StepOut()
+		return true
+	case "continue", "c":
+		c.session.Continue()
+		return true
+	case "quit", "q":
+		c.session.Quit()
+		return true
+
+	case "print", "p":
+		result, err := c.session.Evaluate(arg, c.frame)
+		if err != nil {
+			fmt.Fprintln(c.out, err)
+		} else {
+			fmt.Fprintln(c.out, result.Inspect())
+		}
+	case "locals":
+		bindings := c.session.Bindings(c.frame)
+		for _, name := range sortedNames(bindings) {
+			fmt.Fprintf(c.out, "%s = %s\n", name, bindings[name].Inspect())
+		}
+	case "backtrace", "bt":
+		for i, frame := range c.session.Stack() {
+			fmt.Fprintf(c.out, "#%d %s\n", i, describeFrame(frame))
+		}
+	case "frame", "f":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || n >= len(c.session.Stack()) {
+			fmt.Fprintf(c.out, "no frame %q\n", arg)
+			break
+		}
+		c.frame = n
+		fmt.Fprintf(c.out, "#%d %s\n", n, describeFrame(c.session.Stack()[n]))
+	case "list", "l":
+		line := c.session.Stack()[c.frame].Line
+		for n := line - 5; n <= line+5; n++ {
+			c.showLine(n)
+		}
+	case "help", "h":
+		fmt.Fprint(c.out, cliHelp)
+
+	default:
+		fmt.Fprintf(c.out, "unknown command %q, try help\n", name)
+	}
+	return false
+}
+
+func (c *cli) lineArg(arg string) (int, bool) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		fmt.Fprintf(c.out, "not a line number: %q\n", arg)
+		return 0, false
+	}
+	return n, true
+}
+
+func (c *cli) showLine(n int) {
+	if n >= 1 && n <= len(c.source) {
+		fmt.Fprintf(c.out, "%4d  %s\n", n, c.source[n-1])
+	}
+}
+
+func describeFrame(f *Frame) string {
+	if f.Line == 0 {
+		return f.Name + " in an imported module"
+	}
+	return fmt.Sprintf("%s at line %d", f.Name, f.Line)
+}
+
+func sortedNames(bindings map[string]object.Object) []string {
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
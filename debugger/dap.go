@@ -0,0 +1,454 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/object"
+)
+
+// The subset of the Debug Adapter Protocol the server speaks. Lines and
+// columns are 1-based, the default of the protocol. The program runs as the
+// only thread, with id 1.
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type dapResponse struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+type launchArguments struct {
+	Program     string `json:"program"`
+	StopOnEntry bool   `json:"stopOnEntry"`
+}
+
+type setBreakpointsArguments struct {
+	Breakpoints []struct {
+		Line int `json:"line"`
+	} `json:"breakpoints"`
+}
+
+type frameArguments struct {
+	FrameID            int    `json:"frameId"`
+	VariablesReference int    `json:"variablesReference"`
+	Expression         string `json:"expression"`
+}
+
+type dapSource struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type dapVariable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// Loads the program at path, ready to run. Parse and macro errors are
+// returned as error.
+type LoadFunc func(path string) (*ast.Program, error)
+
+/*
+out: Where responses and events are written, guarded by mu
+session: The session of the launched program
+stopped: Set while the program is stopped, guarded by mu. The other requests
+about the program are only answered then.
+resume: Resumes the stopped program
+done: Closed once the program ended
+*/
+type dapServer struct {
+	load LoadFunc
+
+	mu  sync.Mutex
+	out io.Writer
+	seq int
+
+	path        string
+	stopOnEntry bool
+	session     *Session
+	stopped     bool
+	resume      chan struct{}
+	done        chan struct{}
+}
+
+// Serves the Debug Adapter Protocol on in and out until the client
+// disconnects or in is closed. Programs are loaded with load, and what they
+// print is sent to the client as output events.
+func ServeDAP(in io.Reader, out io.Writer, load LoadFunc) int {
+	s := &dapServer{load: load, out: out, resume: make(chan struct{})}
+	r := bufio.NewReader(in)
+
+	saved := evaluator.Stdout
+	evaluator.Stdout = outputWriter{s, "stdout"}
+	defer func() { evaluator.Stdout = saved }()
+
+	for {
+		req, err := readRequest(r)
+		if err != nil {
+			s.disconnect()
+			return 1
+		}
+
+		if req.Command == "disconnect" {
+			s.disconnect()
+			s.respond(req, nil, "")
+			return 0
+		}
+		s.handle(req)
+	}
+}
+
+func readRequest(r *bufio.Reader) (*dapRequest, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	req := &dapRequest{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Writes msg, giving it the next sequence number. Called with mu held.
+func (s *dapServer) write(msg interface{}) {
+	s.seq++
+	switch msg := msg.(type) {
+	case *dapResponse:
+		msg.Seq = s.seq
+	case *dapEvent:
+		msg.Seq = s.seq
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(body))
+	s.out.Write(body)
+}
+
+// Answers req with body, or with the error message when it is not empty.
+func (s *dapServer) respond(req *dapRequest, body interface{}, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(&dapResponse{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Success:    message == "",
+		Command:    req.Command,
+		Message:    message,
+		Body:       body,
+	})
+}
+
+func (s *dapServer) event(event string, body interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(&dapEvent{Type: "event", Event: event, Body: body})
+}
+
+// Sends what the program writes as output events of category.
+type outputWriter struct {
+	s        *dapServer
+	category string
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	w.s.event("output", map[string]string{"category": w.category, "output": string(p)})
+	return len(p), nil
+}
+
+type dapHandler func(s *dapServer, args json.RawMessage) (interface{}, string)
+
+var dapHandlers = map[string]dapHandler{
+	"initialize":        (*dapServer).initialize,
+	"launch":            (*dapServer).launch,
+	"setBreakpoints":    (*dapServer).setBreakpoints,
+	"configurationDone": (*dapServer).configurationDone,
+	"threads":           (*dapServer).threads,
+	"stackTrace":        (*dapServer).stackTrace,
+	"scopes":            (*dapServer).scopes,
+	"variables":         (*dapServer).variables,
+	"evaluate":          (*dapServer).evaluate,
+	"pause":             (*dapServer).pause,
+	"continue":          resumeWith((*Session).Continue),
+	"next":              resumeWith((*Session).StepOver),
+	"stepIn":            resumeWith((*Session).StepIn),
+	"stepOut":           resumeWith((*Session).StepOut),
+}
+
+func (s *dapServer) handle(req *dapRequest) {
+	handler, ok := dapHandlers[req.Command]
+	if !ok {
+		s.respond(req, nil, "unsupported request "+req.Command)
+		return
+	}
+
+	body, message := handler(s, req.Arguments)
+	s.respond(req, body, message)
+
+	// the initialized event asks for the breakpoints, which can only be
+	// checked against the launched program
+	if req.Command == "launch" && message == "" {
+		s.event("initialized", nil)
+	}
+
+	if resuming[req.Command] && message == "" {
+		s.mu.Lock()
+		s.stopped = false
+		s.mu.Unlock()
+		s.resume <- struct{}{}
+	}
+}
+
+var resuming = map[string]bool{"continue": true, "next": true, "stepIn": true, "stepOut": true}
+
+func (s *dapServer) initialize(json.RawMessage) (interface{}, string) {
+	return map[string]bool{
+		"supportsConfigurationDoneRequest": true,
+		"supportsEvaluateForHovers":        true,
+	}, ""
+}
+
+func (s *dapServer) launch(args json.RawMessage) (interface{}, string) {
+	var a launchArguments
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err.Error()
+	}
+	if s.session != nil {
+		return nil, "a program is already launched"
+	}
+
+	program, err := s.load(a.Program)
+	if err != nil {
+		return nil, err.Error()
+	}
+	s.path, s.stopOnEntry = a.Program, a.StopOnEntry
+	s.session = New(program, s.programStopped)
+	return nil, ""
+}
+
+func (s *dapServer) setBreakpoints(args json.RawMessage) (interface{}, string) {
+	var a setBreakpointsArguments
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err.Error()
+	}
+	if s.session == nil {
+		return nil, "no program launched"
+	}
+
+	s.session.ClearBreakpoints()
+	breakpoints := []map[string]interface{}{}
+	for _, bp := range a.Breakpoints {
+		verified := s.session.SetBreakpoint(bp.Line)
+		breakpoints = append(breakpoints, map[string]interface{}{"verified": verified, "line": bp.Line})
+	}
+	return map[string]interface{}{"breakpoints": breakpoints}, ""
+}
+
+// Starts the program, as every breakpoint is set now.
+func (s *dapServer) configurationDone(json.RawMessage) (interface{}, string) {
+	if s.session == nil {
+		return nil, "no program launched"
+	}
+	if s.done != nil {
+		return nil, ""
+	}
+
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+
+		exitCode := 0
+		result, err := s.session.Run(object.NewEnvironment(), s.stopOnEntry)
+		if errObj, ok := result.(*object.Error); ok {
+			s.event("output", map[string]string{"category": "stderr", "output": errObj.Message + "\n"})
+			exitCode = 1
+		}
+		if err == ErrQuit {
+			exitCode = 1
+		}
+
+		s.event("exited", map[string]int{"exitCode": exitCode})
+		s.event("terminated", nil)
+	}()
+	return nil, ""
+}
+
+// Runs on the goroutine of the program, which waits for a request to resume.
+func (s *dapServer) programStopped(reason string) {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	s.event("stopped", map[string]interface{}{"reason": reason, "threadId": 1, "allThreadsStopped": true})
+	<-s.resume
+}
+
+// Reports whether the program is stopped, and so its stack can be looked at.
+func (s *dapServer) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+// Ends the program, if one runs, and waits for it.
+func (s *dapServer) disconnect() {
+	if s.done == nil {
+		return
+	}
+	s.session.Quit()
+	if s.isStopped() {
+		s.mu.Lock()
+		s.stopped = false
+		s.mu.Unlock()
+		s.resume <- struct{}{}
+	}
+	<-s.done
+}
+
+func resumeWith(resume func(*Session)) dapHandler {
+	return func(s *dapServer, args json.RawMessage) (interface{}, string) {
+		if !s.isStopped() {
+			return nil, "the program is not stopped"
+		}
+		resume(s.session)
+		return map[string]bool{"allThreadsContinued": true}, ""
+	}
+}
+
+func (s *dapServer) pause(json.RawMessage) (interface{}, string) {
+	if s.session == nil {
+		return nil, "no program launched"
+	}
+	s.session.Pause()
+	return nil, ""
+}
+
+func (s *dapServer) threads(json.RawMessage) (interface{}, string) {
+	return map[string]interface{}{
+		"threads": []map[string]interface{}{{"id": 1, "name": "main"}},
+	}, ""
+}
+
+// Frames have the ids of their position in Stack, and the bindings of frame i
+// the variables reference i+1, as references start at 1.
+func (s *dapServer) stackTrace(json.RawMessage) (interface{}, string) {
+	if !s.isStopped() {
+		return nil, "the program is not stopped"
+	}
+
+	frames := []map[string]interface{}{}
+	for i, f := range s.session.Stack() {
+		frame := map[string]interface{}{"id": i, "name": f.Name, "line": f.Line, "column": f.Column}
+		if f.Line != 0 {
+			frame["source"] = dapSource{Name: f.Name, Path: s.path}
+		}
+		frames = append(frames, frame)
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, ""
+}
+
+func (s *dapServer) scopes(args json.RawMessage) (interface{}, string) {
+	var a frameArguments
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err.Error()
+	}
+	if !s.isStopped() {
+		return nil, "the program is not stopped"
+	}
+
+	stack := s.session.Stack()
+	if a.FrameID < 0 || a.FrameID >= len(stack) {
+		return nil, "unknown frame"
+	}
+
+	globals := map[string]interface{}{"name": "Globals", "variablesReference": len(stack), "expensive": false}
+	scopes := []map[string]interface{}{globals}
+	if a.FrameID != len(stack)-1 {
+		locals := map[string]interface{}{"name": "Locals", "variablesReference": a.FrameID + 1, "expensive": false}
+		scopes = []map[string]interface{}{locals, globals}
+	}
+	return map[string]interface{}{"scopes": scopes}, ""
+}
+
+func (s *dapServer) variables(args json.RawMessage) (interface{}, string) {
+	var a frameArguments
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err.Error()
+	}
+	if !s.isStopped() {
+		return nil, "the program is not stopped"
+	}
+
+	frame := a.VariablesReference - 1
+	if frame < 0 || frame >= len(s.session.Stack()) {
+		return nil, "unknown variables reference"
+	}
+
+	bindings := s.session.Bindings(frame)
+	variables := []dapVariable{}
+	for _, name := range sortedNames(bindings) {
+		variables = append(variables, dapVariable{Name: name, Value: bindings[name].Inspect()})
+	}
+	return map[string]interface{}{"variables": variables}, ""
+}
+
+func (s *dapServer) evaluate(args json.RawMessage) (interface{}, string) {
+	var a frameArguments
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err.Error()
+	}
+	if !s.isStopped() {
+		return nil, "the program is not stopped"
+	}
+	if a.FrameID < 0 || a.FrameID >= len(s.session.Stack()) {
+		return nil, "unknown frame"
+	}
+
+	result, err := s.session.Evaluate(a.Expression, a.FrameID)
+	if err != nil {
+		return nil, err.Error()
+	}
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, errObj.Message
+	}
+	return map[string]interface{}{"result": result.Inspect(), "variablesReference": 0}, ""
+}
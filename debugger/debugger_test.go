@@ -0,0 +1,406 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/object"
+	"example/sawan/goInterpreter/parser"
+)
+
+const fibSource = `let fib = fn(n) {
+  if (n < 2) {
+    return n;
+  }
+  fib(n - 1) + fib(n - 2)
+};
+let a = fib(2); let b = a + 1;
+b
+`
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+// Runs source, answering each stop with the next of actions, and returns
+// where it stopped as "reason line depth".
+func stops(t *testing.T, source string, breakpoints []int, actions ...func(*Session)) []string {
+	t.Helper()
+
+	got := []string{}
+	var s *Session
+	s = New(parse(t, source), func(reason string) {
+		top := s.Stack()[0]
+		got = append(got, fmt.Sprintf("%s %d %d", reason, top.Line, len(s.Stack())))
+		if len(got) <= len(actions) {
+			actions[len(got)-1](s)
+		}
+	})
+	for _, line := range breakpoints {
+		if !s.SetBreakpoint(line) {
+			t.Fatalf("no statement on line %d", line)
+		}
+	}
+
+	result, err := s.Run(object.NewEnvironment(), true)
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if result.Inspect() != "2" {
+		t.Fatalf("wrong result. got=%s", result.Inspect())
+	}
+	return got
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		name        string
+		breakpoints []int
+		actions     []func(*Session)
+		expected    []string
+	}{
+		{
+			"continue",
+			nil,
+			[]func(*Session){(*Session).Continue},
+			[]string{"entry 1 1"},
+		},
+		{
+			"breakpoints",
+			[]int{3},
+			[]func(*Session){(*Session).Continue, (*Session).Continue, (*Session).Continue},
+			[]string{"entry 1 1", "breakpoint 3 3", "breakpoint 3 3"},
+		},
+		{
+			"step over",
+			nil,
+			[]func(*Session){(*Session).StepOver, (*Session).StepOver, (*Session).StepOver},
+			[]string{"entry 1 1", "step 7 1", "step 8 1"},
+		},
+		{
+			"step in",
+			nil,
+			[]func(*Session){(*Session).StepOver, (*Session).StepIn, (*Session).StepIn, (*Session).StepIn, (*Session).StepOut},
+			[]string{"entry 1 1", "step 7 1", "step 2 2", "step 5 2", "step 2 3", "step 5 2"},
+		},
+		{
+			"step out of the program",
+			[]int{5},
+			[]func(*Session){(*Session).Continue, (*Session).StepOut, (*Session).StepOut},
+			[]string{"entry 1 1", "breakpoint 5 2", "step 7 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := stops(t, fibSource, tt.breakpoints, tt.actions...)
+		if strings.Join(got, ", ") != strings.Join(tt.expected, ", ") {
+			t.Errorf("%s: wrong stops.\nexpected=%q\ngot=%q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	s := New(parse(t, fibSource), func(string) {})
+
+	for line, ok := range map[int]bool{1: true, 3: true, 4: false, 6: false, 7: true, 9: false} {
+		if s.SetBreakpoint(line) != ok {
+			t.Errorf("SetBreakpoint(%d) should report %t", line, ok)
+		}
+	}
+	s.ClearBreakpoint(3)
+	if got := s.Breakpoints(); fmt.Sprint(got) != "[1 7]" {
+		t.Errorf("wrong breakpoints. got=%v", got)
+	}
+}
+
+func TestInspectingFrames(t *testing.T) {
+	checked := false
+	var s *Session
+	s = New(parse(t, fibSource), func(reason string) {
+		if reason != ReasonBreakpoint {
+			s.Continue()
+			return
+		}
+		checked = true
+
+		var names []string
+		for _, f := range s.Stack() {
+			names = append(names, fmt.Sprintf("%s:%d", f.Name, f.Line))
+		}
+		if got := strings.Join(names, " "); got != "fib:3 fib:5 main:7" {
+			t.Errorf("wrong stack. got=%s", got)
+		}
+
+		if n := s.Bindings(0)["n"]; n == nil || n.Inspect() != "1" {
+			t.Errorf("wrong binding of n in frame 0. got=%v", n)
+		}
+		if _, ok := s.Bindings(2)["fib"]; !ok {
+			t.Errorf("fib is not bound in the program frame")
+		}
+
+		for _, tt := range []struct {
+			input    string
+			frame    int
+			expected string
+		}{
+			{"n * 10", 0, "10"},
+			{"n * 10", 1, "20"},
+			{"let m = n + 1; m", 1, "3"},
+			{"fib(10)", 0, "55"},
+			{"a", 2, "ERROR: identifier not found: a"},
+		} {
+			result, err := s.Evaluate(tt.input, tt.frame)
+			if err != nil {
+				t.Errorf("Evaluate(%q) failed: %s", tt.input, err)
+			} else if result.Inspect() != tt.expected {
+				t.Errorf("Evaluate(%q, %d) wrong. expected=%s, got=%s", tt.input, tt.frame, tt.expected, result.Inspect())
+			}
+		}
+		if _, err := s.Evaluate("let = 1", 0); err == nil {
+			t.Errorf("expected a parse error")
+		}
+		if _, ok := s.Bindings(1)["m"]; ok {
+			t.Errorf("Evaluate changed the bindings of the frame")
+		}
+		s.Quit()
+	})
+	s.SetBreakpoint(3)
+
+	if _, err := s.Run(object.NewEnvironment(), true); err != ErrQuit {
+		t.Errorf("Run should end with ErrQuit. got=%v", err)
+	}
+	if !checked {
+		t.Fatalf("the program did not stop at the breakpoint")
+	}
+}
+
+func TestCLI(t *testing.T) {
+	input := "b 3\nb 4\nc\nbt\np n\n\nfinish\nlocals\nframe 1\nq\n"
+	var out strings.Builder
+	status := RunCLI(parse(t, fibSource), fibSource, strings.NewReader(input), &out)
+
+	expected := []string{
+		"stopped at line 1 (entry)",
+		"breakpoint at line 3",
+		"no statement starts on line 4",
+		"stopped at line 3 (breakpoint)\n   3      return n;",
+		"#0 fib at line 3\n#1 fib at line 5\n#2 main at line 7",
+		"(monkey) 1\n(monkey) 1\n",
+		"stopped at line 5 (step)",
+		"fib = fn(n)",
+		"n = 2",
+		"#1 main at line 7",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("output does not contain %q:\n%s", e, out.String())
+		}
+	}
+	if status != 1 {
+		t.Errorf("quitting should exit with 1. got=%d", status)
+	}
+
+	out.Reset()
+	status = RunCLI(parse(t, fibSource), fibSource, strings.NewReader("c\n"), &out)
+	if status != 0 || !strings.HasSuffix(out.String(), "program finished\n") {
+		t.Errorf("wrong end of the program (status %d):\n%s", status, out.String())
+	}
+}
+
+// Talks to a DAP server running in the background.
+type dapClient struct {
+	t        *testing.T
+	in       io.WriteCloser
+	seq      int
+	messages chan map[string]interface{}
+}
+
+func startDAP(t *testing.T, sources map[string]string) (*dapClient, chan int) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &dapClient{t: t, in: inW, messages: make(chan map[string]interface{}, 100)}
+
+	load := func(path string) (*ast.Program, error) {
+		source, ok := sources[path]
+		if !ok {
+			return nil, fmt.Errorf("no such script %s", path)
+		}
+		return parse(t, source), nil
+	}
+
+	status := make(chan int, 1)
+	go func() {
+		status <- ServeDAP(inR, outW, load)
+		outW.Close()
+	}()
+
+	go func() {
+		r := bufio.NewReader(outR)
+		for {
+			var length int
+			if _, err := fmt.Fscanf(r, "Content-Length: %d\r\n\r\n", &length); err != nil {
+				close(c.messages)
+				return
+			}
+			body := make([]byte, length)
+			io.ReadFull(r, body)
+			msg := map[string]interface{}{}
+			json.Unmarshal(body, &msg)
+			c.messages <- msg
+		}
+	}()
+	return c, status
+}
+
+func (c *dapClient) send(command string, args map[string]interface{}) {
+	c.seq++
+	body, _ := json.Marshal(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// Waits for the response to command or for the event named by it, and
+// returns its body. Other messages are skipped.
+func (c *dapClient) await(kind, name string) map[string]interface{} {
+	c.t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				c.t.Fatalf("server stopped while waiting for %s %s", kind, name)
+			}
+			if msg["type"] != kind || (msg["command"] != name && msg["event"] != name) {
+				continue
+			}
+			if kind == "response" && msg["success"] != true {
+				c.t.Fatalf("%s failed: %v", name, msg["message"])
+			}
+			body, _ := msg["body"].(map[string]interface{})
+			return body
+		case <-timeout:
+			c.t.Fatalf("timed out waiting for %s %s", kind, name)
+		}
+	}
+}
+
+func TestDAP(t *testing.T) {
+	source := fibSource + "puts(b * 10);\n"
+	c, status := startDAP(t, map[string]string{"fib.mk": source})
+
+	c.send("initialize", map[string]interface{}{"adapterID": "monkey"})
+	if body := c.await("response", "initialize"); body["supportsConfigurationDoneRequest"] != true {
+		t.Errorf("wrong capabilities: %v", body)
+	}
+
+	c.send("launch", map[string]interface{}{"program": "fib.mk"})
+	c.await("response", "launch")
+	c.await("event", "initialized")
+
+	c.send("setBreakpoints", map[string]interface{}{
+		"source":      map[string]string{"path": "fib.mk"},
+		"breakpoints": []map[string]int{{"line": 3}, {"line": 4}},
+	})
+	body := c.await("response", "setBreakpoints")
+	if got := fmt.Sprint(body["breakpoints"]); got != "[map[line:3 verified:true] map[line:4 verified:false]]" {
+		t.Errorf("wrong breakpoints: %s", got)
+	}
+
+	c.send("configurationDone", nil)
+	if body := c.await("event", "stopped"); body["reason"] != "breakpoint" {
+		t.Errorf("wrong reason: %v", body)
+	}
+
+	c.send("stackTrace", map[string]interface{}{"threadId": 1})
+	frames := c.await("response", "stackTrace")["stackFrames"].([]interface{})
+	if len(frames) != 3 {
+		t.Fatalf("wrong number of frames: %v", frames)
+	}
+	top := frames[0].(map[string]interface{})
+	if top["name"] != "fib" || top["line"] != 3.0 {
+		t.Errorf("wrong top frame: %v", top)
+	}
+
+	c.send("scopes", map[string]interface{}{"frameId": 1})
+	scopes := c.await("response", "scopes")["scopes"].([]interface{})
+	if len(scopes) != 2 {
+		t.Fatalf("wrong scopes: %v", scopes)
+	}
+	locals := scopes[0].(map[string]interface{})
+	c.send("variables", map[string]interface{}{"variablesReference": locals["variablesReference"]})
+	variables := c.await("response", "variables")["variables"]
+	if got := fmt.Sprint(variables); got != "[map[name:n value:2 variablesReference:0]]" {
+		t.Errorf("wrong variables: %s", got)
+	}
+
+	c.send("evaluate", map[string]interface{}{"expression": "n + 40", "frameId": 0})
+	if body := c.await("response", "evaluate"); body["result"] != "41" {
+		t.Errorf("wrong evaluation: %v", body)
+	}
+
+	c.send("setBreakpoints", map[string]interface{}{"breakpoints": []map[string]int{}})
+	c.await("response", "setBreakpoints")
+	c.send("continue", map[string]interface{}{"threadId": 1})
+	c.await("response", "continue")
+
+	if body := c.await("event", "output"); body["output"] != "20\n" {
+		t.Errorf("wrong output: %v", body)
+	}
+	if body := c.await("event", "exited"); body["exitCode"] != 0.0 {
+		t.Errorf("wrong exit code: %v", body)
+	}
+	c.await("event", "terminated")
+
+	c.send("disconnect", nil)
+	c.await("response", "disconnect")
+	if code := <-status; code != 0 {
+		t.Errorf("wrong status: %d", code)
+	}
+	if evaluator.Stdout != os.Stdout {
+		t.Errorf("Stdout was not restored")
+	}
+}
+
+func TestDAPDisconnectWhileStopped(t *testing.T) {
+	c, status := startDAP(t, map[string]string{"fib.mk": fibSource})
+
+	c.send("initialize", nil)
+	c.await("response", "initialize")
+	c.send("launch", map[string]interface{}{"program": "missing.mk"})
+	for msg := range c.messages {
+		if msg["command"] == "launch" {
+			if msg["success"] != false || msg["message"] != "no such script missing.mk" {
+				t.Errorf("launching a missing script should fail: %v", msg)
+			}
+			break
+		}
+	}
+
+	c.send("launch", map[string]interface{}{"program": "fib.mk", "stopOnEntry": true})
+	c.await("response", "launch")
+	c.send("configurationDone", nil)
+	if body := c.await("event", "stopped"); body["reason"] != "entry" {
+		t.Errorf("wrong reason: %v", body)
+	}
+
+	c.send("disconnect", nil)
+	c.await("event", "terminated")
+	c.await("response", "disconnect")
+	if code := <-status; code != 0 {
+		t.Errorf("wrong status: %d", code)
+	}
+}
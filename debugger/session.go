@@ -0,0 +1,320 @@
+// Package debugger runs Monkey programs under control of a user, stopping
+// at breakpoints and stepping through them a line at a time.
+package debugger
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/object"
+	"example/sawan/goInterpreter/parser"
+)
+
+// Why the program stopped, as passed to the stopped function of a Session.
+const (
+	ReasonEntry      = "entry"
+	ReasonBreakpoint = "breakpoint"
+	ReasonStep       = "step"
+	ReasonPause      = "pause"
+)
+
+type mode int
+
+const (
+	running mode = iota
+	entry
+	stepIn
+	stepOver
+	stepOut
+	pausing
+	quitting
+)
+
+/*
+One call in the call stack of a stopped program.
+
+Name: The name the function was called by, main for the program itself
+Function: The function called, nil for the program
+Env: The environment of the call
+Line, Column: Where the statement running in the call starts, 0 before the
+first one or while it runs code of an imported module
+midLine: Set when the program stopped in the call after a call it made
+returned, so steps stop at its next statement even on the same line
+*/
+type Frame struct {
+	Name     string
+	Function *object.Function
+	Env      *object.Environment
+	Line     int
+	Column   int
+
+	midLine bool
+}
+
+/*
+Debugs one run of a program. The session stops the program only at
+statements of the program itself, never in imported modules, and at most
+once per line in each call.
+
+statements: Statements of the program
+lines: Lines on which one of them starts
+stopped: Called when the program stops, on the goroutine running it. The
+program resumes when it returns, the way Continue, a Step method or Quit
+called before asked for.
+mu: Guards breakpoints and mode, which can change while the program runs
+depth: Depth of the stack when the last step began
+*/
+type Session struct {
+	program    *ast.Program
+	statements map[ast.Statement]bool
+	lines      map[int]bool
+	stopped    func(reason string)
+
+	mu          sync.Mutex
+	breakpoints map[int]bool
+	mode        mode
+
+	stack []*Frame
+	depth int
+}
+
+// Creates a session for program, which must have its macros expanded.
+func New(program *ast.Program, stopped func(reason string)) *Session {
+	s := &Session{
+		program:     program,
+		statements:  map[ast.Statement]bool{},
+		lines:       map[int]bool{},
+		stopped:     stopped,
+		breakpoints: map[int]bool{},
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if stmt, ok := node.(ast.Statement); ok {
+			if _, isBlock := stmt.(*ast.BlockStatement); !isBlock {
+				s.statements[stmt] = true
+				s.lines[ast.TokenOf(stmt).Line] = true
+			}
+		}
+		return true
+	})
+	return s
+}
+
+// Returned by Run when the program was ended by Quit.
+var ErrQuit = errors.New("quit")
+
+// Runs the program in env and returns its result, stopping first at its
+// first statement when stopOnEntry is set.
+func (s *Session) Run(env *object.Environment, stopOnEntry bool) (result object.Object, err error) {
+	s.mu.Lock()
+	s.mode = running
+	if stopOnEntry {
+		s.mode = entry
+	}
+	s.mu.Unlock()
+	s.stack = []*Frame{{Name: "main", Env: env}}
+
+	evaluator.SetDebugger(s)
+	defer func() {
+		evaluator.SetDebugger(nil)
+		if r := recover(); r != nil {
+			if r != ErrQuit {
+				panic(r)
+			}
+			result, err = nil, ErrQuit
+		}
+	}()
+
+	return evaluator.Eval(s.program, env), nil
+}
+
+// Sets a breakpoint on line. Reports false, setting none, when no
+// statement starts on it.
+func (s *Session) SetBreakpoint(line int) bool {
+	if !s.lines[line] {
+		return false
+	}
+	s.mu.Lock()
+	s.breakpoints[line] = true
+	s.mu.Unlock()
+	return true
+}
+
+func (s *Session) ClearBreakpoint(line int) {
+	s.mu.Lock()
+	delete(s.breakpoints, line)
+	s.mu.Unlock()
+}
+
+func (s *Session) ClearBreakpoints() {
+	s.mu.Lock()
+	s.breakpoints = map[int]bool{}
+	s.mu.Unlock()
+}
+
+// Returns the lines with a breakpoint, in order.
+func (s *Session) Breakpoints() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lines := []int{}
+	for line := range s.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Resumes the program until it reaches a breakpoint.
+func (s *Session) Continue() { s.setMode(running) }
+
+// Resumes the program until it reaches another line, in the current call or
+// in one it makes.
+func (s *Session) StepIn() { s.step(stepIn) }
+
+// Resumes the program until it reaches another line of the current call, or
+// the call returns.
+func (s *Session) StepOver() { s.step(stepOver) }
+
+// Resumes the program until the current call returns.
+func (s *Session) StepOut() { s.step(stepOut) }
+
+// Stops the program at the next line it reaches. Unlike the other methods,
+// it can be called while the program runs.
+func (s *Session) Pause() { s.setMode(pausing) }
+
+// Ends the program once it resumes, or at the next line it reaches when it
+// runs.
+func (s *Session) Quit() { s.setMode(quitting) }
+
+func (s *Session) setMode(m mode) {
+	s.mu.Lock()
+	s.mode = m
+	s.mu.Unlock()
+}
+
+// Only called while the program is stopped, as it looks at the stack.
+func (s *Session) step(m mode) {
+	s.setMode(m)
+	s.depth = len(s.stack)
+}
+
+// Returns the calls of the stopped program, innermost first.
+func (s *Session) Stack() []*Frame {
+	frames := make([]*Frame, 0, len(s.stack))
+	for i := len(s.stack) - 1; i >= 0; i-- {
+		frames = append(frames, s.stack[i])
+	}
+	return frames
+}
+
+// Returns the bindings visible in frame, numbered as in Stack.
+func (s *Session) Bindings(frame int) map[string]object.Object {
+	f := s.Stack()[frame]
+	return evaluator.Bindings(f.Function, f.Env)
+}
+
+// Evaluates input, one or more statements, in the environment of frame,
+// numbered as in Stack. Parse errors are returned as error.
+func (s *Session) Evaluate(input string, frame int) (object.Object, error) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "; "))
+	}
+
+	f := s.Stack()[frame]
+	result := evaluator.EvalIn(program, f.Function, f.Env)
+	if result == nil {
+		result = evaluator.NULL
+	}
+	return result, nil
+}
+
+func (s *Session) Statement(stmt ast.Statement, env *object.Environment) {
+	frame := s.stack[len(s.stack)-1]
+	if !s.statements[stmt] {
+		return
+	}
+
+	tok := ast.TokenOf(stmt)
+	newLine, midLine := tok.Line != frame.Line, frame.midLine
+	frame.Line, frame.Column, frame.midLine = tok.Line, tok.Column, false
+	if !newLine && !midLine {
+		return
+	}
+
+	s.mu.Lock()
+	reason := s.stopReason(tok.Line, newLine)
+	quit := s.mode == quitting
+	s.mu.Unlock()
+
+	if quit {
+		panic(ErrQuit)
+	}
+	if reason != "" {
+		s.stop(reason)
+	}
+}
+
+// Hands the stopped program to the user, and ends it when they quit.
+func (s *Session) stop(reason string) {
+	// until the user says otherwise
+	s.setMode(running)
+	s.stopped(reason)
+
+	s.mu.Lock()
+	quit := s.mode == quitting
+	s.mu.Unlock()
+	if quit {
+		panic(ErrQuit)
+	}
+}
+
+// Breakpoints only stop the program once on their line, so not when it
+// reaches another statement of it after a call returned.
+func (s *Session) stopReason(line int, newLine bool) string {
+	switch {
+	case s.mode == entry:
+		return ReasonEntry
+	case s.mode == pausing:
+		return ReasonPause
+	case newLine && s.breakpoints[line]:
+		return ReasonBreakpoint
+	case s.mode == stepIn,
+		s.mode == stepOver && len(s.stack) <= s.depth,
+		s.mode == stepOut && len(s.stack) < s.depth:
+		return ReasonStep
+	}
+	return ""
+}
+
+func (s *Session) Call(call *ast.CallExpression, fn *object.Function, env *object.Environment, tail bool) {
+	frame := &Frame{Name: evaluator.CallName(call), Function: fn, Env: env}
+	if tail {
+		s.stack[len(s.stack)-1] = frame
+	} else {
+		s.stack = append(s.stack, frame)
+	}
+}
+
+// Steps end as soon as the call they were in returns, in the middle of the
+// line that made it.
+func (s *Session) Return(fn *object.Function) {
+	s.stack = s.stack[:len(s.stack)-1]
+	caller := s.stack[len(s.stack)-1]
+
+	s.mu.Lock()
+	step := s.mode == stepIn || (s.mode == stepOver || s.mode == stepOut) && len(s.stack) < s.depth
+	s.mu.Unlock()
+
+	if step && caller.Line != 0 {
+		caller.midLine = true
+		s.stop(ReasonStep)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"example/sawan/goInterpreter/diff"
+)
+
+const diffContext = 3
+
+// Compares a and b line by line and returns the differences in unified
+// diff format, or "" when they are equal.
+func unifiedDiff(path, a, b string) string {
+	edits := diff.Lines(diff.SplitLines(a), diff.SplitLines(b))
+
+	var out strings.Builder
+	for start := 0; start < len(edits); {
+		if edits[start].Op == ' ' {
+			start++
+			continue
+		}
+
+		// grow the hunk while changes are within twice the context
+		first := start - diffContext
+		if first < 0 {
+			first = 0
+		}
+		end := start
+		for k := start; k < len(edits); k++ {
+			if edits[k].Op != ' ' {
+				end = k + 1
+			} else if k-end >= 2*diffContext {
+				break
+			}
+		}
+		last := end + diffContext
+		if last > len(edits) {
+			last = len(edits)
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s (formatted)\n", path, path)
+		}
+
+		oldLines, newLines := 0, 0
+		for _, e := range edits[first:last] {
+			if e.Op != '+' {
+				oldLines++
+			}
+			if e.Op != '-' {
+				newLines++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", edits[first].I+1, oldLines, edits[first].J+1, newLines)
+		for _, e := range edits[first:last] {
+			fmt.Fprintf(&out, "%c%s\n", e.Op, e.Line)
+		}
+
+		start = last
+	}
+
+	return out.String()
+}
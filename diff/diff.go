@@ -0,0 +1,141 @@
+// Package diff compares texts line by line.
+package diff
+
+import "strings"
+
+/*
+One step of turning a text into another.
+
+Op: ' ' for a line both have, '-' for one only the old text has and '+' for
+one only the new text has
+I, J: How many lines of the old and the new text come before it
+*/
+type Edit struct {
+	Op   byte
+	Line string
+	I, J int
+}
+
+// Returns the edits that turn the lines x into the lines y, keeping as many
+// lines as possible. Removals come before the additions replacing them.
+func Lines(x, y []string) []Edit {
+	// the lines both texts start and end with are kept without searching
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	edits := []Edit{}
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, Edit{' ', x[i], i, i})
+	}
+	edits = append(edits, shortest(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix], prefix, prefix)...)
+	for i, j := len(x)-suffix, len(y)-suffix; i < len(x); i, j = i+1, j+1 {
+		edits = append(edits, Edit{' ', x[i], i, j})
+	}
+	return edits
+}
+
+/*
+Finds the fewest edits turning x into y with Myers' algorithm, which takes
+time and memory growing with the number of edits rather than with the
+product of the lengths. i0 and j0 are the lines before x and y.
+
+A path goes through the points (i, j), having kept or removed the first i
+lines of x and kept or added the first j lines of y. trace[d][k+d] is how
+far along x the path with d edits that gets furthest on the diagonal
+k = i - j reaches, or -1 when no path does.
+*/
+func shortest(x, y []string, i0, j0 int) []Edit {
+	n, m := len(x), len(y)
+	trace := [][]int{}
+	for d := 0; ; d++ {
+		// only every other diagonal can be reached with d edits
+		v := make([]int, 2*d+1)
+		for index := range v {
+			v[index] = -1
+		}
+		for k := -d; k <= d; k += 2 {
+			i, _, ok := extend(trace, d, k, n, m)
+			if !ok {
+				continue
+			}
+			for i < n && i-k < m && x[i] == y[i-k] {
+				i++
+			}
+			v[k+d] = i
+		}
+		trace = append(trace, v)
+		if n-m >= -d && n-m <= d && v[n-m+d] == n {
+			break
+		}
+	}
+
+	// walk back from the end, collecting the edits in reverse
+	reversed := []Edit{}
+	i, j := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		k := i - j
+		start, down, _ := extend(trace, d, k, n, m)
+		for i > start {
+			i--
+			j--
+			reversed = append(reversed, Edit{' ', x[i], i0 + i, j0 + j})
+		}
+		if d == 0 {
+			break
+		}
+		if down {
+			j--
+			reversed = append(reversed, Edit{'+', y[j], i0 + i, j0 + j})
+		} else {
+			i--
+			reversed = append(reversed, Edit{'-', x[i], i0 + i, j0 + j})
+		}
+	}
+
+	edits := make([]Edit, len(reversed))
+	for index, e := range reversed {
+		edits[len(edits)-1-index] = e
+	}
+	return edits
+}
+
+// Returns where on the diagonal k a path with d edits gets by its last edit,
+// before the lines after it that both texts have, and whether that edit
+// adds a line rather than removing one. The addition is taken when both get
+// as far, so the path removes lines before adding the ones replacing them.
+// ok is false when neither stays within the texts.
+func extend(trace [][]int, d, k, n, m int) (i int, down bool, ok bool) {
+	if d == 0 {
+		return 0, false, k == 0
+	}
+	prev := trace[d-1]
+
+	// adding a line of y comes down from the diagonal k+1, removing one
+	// of x comes right from k-1
+	above, left := -1, -1
+	if k+1 <= d-1 && prev[k+1+d-1] >= 0 && prev[k+1+d-1]-(k+1) < m {
+		above = prev[k+1+d-1]
+	}
+	if k-1 >= -(d-1) && prev[k-1+d-1] >= 0 && prev[k-1+d-1] < n {
+		left = prev[k-1+d-1] + 1
+	}
+
+	if above >= 0 && above >= left {
+		return above, true, true
+	}
+	return left, false, left >= 0
+}
+
+// Splits s into lines, without the newline ending the last one.
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,127 @@
+package diff
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"", "", ""},
+		{"a\nb\n", "a\nb\n", " a  b"},
+		{"a\nb\nc", "a\nc", " a -b  c"},
+		{"a\nc", "a\nb\nc", " a +b  c"},
+		{"a\nb", "a\nB", " a -b +B"},
+		{"", "x\n", "+x"},
+	}
+
+	for _, tt := range tests {
+		edits := []string{}
+		for _, e := range Lines(SplitLines(tt.a), SplitLines(tt.b)) {
+			edits = append(edits, string(e.Op)+e.Line)
+		}
+		if got := strings.Join(edits, " "); got != tt.want {
+			t.Errorf("Lines(%q, %q) wrong. want=%q, got=%q", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
+
+func TestEditPositions(t *testing.T) {
+	edits := Lines([]string{"a", "b", "c"}, []string{"x", "a", "c"})
+
+	want := []Edit{{'+', "x", 0, 0}, {' ', "a", 0, 1}, {'-', "b", 1, 2}, {' ', "c", 2, 2}}
+	if len(edits) != len(want) {
+		t.Fatalf("wrong number of edits. want=%d, got=%d", len(want), len(edits))
+	}
+	for i := range want {
+		if edits[i] != want[i] {
+			t.Errorf("edits[%d] wrong. want=%+v, got=%+v", i, want[i], edits[i])
+		}
+	}
+}
+
+// The edits must turn one text into the other and keep as many lines as the
+// longest common subsequence has.
+func TestLinesKeepMost(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	text := func() []string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(3)))
+		}
+		return lines
+	}
+
+	for n := 0; n < 500; n++ {
+		x, y := text(), text()
+		edits := Lines(x, y)
+
+		var old, new []string
+		kept := 0
+		for _, e := range edits {
+			if e.Op != '+' {
+				if e.I != len(old) {
+					t.Fatalf("wrong position %+v for %q -> %q", e, x, y)
+				}
+				old = append(old, e.Line)
+			}
+			if e.Op != '-' {
+				if e.J != len(new) {
+					t.Fatalf("wrong position %+v for %q -> %q", e, x, y)
+				}
+				new = append(new, e.Line)
+			}
+			if e.Op == ' ' {
+				kept++
+			}
+		}
+		if strings.Join(old, "") != strings.Join(x, "") || strings.Join(new, "") != strings.Join(y, "") {
+			t.Fatalf("edits do not turn %q into %q: %v", x, y, edits)
+		}
+		if want := lcs(x, y); kept != want {
+			t.Fatalf("kept %d lines of %q and %q, want %d", kept, x, y, want)
+		}
+	}
+}
+
+func lcs(x, y []string) int {
+	lengths := make([][]int, len(x)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] > lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	return lengths[0][0]
+}
+
+// Large files with a few changes spread over them are compared without a
+// table of every pair of lines.
+func TestLinesLarge(t *testing.T) {
+	x := make([]string, 200000)
+	for i := range x {
+		x[i] = strconv.Itoa(i)
+	}
+	y := append([]string{}, x...)
+	y[10] = "changed"
+	y[199990] = "changed"
+
+	edits := Lines(x, y)
+	if len(edits) != len(x)+2 {
+		t.Errorf("wrong number of edits. want=%d, got=%d", len(x)+2, len(edits))
+	}
+}
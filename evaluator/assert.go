@@ -0,0 +1,133 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/diff"
+	"example/sawan/goInterpreter/object"
+)
+
+// The assertions fail with an error, which applyFunction marks with the
+// position of the call making the assertion.
+func init() {
+	for name, builtin := range assertBuiltins {
+		builtins[name] = builtin
+		assertions[builtin] = true
+	}
+}
+
+var assertions = map[*object.Builtin]bool{}
+
+var assertBuiltins = map[string]*object.Builtin{
+	"assert": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			if isTruthy(args[0]) {
+				return NULL
+			}
+			return assertionFailed("assertion failed%s", assertMessage(args[1:]))
+		},
+	},
+	"assert_eq": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 2 || len(args) > 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+			if object.Equal(args[0], args[1]) {
+				return NULL
+			}
+			return assertionFailed("assert_eq failed%s%s", assertMessage(args[2:]), inspectDiff(args[1], args[0]))
+		},
+	},
+	"assert_error": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			switch args[0].(type) {
+			case *object.Function, *object.Builtin:
+			default:
+				return newError("first argument to `assert_error` must be FUNCTION, got %s", args[0].Type())
+			}
+			var substring *object.String
+			if len(args) == 2 {
+				s, ok := args[1].(*object.String)
+				if !ok {
+					return newError("second argument to `assert_error` must be STRING, got %s", args[1].Type())
+				}
+				substring = s
+			}
+
+			result := Call(args[0])
+			err, ok := result.(*object.Error)
+			if !ok {
+				return assertionFailed("assert_error failed: got %s, want an error", result.Inspect())
+			}
+			if substring != nil && !strings.Contains(err.Message, substring.Value) {
+				return assertionFailed("assert_error failed: error %q does not contain %q", err.Message, substring.Value)
+			}
+			return NULL
+		},
+	},
+}
+
+// Returns the error of a failed assertion, which catch and the test runner
+// can tell from errors raised by the program under test.
+func assertionFailed(format string, a ...interface{}) *object.Error {
+	err := newError(format, a...)
+	err.Kind = "assertion"
+	return err
+}
+
+// Puts the position of call into err, the failure of an assertion it made.
+func locateAssertion(call *ast.CallExpression, err *object.Error) {
+	if err.Line == 0 {
+		tok := ast.TokenOf(call.Function)
+		err.Line, err.Column = tok.Line, tok.Column
+	}
+}
+
+// The optional message of an assertion, as it goes after its failure.
+func assertMessage(args []object.Object) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return ": " + args[0].Inspect()
+}
+
+// Shows how actual differs from expected. Values on one line are written
+// one above the other, with a mark under the first character that differs;
+// longer ones as the lines to remove from expected and to add to get actual.
+func inspectDiff(expected, actual object.Object) string {
+	want, got := expected.Inspect(), actual.Inspect()
+
+	if want == got {
+		// values that look the same, like 1 and "1"
+		return fmt.Sprintf("\n  expected: %s (%s)\n    actual: %s (%s)", want, expected.Type(), got, actual.Type())
+	}
+
+	if !strings.Contains(want+got, "\n") {
+		same := 0
+		for same < len(want) && same < len(got) && want[same] == got[same] {
+			same++
+		}
+		// back to the start of the character the two differ in
+		for same > 0 && same < len(want) && !utf8.RuneStart(want[same]) {
+			same--
+		}
+		marker := strings.Repeat(" ", utf8.RuneCountInString(want[:same]))
+		return fmt.Sprintf("\n  expected: %s\n    actual: %s\n            %s^", want, got, marker)
+	}
+
+	var out strings.Builder
+	out.WriteString("\n  --- expected\n  +++ actual")
+	for _, e := range diff.Lines(diff.SplitLines(want), diff.SplitLines(got)) {
+		fmt.Fprintf(&out, "\n  %c %s", e.Op, e.Line)
+	}
+	return out.String()
+}
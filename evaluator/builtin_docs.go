@@ -0,0 +1,117 @@
+package evaluator
+
+import "sort"
+
+// Describes a builtin for tools such as the linter and the language server.
+// MaxArgs is -1 for builtins that take any number of arguments.
+type BuiltinDoc struct {
+	Signature string
+	MinArgs   int
+	MaxArgs   int
+	Doc       string
+}
+
+var builtinDocs = map[string]BuiltinDoc{
+	"len":     {"len(value)", 1, 1, "Returns the number of characters of a string or of elements of an array."},
+	"first":   {"first(array)", 1, 1, "Returns the first element of array, or null when it is empty."},
+	"last":    {"last(array)", 1, 1, "Returns the last element of array, or null when it is empty."},
+	"rest":    {"rest(array)", 1, 1, "Returns a new array with every element of array but the first."},
+	"push":    {"push(array, value)", 2, 2, "Returns a new array with value appended to array."},
+	"puts":    {"puts(values...)", 0, -1, "Prints each value on its own line."},
+	"keys":    {"keys(hash)", 1, 1, "Returns the keys of hash in insertion order."},
+	"values":  {"values(hash)", 1, 1, "Returns the values of hash in insertion order."},
+	"entries": {"entries(hash)", 1, 1, "Returns the [key, value] pairs of hash in insertion order."},
+	"has":     {"has(hash, key)", 2, 2, "Reports whether hash contains key."},
+	"delete":  {"delete(hash, key)", 2, 2, "Returns a copy of hash without key."},
+	"merge":   {"merge(hashes...)", 1, -1, "Returns a new hash with the pairs of all hashes, later ones winning."},
+
+	"map":     {"map(array, fn)", 2, 2, "Returns the results of calling fn on each element."},
+	"filter":  {"filter(array, fn)", 2, 2, "Returns the elements for which fn returns a truthy value."},
+	"reduce":  {"reduce(array, fn, initial)", 3, 3, "Folds array into one value by calling fn(accumulator, element), starting from initial."},
+	"find":    {"find(array, fn)", 2, 2, "Returns the first element for which fn is truthy, or null."},
+	"any":     {"any(array, fn)", 2, 2, "Reports whether fn is truthy for some element."},
+	"all":     {"all(array, fn)", 2, 2, "Reports whether fn is truthy for every element."},
+	"sort":    {"sort(array, compare?)", 1, 2, "Returns a sorted copy of array, ordered by < or by compare(a, b)."},
+	"zip":     {"zip(arrays...)", 1, -1, "Returns arrays of the elements at the same index, as long as the shortest array."},
+	"range":   {"range(end) | range(start, end, step?)", 1, 3, "Returns the integers from start up to, but not including, end."},
+	"flatten": {"flatten(array, depth?)", 1, 2, "Returns array with nested arrays spliced in, up to depth levels."},
+	"unique":  {"unique(array)", 1, 1, "Returns array without repeated elements, keeping the first of each."},
+
+	"split":       {"split(string, separator?)", 1, 2, "Splits string around separator, or around whitespace when it is left out."},
+	"join":        {"join(array, separator?)", 1, 2, "Joins the elements of array into a string."},
+	"trim":        {"trim(string, cutset?)", 1, 2, "Removes surrounding whitespace, or the characters in cutset."},
+	"upper":       {"upper(string)", 1, 1, "Returns string in upper case."},
+	"lower":       {"lower(string)", 1, 1, "Returns string in lower case."},
+	"replace":     {"replace(string, old, new, count?)", 3, 4, "Replaces count, or all, occurrences of old by new."},
+	"contains":    {"contains(string, substring)", 2, 2, "Reports whether substring is within string."},
+	"starts_with": {"starts_with(string, prefix)", 2, 2, "Reports whether string begins with prefix."},
+	"ends_with":   {"ends_with(string, suffix)", 2, 2, "Reports whether string ends with suffix."},
+	"index_of":    {"index_of(string, substring)", 2, 2, "Returns the character index of substring in string, or -1."},
+	"repeat":      {"repeat(string, count)", 2, 2, "Returns count copies of string."},
+	"chars":       {"chars(string)", 1, 1, "Returns the characters of string as an array."},
+	"format":      {"format(template, values...)", 1, -1, "Formats values with printf style verbs."},
+
+	"abs":    {"abs(number)", 1, 1, "Returns the absolute value of number."},
+	"min":    {"min(numbers...)", 1, -1, "Returns the smallest number, given as arguments or as one array."},
+	"max":    {"max(numbers...)", 1, -1, "Returns the largest number, given as arguments or as one array."},
+	"pow":    {"pow(base, exponent)", 2, 2, "Returns base raised to exponent."},
+	"sqrt":   {"sqrt(number)", 1, 1, "Returns the square root of number."},
+	"sin":    {"sin(radians)", 1, 1, "Returns the sine of the angle."},
+	"cos":    {"cos(radians)", 1, 1, "Returns the cosine of the angle."},
+	"tan":    {"tan(radians)", 1, 1, "Returns the tangent of the angle."},
+	"asin":   {"asin(number)", 1, 1, "Returns the arcsine of number in radians."},
+	"acos":   {"acos(number)", 1, 1, "Returns the arccosine of number in radians."},
+	"atan":   {"atan(number)", 1, 1, "Returns the arctangent of number in radians."},
+	"atan2":  {"atan2(y, x)", 2, 2, "Returns the angle of the point (x, y) in radians."},
+	"log":    {"log(number)", 1, 1, "Returns the natural logarithm of number."},
+	"exp":    {"exp(number)", 1, 1, "Returns e raised to number."},
+	"floor":  {"floor(number)", 1, 1, "Rounds number down to an integer."},
+	"ceil":   {"ceil(number)", 1, 1, "Rounds number up to an integer."},
+	"round":  {"round(number)", 1, 1, "Rounds number to the nearest integer, halves away from zero."},
+	"random": {"random() | random(end) | random(start, end)", 0, 2, "Returns a float in [0, 1), or an integer in [start, end)."},
+	"seed":   {"seed(integer)", 1, 1, "Seeds the generator behind random so runs repeat."},
+
+	"json_parse":     {"json_parse(string)", 1, 1, "Decodes a JSON document into hashes, arrays and scalars."},
+	"json_stringify": {"json_stringify(value, indent?)", 1, 2, "Encodes value as JSON, indented by indent when given."},
+
+	"error": {"error(message, kind?)", 1, 2, "Returns an error to throw: a hash of its message, kind (error by default) and stack."},
+
+	"assert":       {"assert(condition, message?)", 1, 2, "Fails with message unless condition is truthy."},
+	"assert_eq":    {"assert_eq(actual, expected, message?)", 2, 3, "Fails with the difference between the values unless they are equal."},
+	"assert_error": {"assert_error(fn, substring?)", 1, 2, "Calls fn and fails unless it returns an error, containing substring when given."},
+
+	"quote":   {"quote(expression)", 1, 1, "Returns expression unevaluated, with unquote calls spliced in."},
+	"unquote": {"unquote(expression)", 1, 1, "Inside quote, evaluates expression and splices in the result."},
+}
+
+// Returns the documentation of the builtin called name.
+func LookupBuiltinDoc(name string) (BuiltinDoc, bool) {
+	doc, ok := builtinDocs[name]
+	return doc, ok
+}
+
+// Returns the names of all builtins and constants, sorted.
+func BuiltinNames() []string {
+	names := []string{}
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range constants {
+		names = append(names, name)
+	}
+	names = append(names, "quote", "unquote")
+	sort.Strings(names)
+	return names
+}
+
+// Reports whether name is defined without being declared: a builtin, a
+// constant or one of quote and unquote.
+func IsPredeclared(name string) bool {
+	if _, ok := builtins[name]; ok {
+		return true
+	}
+	if _, ok := constants[name]; ok {
+		return true
+	}
+	return name == "quote" || name == "unquote"
+}
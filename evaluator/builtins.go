@@ -2,10 +2,17 @@ package evaluator
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
 
 	"example/sawan/goInterpreter/object"
 )
 
+// Where puts writes. Tools that use stdout for something else, like talking
+// to an editor, send it elsewhere.
+var Stdout io.Writer = os.Stdout
+
 var builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
@@ -16,7 +23,7 @@ var builtins = map[string]*object.Builtin{
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
@@ -79,12 +86,12 @@ var builtins = map[string]*object.Builtin{
 	},
 	"push": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
 			}
 
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `rest` must be ARRAY, got %s", args[0].Type())
+				return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
 			}
 
 			arr := args[0].(*object.Array)
@@ -95,10 +102,122 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},
+	"keys": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if args[0].Type() != object.HASH_OBJ {
+				return newError("argument to `keys` must be HASH, got %s", args[0].Type())
+			}
+
+			pairs := args[0].(*object.Hash).Pairs()
+			elements := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = pair.Key
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"values": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if args[0].Type() != object.HASH_OBJ {
+				return newError("argument to `values` must be HASH, got %s", args[0].Type())
+			}
+
+			pairs := args[0].(*object.Hash).Pairs()
+			elements := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = pair.Value
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"entries": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if args[0].Type() != object.HASH_OBJ {
+				return newError("argument to `entries` must be HASH, got %s", args[0].Type())
+			}
+
+			pairs := args[0].(*object.Hash).Pairs()
+			elements := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = &object.Array{Elements: []object.Object{pair.Key, pair.Value}}
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"has": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			if args[0].Type() != object.HASH_OBJ {
+				return newError("argument to `has` must be HASH, got %s", args[0].Type())
+			}
+
+			key, ok := object.AsHashable(args[1])
+			if !ok {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+
+			_, ok = args[0].(*object.Hash).Get(key)
+			return nativeBoolToBooleanObject(ok)
+		},
+	},
+	"delete": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			if args[0].Type() != object.HASH_OBJ {
+				return newError("argument to `delete` must be HASH, got %s", args[0].Type())
+			}
+
+			key, ok := object.AsHashable(args[1])
+			if !ok {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+
+			hash := args[0].(*object.Hash).Copy()
+			hash.Delete(key)
+			return hash
+		},
+	},
+	"merge": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 {
+				return newError("wrong number of arguments. got=%d, want at least 1", len(args))
+			}
+
+			merged := object.NewHash()
+			for _, arg := range args {
+				if arg.Type() != object.HASH_OBJ {
+					return newError("argument to `merge` must be HASH, got %s", arg.Type())
+				}
+
+				for _, pair := range arg.(*object.Hash).Pairs() {
+					merged.Set(pair.Key.(object.Hashable), pair.Value)
+				}
+			}
+			return merged
+		},
+	},
 	"puts": {
 		Fn: func(args ...object.Object) object.Object {
 			for _, args := range args {
-				fmt.Println(args.Inspect())
+				fmt.Fprintln(Stdout, args.Inspect())
 			}
 
 			return NULL
@@ -0,0 +1,394 @@
+package evaluator
+
+import (
+	"sort"
+
+	"example/sawan/goInterpreter/object"
+)
+
+// The collection builtins call back into Monkey functions. applyFunction ends
+// up referring to the builtins table itself, so they are added in init to
+// avoid an initialization cycle.
+func init() {
+	for name, builtin := range collectionBuiltins {
+		builtins[name] = builtin
+	}
+}
+
+// Calls fn, which can be a Monkey function or a builtin, with args. It lets
+// builtins, including ones defined outside this package, invoke closures.
+func Call(fn object.Object, args ...object.Object) object.Object {
+	if result := applyFunction(nil, fn, args); result != nil {
+		return result
+	}
+	return NULL
+}
+
+// The most elements a builtin creates an array of, so a runaway range is an
+// error rather than a crash.
+const maxArrayLength = 1 << 27
+
+var collectionBuiltins = map[string]*object.Builtin{
+	"map": {
+		Fn: func(args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArgs("map", args)
+			if err != nil {
+				return err
+			}
+
+			elements := make([]object.Object, len(arr.Elements))
+			for i, el := range arr.Elements {
+				result := Call(fn, el)
+				if isError(result) {
+					return result
+				}
+				elements[i] = result
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"filter": {
+		Fn: func(args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArgs("filter", args)
+			if err != nil {
+				return err
+			}
+
+			elements := []object.Object{}
+			for _, el := range arr.Elements {
+				result := Call(fn, el)
+				if isError(result) {
+					return result
+				}
+				if isTruthy(result) {
+					elements = append(elements, el)
+				}
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"reduce": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+
+			arr, fn, err := arrayAndFunctionArgs("reduce", args[:2])
+			if err != nil {
+				return err
+			}
+
+			acc := args[2]
+			for _, el := range arr.Elements {
+				acc = Call(fn, acc, el)
+				if isError(acc) {
+					return acc
+				}
+			}
+			return acc
+		},
+	},
+	"find": {
+		Fn: func(args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArgs("find", args)
+			if err != nil {
+				return err
+			}
+
+			for _, el := range arr.Elements {
+				result := Call(fn, el)
+				if isError(result) {
+					return result
+				}
+				if isTruthy(result) {
+					return el
+				}
+			}
+			return NULL
+		},
+	},
+	"any": {
+		Fn: func(args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArgs("any", args)
+			if err != nil {
+				return err
+			}
+
+			for _, el := range arr.Elements {
+				result := Call(fn, el)
+				if isError(result) {
+					return result
+				}
+				if isTruthy(result) {
+					return TRUE
+				}
+			}
+			return FALSE
+		},
+	},
+	"all": {
+		Fn: func(args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArgs("all", args)
+			if err != nil {
+				return err
+			}
+
+			for _, el := range arr.Elements {
+				result := Call(fn, el)
+				if isError(result) {
+					return result
+				}
+				if !isTruthy(result) {
+					return FALSE
+				}
+			}
+			return TRUE
+		},
+	},
+	"sort": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to `sort` must be ARRAY, got %s", args[0].Type())
+			}
+
+			less := defaultLess
+			if len(args) == 2 {
+				if !isCallable(args[1]) {
+					return newError("comparator passed to `sort` must be FUNCTION, got %s", args[1].Type())
+				}
+				less = comparatorLess(args[1])
+			}
+
+			arr := args[0].(*object.Array)
+			elements := make([]object.Object, len(arr.Elements))
+			copy(elements, arr.Elements)
+
+			// sort.SliceStable cannot be stopped, so once a comparison has
+			// failed the remaining ones are skipped and the error is returned
+			var sortErr object.Object
+			sort.SliceStable(elements, func(i, j int) bool {
+				if sortErr != nil {
+					return false
+				}
+				result, err := less(elements[i], elements[j])
+				if err != nil {
+					sortErr = err
+				}
+				return result
+			})
+
+			if sortErr != nil {
+				return sortErr
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"zip": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 {
+				return newError("wrong number of arguments. got=%d, want at least 1", len(args))
+			}
+
+			length := -1
+			for _, arg := range args {
+				if arg.Type() != object.ARRAY_OBJ {
+					return newError("argument to `zip` must be ARRAY, got %s", arg.Type())
+				}
+				if n := len(arg.(*object.Array).Elements); length < 0 || n < length {
+					length = n
+				}
+			}
+
+			elements := make([]object.Object, length)
+			for i := range elements {
+				tuple := make([]object.Object, len(args))
+				for j, arg := range args {
+					tuple[j] = arg.(*object.Array).Elements[i]
+				}
+				elements[i] = &object.Array{Elements: tuple}
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"range": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 3 {
+				return newError("wrong number of arguments. got=%d, want=1 to 3", len(args))
+			}
+
+			bounds := make([]int64, len(args))
+			for i, arg := range args {
+				integer, ok := arg.(*object.Integer)
+				if !ok || integer.IsBig() {
+					return newError("argument to `range` must be INTEGER, got %s", arg.Inspect())
+				}
+				bounds[i] = integer.Value
+			}
+
+			start, end, step := int64(0), bounds[0], int64(1)
+			if len(bounds) > 1 {
+				start, end = bounds[0], bounds[1]
+			}
+			if len(bounds) > 2 {
+				step = bounds[2]
+			}
+			if step == 0 {
+				return newError("step passed to `range` must not be 0")
+			}
+
+			// counted up front in uint64, where the span between any two
+			// integers fits, so stepping never runs past the int64 limits
+			var span, stride uint64
+			if step > 0 && start < end {
+				span, stride = uint64(end)-uint64(start), uint64(step)
+			} else if step < 0 && start > end {
+				span, stride = uint64(start)-uint64(end), -uint64(step)
+			}
+			var count uint64
+			if span > 0 {
+				count = (span-1)/stride + 1
+			}
+			if count > maxArrayLength {
+				return newError("range passed to `range` has %d elements, more than %d", count, maxArrayLength)
+			}
+
+			elements := make([]object.Object, count)
+			for i := range elements {
+				elements[i] = &object.Integer{Value: int64(uint64(start) + uint64(i)*uint64(step))}
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"flatten": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to `flatten` must be ARRAY, got %s", args[0].Type())
+			}
+
+			depth := int64(1)
+			if len(args) == 2 {
+				integer, ok := args[1].(*object.Integer)
+				if !ok || integer.IsBig() {
+					return newError("depth passed to `flatten` must be INTEGER, got %s", args[1].Type())
+				}
+				depth = integer.Value
+			}
+
+			return &object.Array{Elements: flatten(args[0].(*object.Array).Elements, depth)}
+		},
+	},
+	"unique": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to `unique` must be ARRAY, got %s", args[0].Type())
+			}
+
+			// hashable elements are deduplicated through a hash, anything
+			// else has to be compared against every element kept so far.
+			// Floats equal to integers are not hashable, so hashable
+			// elements are compared against the unhashable ones kept too.
+			seen := object.NewHash()
+			unhashable := []object.Object{}
+			elements := []object.Object{}
+			for _, el := range args[0].(*object.Array).Elements {
+				if key, ok := object.AsHashable(el); ok {
+					if _, ok := seen.Get(key); ok || containsEqual(unhashable, el) {
+						continue
+					}
+					seen.Set(key, TRUE)
+				} else if containsEqual(elements, el) {
+					continue
+				} else {
+					unhashable = append(unhashable, el)
+				}
+				elements = append(elements, el)
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+}
+
+// Checks the (array, function) arguments shared by most collection builtins.
+func arrayAndFunctionArgs(name string, args []object.Object) (*object.Array, object.Object, object.Object) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	if args[0].Type() != object.ARRAY_OBJ {
+		return nil, nil, newError("argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+
+	if !isCallable(args[1]) {
+		return nil, nil, newError("second argument to `%s` must be FUNCTION, got %s", name, args[1].Type())
+	}
+
+	return args[0].(*object.Array), args[1], nil
+}
+
+func isCallable(obj object.Object) bool {
+	return obj.Type() == object.FUNCTION_OBJ || obj.Type() == object.BUILTIN_OBJ
+}
+
+// Orders elements the same way the < operator does.
+func defaultLess(a, b object.Object) (bool, object.Object) {
+	result := evalInfixExpression("<", a, b)
+	if isError(result) {
+		return false, newError("cannot sort %s and %s", a.Type(), b.Type())
+	}
+	return result == TRUE, nil
+}
+
+// Wraps a user comparator. It may either return a boolean telling whether a
+// goes before b, or an integer that is negative when it does.
+func comparatorLess(fn object.Object) func(a, b object.Object) (bool, object.Object) {
+	return func(a, b object.Object) (bool, object.Object) {
+		result := Call(fn, a, b)
+
+		switch result := result.(type) {
+		case *object.Error:
+			return false, result
+		case *object.Boolean:
+			return result.Value, nil
+		case *object.Integer:
+			if result.IsBig() {
+				return result.Big.Sign() < 0, nil
+			}
+			return result.Value < 0, nil
+		default:
+			return false, newError("comparator passed to `sort` must return BOOLEAN or INTEGER, got %s", result.Type())
+		}
+	}
+}
+
+func flatten(elements []object.Object, depth int64) []object.Object {
+	flat := []object.Object{}
+	for _, el := range elements {
+		if arr, ok := el.(*object.Array); ok && depth > 0 {
+			flat = append(flat, flatten(arr.Elements, depth-1)...)
+		} else {
+			flat = append(flat, el)
+		}
+	}
+	return flat
+}
+
+func containsEqual(elements []object.Object, obj object.Object) bool {
+	for _, el := range elements {
+		if object.Equal(el, obj) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package evaluator
+
+import (
+	"example/sawan/goInterpreter/ast"
+)
+
+// Records which parts of a program run. Unlike a Debugger it only sees what
+// runs, not the environments it runs in, so it can be set together with one.
+type Coverage interface {
+	// Called with every module imported, named as by its loader, before it
+	// runs.
+	Module(name string, source string, program *ast.Program)
+	// Called before stmt runs.
+	Statement(stmt ast.Statement)
+	// Called once the condition of ie has picked a branch: the consequence
+	// when taken is set, otherwise the alternative or, without one, nothing.
+	Branch(ie *ast.IfExpression, taken bool)
+}
+
+var coverage Coverage
+
+// Sets the coverage whose hooks are called while programs run. nil turns the
+// hooks off.
+func SetCoverage(c Coverage) {
+	coverage = c
+}
@@ -0,0 +1,101 @@
+package evaluator
+
+import (
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/object"
+)
+
+// Follows a program while it runs. The evaluator calls the hooks of the
+// debugger set with SetDebugger, from the goroutine running the program, so
+// a hook that does not return pauses the program.
+type Debugger interface {
+	// Called before stmt runs in env.
+	Statement(stmt ast.Statement, env *object.Environment)
+	// Called when a call of fn starts, once its arguments are bound in env.
+	// call is nil when a builtin like map makes the call. tail is set for a
+	// call in tail position, which takes the place of the call making it, so
+	// Return is only called once for both.
+	Call(call *ast.CallExpression, fn *object.Function, env *object.Environment, tail bool)
+	// Called when the call of fn ends.
+	Return(fn *object.Function)
+}
+
+var debugger Debugger
+
+// Sets the debugger whose hooks are called while programs run. nil turns the
+// hooks off.
+func SetDebugger(d Debugger) {
+	debugger = d
+}
+
+// Returns the bindings visible in env, the environment of a call of fn or,
+// when fn is nil, of a program. The resolver keeps the bindings of calls in
+// slots and cells instead of by name, so fn is needed to name them.
+func Bindings(fn *object.Function, env *object.Environment) map[string]object.Object {
+	bindings := map[string]object.Object{}
+	for _, name := range env.Names() {
+		bindings[name], _ = env.Get(name)
+	}
+	if fn == nil || fn.Frame == nil {
+		return bindings
+	}
+
+	add := func(ident *ast.Identifier) {
+		var val object.Object
+		switch ident.Scope {
+		case ast.LocalScope:
+			val = env.Local(ident.Index)
+		case ast.FreeScope:
+			val = env.Cell(ident.Index).Value
+		}
+		// nil until the let binding it has run
+		if val != nil {
+			bindings[ident.Value] = val
+		}
+	}
+
+	for _, param := range fn.Parameters {
+		add(param)
+	}
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			add(node)
+		// the identifiers of nested functions refer to their own frames
+		case *ast.FunctionLiteral:
+			return false
+		}
+		return true
+	})
+	return bindings
+}
+
+// Evaluates input, a program, in env as it is seen by a call of fn, without
+// changing any of its bindings. Hooks are not called while it runs.
+func EvalIn(input *ast.Program, fn *object.Function, env *object.Environment) object.Object {
+	savedDebugger, savedCoverage := debugger, coverage
+	debugger, coverage = nil, nil
+	defer func() { debugger, coverage = savedDebugger, savedCoverage }()
+
+	scope := object.NewEnclosedEnvironment(env)
+	for name, val := range Bindings(fn, env) {
+		scope.Set(name, val)
+	}
+	return Eval(input, scope)
+}
+
+// Returns the name a function is called by in call, as written in it. Calls
+// of function literals and calls made by builtins, for which call is nil,
+// give fn.
+func CallName(call *ast.CallExpression) string {
+	if call == nil {
+		return "fn"
+	}
+	switch function := call.Function.(type) {
+	case *ast.Identifier:
+		return function.Value
+	case *ast.FunctionLiteral:
+		return "fn"
+	}
+	return call.Function.String()
+}
@@ -2,6 +2,7 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
 
 	"example/sawan/goInterpreter/ast"
 	"example/sawan/goInterpreter/object"
@@ -10,17 +11,31 @@ import (
 // A function that takes any ast node and converts it into a suitable object
 // type
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	if tracing != nil {
+		return traced(node, eval(node, env))
+	}
+	return eval(node, env)
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	case *ast.Program:
+		resolve(node)
 		return evalProgram(node, env)
 
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return object.NewBigInteger(node.Big)
+		}
 		return &object.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
@@ -55,13 +70,29 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return &object.ReturnValue{Value: val}
 
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return throw(node, val)
+
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+
+	case *ast.ExportStatement:
+		return Eval(node.Statement, env)
+
+	case *ast.ImportExpression:
+		return evalImportExpression(node)
+
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 
-		env.Set(node.Name.Value, val)
+		bind(node.Name, val, env)
 
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
@@ -69,9 +100,22 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		function := &object.Function{Parameters: params, Env: env, Body: body, Frame: node.Frame}
+		if node.Frame != nil {
+			for _, index := range node.Frame.Free {
+				function.Free = append(function.Free, env.Cell(index))
+			}
+		}
+		return function
+
+	case *ast.MacroLiteral:
+		return newError("macros can only be defined by top level let statements")
 
 	case *ast.CallExpression:
+		if isQuoteCall(node) {
+			return quote(node, env)
+		}
+
 		function := Eval(node.Function, env)
 		if isError(function) {
 			return function
@@ -81,7 +125,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return applyFunction(node, function, args)
 
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
@@ -105,6 +149,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 		return evalIndexExpression(left, index)
 
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
+
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
 	}
@@ -122,6 +169,12 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
+		if debugger != nil {
+			debugger.Statement(statement, env)
+		}
+		if coverage != nil {
+			coverage.Statement(statement)
+		}
 		result = Eval(statement, env)
 
 		switch result := result.(type) {
@@ -168,27 +221,37 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-// Minus Operator Evaluator for integer values.
+// Minus Operator Evaluator for integer and float values.
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+	if float, ok := right.(*object.Float); ok {
+		return &object.Float{Value: -float.Value}
+	}
+
 	// check if the right value is an integer
 	if right.Type() != object.INTEGER_OBJ {
 		return newError("unknown operator: -%s", right.Type())
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	integer := right.(*object.Integer)
+	if integer.IsBig() || integer.Value == math.MinInt64 {
+		value := integer.BigValue()
+		return object.NewBigInteger(value.Neg(value))
+	}
+	return &object.Integer{Value: -integer.Value}
 }
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumeric(left) && isNumeric(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
@@ -196,18 +259,47 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 }
 
+// Integer arithmetic is done on int64 values and falls back to
+// evalBigIntegerInfixExpression whenever an operand is already big or the
+// int64 result would overflow.
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+	if left.(*object.Integer).IsBig() || right.(*object.Integer).IsBig() {
+		return evalBigIntegerInfixExpression(operator, left, right)
+	}
+
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		result := leftVal + rightVal
+		if (leftVal^result)&(rightVal^result) < 0 {
+			return evalBigIntegerInfixExpression(operator, left, right)
+		}
+		return &object.Integer{Value: result}
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		result := leftVal - rightVal
+		if (leftVal^rightVal)&(leftVal^result) < 0 {
+			return evalBigIntegerInfixExpression(operator, left, right)
+		}
+		return &object.Integer{Value: result}
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		if leftVal == 0 || rightVal == 0 {
+			return &object.Integer{Value: 0}
+		}
+		result := leftVal * rightVal
+		if result/rightVal != leftVal || (leftVal == -1 && rightVal == math.MinInt64) ||
+			(rightVal == -1 && leftVal == math.MinInt64) {
+			return evalBigIntegerInfixExpression(operator, left, right)
+		}
+		return &object.Integer{Value: result}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return evalBigIntegerInfixExpression(operator, left, right)
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -222,12 +314,93 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
+func evalBigIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.Integer).BigValue()
+	rightVal := right.(*object.Integer).BigValue()
+
+	switch operator {
+	case "+":
+		return object.NewBigInteger(leftVal.Add(leftVal, rightVal))
+	case "-":
+		return object.NewBigInteger(leftVal.Sub(leftVal, rightVal))
+	case "*":
+		return object.NewBigInteger(leftVal.Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero")
+		}
+		// Quo truncates towards zero like the int64 division does
+		return object.NewBigInteger(leftVal.Quo(leftVal, rightVal))
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError("unknown mismatch:  %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isNumeric(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+// Returns the value of an integer or float as a float64.
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Float()
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
+
+// Used when at least one operand is a float. The other one is converted, so
+// 1 + 0.5 is 1.5.
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: leftVal / rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
+	if coverage != nil {
+		coverage.Branch(ie, isTruthy(condition))
+	}
+
 	if isTruthy(condition) {
 		return Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
@@ -258,6 +431,12 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	var result object.Object
 
 	for _, statement := range block.Statements {
+		if debugger != nil {
+			debugger.Statement(statement, env)
+		}
+		if coverage != nil {
+			coverage.Statement(statement)
+		}
 		result = Eval(statement, env)
 
 		if result != nil {
@@ -272,7 +451,7 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 }
 
 func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: "runtime"}
 }
 
 func isError(obj object.Object) bool {
@@ -283,6 +462,20 @@ func isError(obj object.Object) bool {
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	switch node.Scope {
+	case ast.LocalScope:
+		if val := env.Local(node.Index); val != nil {
+			return val
+		}
+		return evalOuterIdentifier(node, env)
+
+	case ast.FreeScope:
+		if val := env.Cell(node.Index).Value; val != nil {
+			return val
+		}
+		return evalOuterIdentifier(node, env)
+	}
+
 	// the builtin error was because of this stupid shit. I was doing !ok instead.
 	// Hours upon hours of looking at the stack trace
 	if val, ok := env.Get(node.Value); ok {
@@ -293,9 +486,22 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return builtin
 	}
 
+	if constant, ok := constants[node.Value]; ok {
+		return constant
+	}
+
 	return newError("identifier not found: " + node.Value)
 }
 
+// Looks up a resolved identifier whose binding is not made yet where the
+// resolver says to look instead.
+func evalOuterIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if node.Outer == nil {
+		return newError("identifier not found: " + node.Value)
+	}
+	return evalIdentifier(node.Outer, env)
+}
+
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
@@ -310,35 +516,101 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	switch fn := fn.(type) {
+// Calls in tail position come back from the function body as a tailCall
+// instead of being evaluated in place, so this loop acts as a trampoline and
+// properly tail recursive functions run without growing the Go stack.
+// call is the expression making the call, nil when a builtin makes it.
+func applyFunction(call *ast.CallExpression, fn object.Object, args []object.Object) object.Object {
+	// the function of the call a call in tail position takes the place of
+	var replaced *object.Function
+
+	for {
+		switch function := fn.(type) {
+
+		case *object.Function:
+			if len(args) != len(function.Parameters) {
+				err := newError("wrong number of arguments. got=%d, want=%d", len(args), len(function.Parameters))
+				if replaced != nil && debugger != nil {
+					debugger.Return(replaced)
+				}
+				if replaced != nil && tracing != nil {
+					tracing.ret(err)
+				}
+				return err
+			}
 
-	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+			extendedEnv := extendFunctionEnv(function, args)
+			if debugger != nil {
+				debugger.Call(call, function, extendedEnv, replaced != nil)
+			}
+			if tracing != nil {
+				tracing.call(call, args, replaced != nil)
+			}
+			evaluated := unwrapReturnValue(evalTailBlock(function.Body, extendedEnv, true))
 
-	case *object.Builtin:
-		return fn.Fn(args...)
+			if tc, ok := evaluated.(*tailCall); ok {
+				call, fn, args, replaced = tc.call, tc.fn, tc.args, function
+				continue
+			}
+			if debugger != nil {
+				debugger.Return(function)
+			}
+			if tracing != nil {
+				tracing.ret(evaluated)
+			}
+			if err, ok := evaluated.(*object.Error); ok {
+				err.Stack = append(err.Stack, stackFrame(call))
+			}
+			return evaluated
 
-	default:
-		return newError("not a function: %s", fn.Type())
+		case *object.Builtin:
+			result := function.Fn(args...)
+			if err, ok := result.(*object.Error); ok && call != nil && assertions[function] {
+				locateAssertion(call, err)
+			}
+			return result
 
+		default:
+			return newError("not a function: %s", fn.Type())
+
+		}
 	}
 }
 
 // creates a new environment inside the function that binds the arguments of the function
 // call to the function parameter names.
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+	var env *object.Environment
+	if fn.Frame != nil {
+		cells := make([]*object.Cell, 0, fn.Frame.Cells+len(fn.Free))
+		for i := 0; i < fn.Frame.Cells; i++ {
+			cells = append(cells, &object.Cell{})
+		}
+		env = object.NewFrameEnvironment(fn.Env, fn.Frame.Locals, append(cells, fn.Free...))
+	} else {
+		env = object.NewEnclosedEnvironment(fn.Env)
+	}
 
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		bind(param, args[paramIdx], env)
 	}
 
 	return env
 }
 
+// Binds name to val where the resolver put it, or by name when it has not
+// seen it.
+func bind(name *ast.Identifier, val object.Object, env *object.Environment) {
+	switch name.Scope {
+	case ast.LocalScope:
+		env.SetLocal(name.Index, val)
+	case ast.FreeScope:
+		env.Cell(name.Index).Value = val
+	default:
+		env.Set(name.Value, val)
+	}
+}
+
 // Used to stop returns from bubbling up into multiple function calls.
 // This function allows return to only go up one scope / environment
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -350,19 +622,31 @@ func unwrapReturnValue(obj object.Object) object.Object {
 }
 
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	}
-
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
-	return &object.String{Value: leftVal + rightVal}
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
 }
 
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
   case left.Type() == object.HASH_OBJ:
     return evalHashIndexExpression(left, index)
 	default:
@@ -372,21 +656,20 @@ func evalIndexExpression(left, index object.Object) object.Object {
 
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
 
-	if idx < 0 || idx > max {
-		return NULL
+	idx, ok := resolveIndex(index.(*object.Integer), len(arrayObject.Elements))
+	if !ok {
+		return indexOutOfRange(index, len(arrayObject.Elements))
 	}
 
 	return arrayObject.Elements[idx]
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
 
 		if isError(key) {
 			return key
@@ -394,32 +677,31 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 
     // this is why hashable interface is used. 
     // to check whether or not a key is hashable
-		hashkey, ok := key.(object.Hashable)
+		hashkey, ok := object.AsHashable(key)
 		if !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(pair.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashkey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashkey, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
   hashObject := hash.(*object.Hash)
 
-  key, ok := index.(object.Hashable)
+  key, ok := object.AsHashable(index)
   if !ok {
     return newError("unusable as hash key: %s", index.Type())
   }
 
-  pair, ok := hashObject.Pairs[key.HashKey()]
+  pair, ok := hashObject.Get(key)
   if !ok {
     return NULL
   }
@@ -1,8 +1,14 @@
 package evaluator
 
 import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
 	"testing"
 
+	"example/sawan/goInterpreter/ast"
 	"example/sawan/goInterpreter/lexer"
 	"example/sawan/goInterpreter/object"
 	"example/sawan/goInterpreter/parser"
@@ -239,6 +245,18 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			`{[1, fn(x) { x }]: 1}`,
+			"unusable as hash key: ARRAY",
+		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"18446744073709551616 / (1 - 1)",
+			"division by zero",
+		},
 	}
 
 	for _, tt := range tests {
@@ -446,6 +464,14 @@ func TestArrayIndexExpressions(t *testing.T) {
 		},
 		{
 			"[1, 2, 3][-1]",
+			3,
+		},
+		{
+			"[1, 2, 3][-3]",
+			1,
+		},
+		{
+			"[1, 2, 3][-4]",
 			nil,
 		},
 	}
@@ -479,26 +505,35 @@ func TestHashLiterals(t *testing.T) {
 		t.Fatalf("Eval didn't return Hash. got=%T (+%v)", evaluated, evaluated)
 	}
 
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		TRUE.HashKey():                             5,
-		FALSE.HashKey():                            6,
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+	for i, tt := range expected {
+		pair, ok := result.Get(tt.key)
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
+			continue
 		}
 
-		testIntegerObject(t, pair.Value, expectedValue)
+		testIntegerObject(t, pair.Value, tt.value)
+
+		// pairs must come back in the order they were written
+		if result.Pairs()[i].Key.Inspect() != tt.key.(object.Object).Inspect() {
+			t.Errorf("pair %d has wrong key. got=%s", i, result.Pairs()[i].Key.Inspect())
+		}
 	}
 }
 
@@ -535,6 +570,22 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{18446744073709551616: 5}[4294967296 * 4294967296]`,
+			5,
+		},
+		{
+			`{[1, "a"]: 5}[[1, "a"]]`,
+			5,
+		},
+		{
+			`{[1, [2, 3]]: 5}[[1, [2, 3]]]`,
+			5,
+		},
+		{
+			`{[1, "a"]: 5}[["a", 1]]`,
+			nil,
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -546,3 +597,1299 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestTailCalls(t *testing.T) {
+	// A small stack limit makes any call that still nests Go frames per
+	// Monkey call crash instead of passing slowly.
+	defer debug.SetMaxStack(debug.SetMaxStack(8 << 20))
+
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			`let count = fn(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } };
+      count(100000, 0);`,
+			100000,
+		},
+		{
+			`let count = fn(n, acc) { if (n == 0) { return acc; } return count(n - 1, acc + 1); };
+      count(100000, 0);`,
+			100000,
+		},
+		{
+			`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+      let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+      if (even(100000)) { 1 } else { 0 }`,
+			1,
+		},
+		{
+			`let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } };
+      sum(100);`,
+			5050,
+		},
+		{
+			`let f = fn(x) { if (x > 0) { return f(x - 1); } 42 }; f(10)`,
+			42,
+		},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBigIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"99999999999999999999 - 99999999999999999998", "1"},
+		{"18446744073709551616 / 4294967296", "4294967296"},
+		{"-18446744073709551617 / 2", "-9223372036854775808"},
+		{`let fact = fn(n) { if (n == 0) { 1 } else { n * fact(n - 1) } }; fact(25)`,
+			"15511210043330985984000000"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := evaluated.(*object.Integer)
+		if !ok {
+			t.Errorf("object is not integer. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if integer.Inspect() != tt.expected {
+			t.Errorf("wrong value for %q. got=%s, want=%s", tt.input, integer.Inspect(), tt.expected)
+		}
+	}
+
+	// values that fit again must drop back to the int64 representation
+	testIntegerObject(t, testEval("9223372036854775808 - 1"), 9223372036854775807)
+	if testEval("9223372036854775808 - 1").(*object.Integer).IsBig() {
+		t.Errorf("integer that fits in int64 was not demoted")
+	}
+}
+
+func TestBigIntegerComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"9223372036854775808 > 9223372036854775807", true},
+		{"9223372036854775808 < 1", false},
+		{"-9223372036854775809 < 0", true},
+		{"9223372036854775807 + 1 == 9223372036854775808", true},
+		{"9223372036854775808 != 9223372036854775808", false},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{"[[1, [2]], \"a\"] == [[1, [2]], \"a\"]", true},
+		{"[] == []", true},
+		{"[1] == 1", false},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`{"a": 1} != {"b": 1}`, true},
+		{"[9223372036854775808] == [9223372036854775807 + 1]", true},
+		{"let f = fn(x) { x }; f == f", true},
+		{"fn(x) { x } == fn(x) { x }", false},
+		{"len == len", true},
+		{"[true, false] == [true, false]", true},
+		{`"a" == "a"`, true},
+		{`"a" != "b"`, true},
+		{`"a" < "b"`, true},
+		{`"b" < "a"`, false},
+		{`"abc" > "abd"`, false},
+		{`"b" > "abc"`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestHashOrderBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, `{b: 1, a: 2, c: 3}`},
+		{`{3: "x", 1: "y", 2: "z"}`, `{3: x, 1: y, 2: z}`},
+		{`keys({"b": 1, "a": 2, "c": 3})`, `[b, a, c]`},
+		{`values({"b": 1, "a": 2, "c": 3})`, `[1, 2, 3]`},
+		{`entries({"b": 1, "a": 2})`, `[[b, 1], [a, 2]]`},
+		{`has({"b": 1}, "b")`, `true`},
+		{`has({"b": 1}, "a")`, `false`},
+		{`delete({"b": 1, "a": 2, "c": 3}, "a")`, `{b: 1, c: 3}`},
+		{`delete({"b": 1}, "x")`, `{b: 1}`},
+		{`let h = {"b": 1, "a": 2}; delete(h, "b"); h`, `{b: 1, a: 2}`},
+		{`merge({"b": 1, "a": 2}, {"c": 3, "b": 4})`, `{b: 4, a: 2, c: 3}`},
+		{`merge({"a": 1})`, `{a: 1}`},
+		{`keys(delete({"a": 1, "b": 2, "c": 3}, "a"))`, `[b, c]`},
+		{`delete({"a": 1, "b": 2, "c": 3}, "a")["c"]`, `3`},
+		{`keys([])`, "ERROR: argument to `keys` must be HASH, got ARRAY"},
+		{`has({}, fn(x) { x })`, "ERROR: unusable as hash key: FUNCTION"},
+		{`merge({}, 1)`, "ERROR: argument to `merge` must be HASH, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. got=%s, want=%s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, `[2, 4, 6]`},
+		{`map([], fn(x) { x })`, `[]`},
+		{`let n = 10; map([1, 2], fn(x) { x + n })`, `[11, 12]`},
+		{`map([[1], [2, 3]], len)`, `[1, 2]`},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, `[3, 4]`},
+		{`reduce([1, 2, 3, 4], fn(acc, x) { acc + x }, 0)`, `10`},
+		{`reduce([], fn(acc, x) { acc + x }, 7)`, `7`},
+		{`find([1, 2, 3], fn(x) { x > 1 })`, `2`},
+		{`find([1, 2, 3], fn(x) { x > 5 })`, `null`},
+		{`any([1, 2, 3], fn(x) { x == 2 })`, `true`},
+		{`any([], fn(x) { true })`, `false`},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, `true`},
+		{`all([1, 2, 3], fn(x) { x > 1 })`, `false`},
+		{`sort([3, 1, 2])`, `[1, 2, 3]`},
+		{`sort(["b", "c", "a"])`, `[a, b, c]`},
+		{`sort([3, 1, 2], fn(a, b) { a > b })`, `[3, 2, 1]`},
+		{`sort([3, 1, 2], fn(a, b) { b - a })`, `[3, 2, 1]`},
+		{`sort([[2, "b"], [1, "a"], [2, "a"]], fn(a, b) { a[0] < b[0] })`, `[[1, a], [2, b], [2, a]]`},
+		{`let xs = [2, 1]; sort(xs); xs`, `[2, 1]`},
+		{`zip([1, 2, 3], ["a", "b"])`, `[[1, a], [2, b]]`},
+		{`zip([1], [2], [3])`, `[[1, 2, 3]]`},
+		{`range(4)`, `[0, 1, 2, 3]`},
+		{`range(2, 5)`, `[2, 3, 4]`},
+		{`range(5, 0, -2)`, `[5, 3, 1]`},
+		{`range(3, 1)`, `[]`},
+		{`range(1, 10, 4)`, `[1, 5, 9]`},
+		{`range(9223372036854775806, 9223372036854775807, 2)`, `[9223372036854775806]`},
+		{`range(-9223372036854775807, -9223372036854775808, -5)`, `[-9223372036854775807]`},
+		{`range(9223372036854775807, -9223372036854775808, -9223372036854775808)`, `[9223372036854775807, -1]`},
+		{`flatten([1, [2, [3]], []])`, `[1, 2, [3]]`},
+		{`flatten([1, [2, [3, [4]]]], 5)`, `[1, 2, 3, 4]`},
+		{`unique([1, 2, 1, "a", "a", [1], [1], true])`, `[1, 2, a, [1], true]`},
+		{`unique([len, len, first])`, `[builtin function, builtin function]`},
+		{`unique([1, 1.0])`, `[1]`},
+		{`unique([1.0, 1])`, `[1.0]`},
+		{`unique([[1.0], [1], [1.0]])`, `[[1.0]]`},
+		{`unique([2, 1.0, 2.0, 1])`, `[2, 1.0]`},
+		{`push([1], 2)`, `[1, 2]`},
+		{`map([1, 2], fn(x) { x + true })`, `ERROR: type mismatch: INTEGER + BOOLEAN`},
+		{`map([1, 2], fn(x, y) { x })`, `ERROR: wrong number of arguments. got=1, want=2`},
+		{`map(1, fn(x) { x })`, "ERROR: argument to `map` must be ARRAY, got INTEGER"},
+		{`filter([1], 1)`, "ERROR: second argument to `filter` must be FUNCTION, got INTEGER"},
+		{`sort([1, "a"])`, `ERROR: cannot sort STRING and INTEGER`},
+		{`sort([1, 2], fn(a, b) { "x" })`, "ERROR: comparator passed to `sort` must return BOOLEAN or INTEGER, got STRING"},
+		{`range(0, 5, 0)`, "ERROR: step passed to `range` must not be 0"},
+		{`range(-9223372036854775807, 9223372036854775807)`, "ERROR: range passed to `range` has 18446744073709551614 elements, more than 134217728"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. got=%s, want=%s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len("héllo")`, `5`},
+		{`"héllo"[1]`, `é`},
+		{`"abc"[3]`, `null`},
+		{`split("a,b,,c", ",")`, `[a, b, , c]`},
+		{`split("  one two\tthree ")`, `[one, two, three]`},
+		{`split("héllo", "")`, `[h, é, l, l, o]`},
+		{`join(["a", "b", "c"], "-")`, `a-b-c`},
+		{`join([1, 2, 3])`, `123`},
+		{`trim("  padded \n")`, `padded`},
+		{`trim("xxhixx", "x")`, `hi`},
+		{`upper("héllo")`, `HÉLLO`},
+		{`lower("ÀB")`, `àb`},
+		{`replace("aaa", "a", "b")`, `bbb`},
+		{`replace("aaa", "a", "b", 2)`, `bba`},
+		{`contains("monkey", "key")`, `true`},
+		{`contains("monkey", "donkey")`, `false`},
+		{`starts_with("monkey", "mon")`, `true`},
+		{`ends_with("monkey", "mon")`, `false`},
+		{`index_of("日本語", "語")`, `2`},
+		{`index_of("abc", "z")`, `-1`},
+		{`repeat("ab", 3)`, `ababab`},
+		{`chars("日本")`, `[日, 本]`},
+		{`format("%s is %d years", "Monkey", 3)`, `Monkey is 3 years`},
+		{`format("%5d|%-4s|%05.1f", 42, "ab", 2)`, `   42|ab  |002.0`},
+		{`format("%q %v %t %x %%", "hi", [1, 2], true, 255)`, `"hi" [1, 2] true ff %`},
+		{`format("%d", 18446744073709551616)`, `18446744073709551616`},
+		{`format("%d")`, `ERROR: format: missing argument for %d`},
+		{`format("%d", "a")`, `ERROR: format: cannot use STRING with %d`},
+		{`format("hi", 1)`, `ERROR: format: 1 unused arguments`},
+		{`upper(1)`, "ERROR: argument to `upper` must be STRING, got INTEGER"},
+		{`repeat("a", -1)`, "ERROR: count passed to `repeat` must be a non-negative INTEGER, got -1"},
+		{`repeat("ab", 9223372036854775807)`, "ERROR: result of `repeat` would be longer than 1073741824 bytes"},
+		{`repeat("", 9223372036854775807)`, ``},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. got=%s, want=%s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4][2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][-2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:-1]", "[1, 2, 3]"},
+		{"[1, 2, 3, 4][3:1]", "[]"},
+		{"[1, 2, 3, 4][-10:10]", "[1, 2, 3, 4]"},
+		{"let a = [1, 2, 3]; let i = 1; a[i:i + 1]", "[2]"},
+		{`"héllo"[2:]`, "llo"},
+		{`"héllo"[-1]`, "o"},
+		{`"hello"[:-2]`, "hel"},
+		{`"hello"[1:99]`, "ello"},
+		{`[1, 2][true:]`, "ERROR: slice bound must be INTEGER, got BOOLEAN"},
+		{`{"a": 1}[1:]`, "ERROR: slice operator not supported: HASH"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. got=%s, want=%s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestOutOfRangeError(t *testing.T) {
+	defer SetOutOfRange(outOfRange)
+	SetOutOfRange(OutOfRangeError)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3][3]", "ERROR: index out of range: 3 with length 3"},
+		{"[1, 2, 3][-4]", "ERROR: index out of range: -4 with length 3"},
+		{`"abc"[5]`, "ERROR: index out of range: 5 with length 3"},
+		{"[1, 2, 3][1:5]", "ERROR: slice bound out of range: 5 with length 3"},
+		{"[1, 2, 3][-5:]", "ERROR: slice bound out of range: -5 with length 3"},
+		{"[1, 2, 3][-1]", "3"},
+		{"[1, 2, 3][1:3]", "[2, 3]"},
+		{"[1, 2, 3][3:]", "[]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. got=%s, want=%s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.5", "1.5"},
+		{"2.0", "2.0"},
+		{"-2.5", "-2.5"},
+		{"1.5 + 1.5", "3.0"},
+		{"1 + 0.5", "1.5"},
+		{"0.5 * 4", "2.0"},
+		{"7 / 2.0", "3.5"},
+		{"1.5 < 2", "true"},
+		{"2 > 2.5", "false"},
+		{"1 == 1.0", "true"},
+		{"[1, 2.5] == [1.0, 2.5]", "true"},
+		{"1.0 / 0", "ERROR: division by zero"},
+		{"sort([2.5, 1, 1.5])", "[1, 1.5, 2.5]"},
+		{`format("%.2f", 3.14159)`, "3.14"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. got=%s, want=%s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"abs(-5)", "5"},
+		{"abs(-2.5)", "2.5"},
+		{"abs(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"min(3, 1, 2)", "1"},
+		{"max([3, 1.5, 7])", "7"},
+		{"max(2, 2.5)", "2.5"},
+		{"min(9223372036854775808, 1)", "1"},
+		{"pow(2, 10)", "1024"},
+		{"pow(2, 100)", "1267650600228229401496703205376"},
+		{"pow(2, -1)", "0.5"},
+		{"pow(4, 0.5)", "2.0"},
+		{"pow(2, 16777217)", "ERROR: result of `pow` would be longer than 16777216 bits"},
+		{"pow(3, 100000000000)", "ERROR: result of `pow` would be longer than 16777216 bits"},
+		{"pow(-3, 9223372036854775808)", "ERROR: result of `pow` would be longer than 16777216 bits"},
+		{"pow(1, 100000000000)", "1"},
+		{"pow(-1, 100000000001)", "-1"},
+		{"pow(0, 100000000000)", "0"},
+		{"pow(2, 16777215) > 0", "true"},
+		{"sqrt(16)", "4.0"},
+		{"floor(2.7)", "2"},
+		{"floor(-2.5)", "-3"},
+		{"ceil(2.1)", "3"},
+		{"round(2.5)", "3"},
+		{"round(7)", "7"},
+		{"floor(100000000000000000000.0)", "100000000000000000000"},
+		{"sin(0)", "0.0"},
+		{"cos(PI)", "-1.0"},
+		{"round(atan2(1, 1) * 4 * 1000)", "3142"},
+		{"exp(0)", "1.0"},
+		{"log(E)", "1.0"},
+		{"min()", "ERROR: `min` needs at least one number"},
+		{`max(1, "a")`, "ERROR: argument to `max` must be INTEGER or FLOAT, got STRING"},
+		{`sqrt("a")`, "ERROR: argument to `sqrt` must be INTEGER or FLOAT, got STRING"},
+		{"random(5, 5)", "ERROR: empty range passed to `random`: [5, 5)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. got=%s, want=%s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestSeededRandom(t *testing.T) {
+	input := `seed(42); [random(), random(10), random(5, 8)]`
+
+	first := testEval(input).Inspect()
+	second := testEval(input).Inspect()
+	if first != second {
+		t.Errorf("seeded runs differ. first=%s, second=%s", first, second)
+	}
+
+	for i := 0; i < 100; i++ {
+		value := testEval("random(5, 8)").(*object.Integer).Value
+		if value < 5 || value >= 8 {
+			t.Fatalf("random(5, 8) out of range. got=%d", value)
+		}
+	}
+
+	// ranges wider than the largest integer
+	for _, input := range []string{"random(-9223372036854775807, 9223372036854775807)", "random(-9223372036854775808, 9223372036854775807)"} {
+		for i := 0; i < 100; i++ {
+			if _, ok := testEval(input).(*object.Integer); !ok {
+				t.Fatalf("%s did not give an integer", input)
+			}
+		}
+	}
+	if got := testEval("random(9223372036854775806, 9223372036854775807)").Inspect(); got != "9223372036854775806" {
+		t.Errorf("wrong value at the top of the range. got=%s", got)
+	}
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`json_parse("{\"b\": 1, \"a\": [true, null, 2.5, \"x\"]}")`, `{b: 1, a: [true, null, 2.5, x]}`},
+		{`json_parse("[]")`, `[]`},
+		{`json_parse("18446744073709551616")`, `18446744073709551616`},
+		{`json_parse("1e3")`, `1000.0`},
+		{`json_parse("null")`, `null`},
+		{`json_parse("{\"a\": {\"b\": 2}}")["a"]["b"]`, `2`},
+		{`json_parse("[1,")`, `ERROR: json_parse: unexpected end of JSON input`},
+		{`json_parse("[1] 2")`, `ERROR: json_parse: unexpected data after top-level value`},
+		{`json_stringify({"b": 1, "a": [true, 2.5, "x\"y"]})`, `{"b":1,"a":[true,2.5,"x\"y"]}`},
+		{`json_stringify([1, {}, []], 2)`, "[\n  1,\n  {},\n  []\n]"},
+		{`json_stringify({"a": [1], "b": first([])}, "\t")`, "{\n\t\"a\": [\n\t\t1\n\t],\n\t\"b\": null\n}"},
+		{`json_stringify("<tag>")`, `"<tag>"`},
+		{`json_stringify(json_parse("{\"z\": 1, \"y\": [2.0]}"))`, `{"z":1,"y":[2.0]}`},
+		{`json_stringify({"f": fn(x) { x }})`, `ERROR: json_stringify: cannot serialize FUNCTION`},
+		{`json_stringify({1: 2})`, `ERROR: json_stringify: hash keys must be STRING, got INTEGER`},
+		{`json_stringify(len)`, `ERROR: json_stringify: cannot serialize BUILTIN`},
+		{`json_parse(1)`, "ERROR: argument to `json_parse` must be STRING, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. got=%s, want=%s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestImportExpressions(t *testing.T) {
+	defer SetModuleLoader(moduleLoader)
+	SetModuleLoader(MemoryLoader{
+		"math.mk":  `let secret = 2; export let double = fn(x) { x * secret }; export let pi = 3;`,
+		"uses.mk":  `let m = import "math.mk"; export let six = m["double"](m["pi"]);`,
+		"a.mk":     `let b = import "b.mk"; export let x = 1;`,
+		"b.mk":     `let c = import "c.mk"; export let y = 1;`,
+		"c.mk":     `let a = import "a.mk"; export let z = 1;`,
+		"bad.mk":   `let x = ;`,
+		"fails.mk": `export let x = 1 + true;`,
+		"early.mk": `export let a = 1; return 1; export let b = 2;`,
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "math.mk"`, `{double: fn(x) {
+(x * secret)
+}, pi: 3}`},
+		{`let m = import "math.mk"; m["double"](21)`, `42`},
+		{`(import "math.mk")["secret"]`, `null`},
+		{`(import "uses.mk")["six"]`, `6`},
+		{`import "missing.mk"`, `ERROR: import "missing.mk": module not found`},
+		{`import "a.mk"`, `ERROR: import cycle: a.mk -> b.mk -> c.mk -> a.mk`},
+		{`import "bad.mk"`, `ERROR: import "bad.mk": no prefix parse function for ; found`},
+		{`import "fails.mk"`, `ERROR: type mismatch: INTEGER + BOOLEAN`},
+		{`import "early.mk"`, `{a: 1}`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. got=%s, want=%s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+
+	// the module is only evaluated once, later imports share its exports
+	if testEval(`import "math.mk"`) != testEval(`import "math.mk"`) {
+		t.Errorf("module was evaluated more than once")
+	}
+}
+
+func TestFileLoader(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(second, "lib.mk"), []byte(`export let x = 1;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := &FileLoader{SearchPath: []string{first, second}}
+	name, source, err := loader.Load("lib.mk", "")
+	if err != nil {
+		t.Fatalf("Load returned error: %s", err)
+	}
+
+	if name != filepath.Join(second, "lib.mk") {
+		t.Errorf("wrong module name. got=%q", name)
+	}
+	if source != `export let x = 1;` {
+		t.Errorf("wrong module source. got=%q", source)
+	}
+
+	if _, _, err := loader.Load("missing.mk", ""); err == nil {
+		t.Errorf("Load of missing module did not fail")
+	}
+
+	// modules import relative to their own directory first
+	lib := filepath.Join(first, "lib")
+	if err := os.Mkdir(lib, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"a.mk": `let b = import "b.mk"; export let y = b["x"] + 1;`,
+		"b.mk": `export let x = 41;`,
+	}
+	for file, source := range files {
+		if err := os.WriteFile(filepath.Join(lib, file), []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer SetModuleLoader(moduleLoader)
+	SetModuleLoader(loader)
+	if got := testEval(`(import "lib/a.mk")["y"]`).Inspect(); got != "42" {
+		t.Errorf("wrong result of relative import. got=%s", got)
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(5 + 8)`, `(5 + 8)`},
+		{`quote(foobar)`, `foobar`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+		{`quote(unquote(4))`, `4`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`quote(unquote(4 + 4) + 8)`, `(8 + 8)`},
+		{`let foobar = 8; quote(foobar)`, `foobar`},
+		{`let foobar = 8; quote(unquote(foobar))`, `8`},
+		{`quote(unquote(true))`, `true`},
+		{`quote(unquote(true == false))`, `false`},
+		{`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
+		{`let quotedInfixExpression = quote(4 + 4);
+      quote(unquote(4 + 4) + unquote(quotedInfixExpression))`, `(8 + (4 + 4))`},
+		{`quote(unquote("a" + "b"))`, `ab`},
+		{`quote(unquote(1.5))`, `1.5`},
+		{`quote(unquote(9223372036854775807 + 1))`, `9223372036854775808`},
+		{`let f = fn(x) { quote(x + unquote(x)) }; f(2)`, `(x + 2)`},
+		// every call unquotes into the quote as written
+		{`let f = fn(x) { quote(unquote(x) + 1) }; f(1); f(2)`, `(2 + 1)`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			t.Errorf("expected *object.Quote for %s. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if quote.Node == nil {
+			t.Errorf("quote.Node is nil")
+			continue
+		}
+
+		if quote.Node.String() != tt.expected {
+			t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), tt.expected)
+		}
+	}
+
+	testEvalError(t, `quote(unquote([1]))`, "cannot unquote ARRAY")
+	testEvalError(t, `quote(1, 2)`, "wrong number of arguments. got=2, want=1")
+}
+
+func testEvalError(t *testing.T, input, expected string) {
+	t.Helper()
+
+	errObj, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Errorf("no error object returned for %s", input)
+		return
+	}
+
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+  let number = 1;
+  let function = fn(x, y) { x + y };
+  let mymacro = macro(x, y) { x + y; };
+  `
+
+	env := object.NewEnvironment()
+	program := testParseProgram(input)
+
+	DefineMacros(program, env)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("Wrong number of statements. got=%d", len(program.Statements))
+	}
+
+	if _, ok := env.Get("number"); ok {
+		t.Fatalf("number should not be defined")
+	}
+	if _, ok := env.Get("function"); ok {
+		t.Fatalf("function should not be defined")
+	}
+
+	obj, ok := env.Get("mymacro")
+	if !ok {
+		t.Fatalf("macro not in environment.")
+	}
+
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("Wrong number of macro parameters. got=%d", len(macro.Parameters))
+	}
+
+	if macro.Parameters[0].String() != "x" || macro.Parameters[1].String() != "y" {
+		t.Fatalf("wrong parameters. got=%v", macro.Parameters)
+	}
+
+	if macro.Body.String() != "(x + y)" {
+		t.Fatalf("body is not %q. got=%q", "(x + y)", macro.Body.String())
+	}
+}
+
+func testParseProgram(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`
+      let infixExpression = macro() { quote(1 + 2); };
+
+      infixExpression();
+      `,
+			`(1 + 2)`,
+		},
+		{
+			`
+      let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+
+      reverse(2 + 2, 10 - 5);
+      `,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`
+      let unless = macro(condition, consequence, alternative) {
+        quote(if (!(unquote(condition))) {
+          unquote(consequence);
+        } else {
+          unquote(alternative);
+        });
+      };
+
+      unless(10 > 5, puts("not greater"), puts("greater"));
+      `,
+			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
+		},
+		{
+			`
+      let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };
+
+      unless(10 > 5, puts("no"), puts("yes"));
+      unless(1 > 5, puts("a"), puts("b"));
+      `,
+			`if (!(10 > 5)) { puts("no") } else { puts("yes") }; if (!(1 > 5)) { puts("a") } else { puts("b") }`,
+		},
+	}
+
+	for _, tt := range tests {
+		expected := testParseProgram(tt.expected)
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("ExpandMacros returned error: %s", err.Message)
+		}
+
+		if expanded.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+		}
+	}
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = macro(x) { 1 }; m(2);`, "macros must return a quote, got INTEGER"},
+		{`let m = macro(x) { quote(x) }; m();`, "wrong number of arguments to macro. got=0, want=1"},
+		{`let m = macro() { 1 + true }; m();`, "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		_, err := ExpandMacros(program, env)
+		if err == nil {
+			t.Errorf("ExpandMacros did not fail for %s", tt.input)
+			continue
+		}
+
+		if err.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, err.Message)
+		}
+	}
+}
+
+func TestBuiltinDocs(t *testing.T) {
+	for name, builtin := range builtins {
+		doc, ok := LookupBuiltinDoc(name)
+		if !ok {
+			t.Errorf("builtin %s has no documentation", name)
+			continue
+		}
+
+		if doc.MaxArgs >= 0 {
+			args := make([]object.Object, doc.MaxArgs+1)
+			for i := range args {
+				args[i] = NULL
+			}
+			errObj, ok := builtin.Fn(args...).(*object.Error)
+			if !ok || !strings.Contains(errObj.Message, "wrong number of arguments") {
+				t.Errorf("%s accepts %d arguments, documented maximum is %d", name, len(args), doc.MaxArgs)
+			}
+		}
+
+		if doc.MinArgs > 0 {
+			args := make([]object.Object, doc.MinArgs-1)
+			for i := range args {
+				args[i] = NULL
+			}
+			if !isError(builtin.Fn(args...)) {
+				t.Errorf("%s accepts %d arguments, documented minimum is %d", name, len(args), doc.MinArgs)
+			}
+		}
+	}
+
+	for _, name := range BuiltinNames() {
+		if !IsPredeclared(name) {
+			t.Errorf("%s is listed but not predeclared", name)
+		}
+	}
+}
+
+// Each builtin called as its documentation describes, so a doc that gets
+// the order or the kind of the arguments wrong fails here.
+func TestBuiltinDocExamples(t *testing.T) {
+	examples := map[string]struct {
+		input    string
+		expected string
+	}{
+		"len":     {`[len("héllo"), len([1, 2])]`, `[5, 2]`},
+		"first":   {`[first([1, 2]), first([])]`, `[1, null]`},
+		"last":    {`[last([1, 2]), last([])]`, `[2, null]`},
+		"rest":    {`rest([1, 2, 3])`, `[2, 3]`},
+		"push":    {`push([1], 2)`, `[1, 2]`},
+		"puts":    {`puts(1, 2)`, `null`},
+		"keys":    {`keys({"b": 1, "a": 2})`, `[b, a]`},
+		"values":  {`values({"b": 1, "a": 2})`, `[1, 2]`},
+		"entries": {`entries({"b": 1, "a": 2})`, `[[b, 1], [a, 2]]`},
+		"has":     {`[has({"a": 1}, "a"), has({"a": 1}, "b")]`, `[true, false]`},
+		"delete":  {`let h = {"a": 1, "b": 2}; [delete(h, "a"), h]`, `[{b: 2}, {a: 1, b: 2}]`},
+		"merge":   {`merge({"a": 1, "b": 1}, {"b": 2}, {"c": 3})`, `{a: 1, b: 2, c: 3}`},
+
+		"map":     {`map([1, 2], fn(x) { x * 2 })`, `[2, 4]`},
+		"filter":  {`filter([1, 2, 3], fn(x) { x > 1 })`, `[2, 3]`},
+		"reduce":  {`reduce([1, 2, 3], fn(acc, x) { acc * 10 + x }, 4)`, `4123`},
+		"find":    {`[find([1, 2, 3], fn(x) { x > 1 }), find([1], fn(x) { false })]`, `[2, null]`},
+		"any":     {`[any([1, 2], fn(x) { x > 1 }), any([1], fn(x) { x > 1 })]`, `[true, false]`},
+		"all":     {`[all([1, 2], fn(x) { x > 1 }), all([2], fn(x) { x > 1 })]`, `[false, true]`},
+		"sort":    {`[sort([3, 1, 2]), sort([1, 3, 2], fn(a, b) { a > b })]`, `[[1, 2, 3], [3, 2, 1]]`},
+		"zip":     {`zip([1, 2, 3], ["a", "b"])`, `[[1, a], [2, b]]`},
+		"range":   {`[range(3), range(1, 4), range(1, 7, 2)]`, `[[0, 1, 2], [1, 2, 3], [1, 3, 5]]`},
+		"flatten": {`[flatten([1, [2, [3]]]), flatten([1, [2, [3]]], 2)]`, `[[1, 2, [3]], [1, 2, 3]]`},
+		"unique":  {`unique([1, 2, 1, 3, 2])`, `[1, 2, 3]`},
+
+		"split":       {`[split("a,b", ","), split(" a  b ")]`, `[[a, b], [a, b]]`},
+		"join":        {`[join(["a", "b"], "-"), join([1, 2])]`, `[a-b, 12]`},
+		"trim":        {`[trim(" a "), trim("xax", "x")]`, `[a, a]`},
+		"upper":       {`upper("a")`, `A`},
+		"lower":       {`lower("A")`, `a`},
+		"replace":     {`[replace("aaa", "a", "b"), replace("aaa", "a", "b", 1)]`, `[bbb, baa]`},
+		"contains":    {`contains("monkey", "key")`, `true`},
+		"starts_with": {`starts_with("monkey", "mon")`, `true`},
+		"ends_with":   {`ends_with("monkey", "key")`, `true`},
+		"index_of":    {`[index_of("héllo", "l"), index_of("a", "z")]`, `[2, -1]`},
+		"repeat":      {`repeat("ab", 2)`, `abab`},
+		"chars":       {`chars("ab")`, `[a, b]`},
+		"format":      {`format("%d-%s", 1, "a")`, `1-a`},
+
+		"abs":    {`abs(-2)`, `2`},
+		"min":    {`[min(3, 1, 2), min([3, 1])]`, `[1, 1]`},
+		"max":    {`[max(3, 1, 2), max([3, 1])]`, `[3, 3]`},
+		"pow":    {`pow(2, 10)`, `1024`},
+		"sqrt":   {`sqrt(4)`, `2.0`},
+		"sin":    {`sin(0)`, `0.0`},
+		"cos":    {`cos(0)`, `1.0`},
+		"tan":    {`tan(0)`, `0.0`},
+		"asin":   {`asin(0)`, `0.0`},
+		"acos":   {`acos(1)`, `0.0`},
+		"atan":   {`atan(0)`, `0.0`},
+		"atan2":  {`atan2(1, 0) == PI / 2`, `true`},
+		"log":    {`log(1)`, `0.0`},
+		"exp":    {`exp(0)`, `1.0`},
+		"floor":  {`floor(1.5)`, `1`},
+		"ceil":   {`ceil(1.5)`, `2`},
+		"round":  {`[round(2.5), round(-2.5), round(2.4)]`, `[3, -3, 2]`},
+		"random": {`[random() < 1, random(1), random(3, 4)]`, `[true, 0, 3]`},
+		"seed":   {`seed(1)`, `null`},
+
+		"json_parse":     {`json_parse("{\"a\": [1, true]}")`, `{a: [1, true]}`},
+		"json_stringify": {`json_stringify({"a": [1]}, "  ")`, "{\n  \"a\": [\n    1\n  ]\n}"},
+
+		"error": {`let e = error("m", "k"); [e["message"], e["kind"], error("m")["kind"]]`, `[m, k, error]`},
+
+		"assert":       {`assert(true, "message")`, `null`},
+		"assert_eq":    {`assert_eq(1, 1, "message")`, `null`},
+		"assert_error": {`assert_error(fn() { 1 / 0 }, "zero")`, `null`},
+
+		"quote":   {`quote(1 + 2)`, `QUOTE((1 + 2))`},
+		"unquote": {`quote(1 + unquote(1 + 1))`, `QUOTE((1 + 2))`},
+	}
+
+	defer func(out io.Writer) { Stdout = out }(Stdout)
+	Stdout = io.Discard
+
+	for name := range builtinDocs {
+		example, ok := examples[name]
+		if !ok {
+			t.Errorf("documented builtin %s has no example", name)
+			continue
+		}
+		if got := testEval(example.input).Inspect(); got != example.expected {
+			t.Errorf("%s does not work as documented: %s gave %s, want %s", name, example.input, got, example.expected)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"let a = 1; let f = fn(x) { let y = x; len(y) + a }",
+			[]string{"a GLOBAL", "f GLOBAL", "x LOCAL 0", "y LOCAL 1", "x LOCAL 0", "len GLOBAL", "y LOCAL 1", "a GLOBAL"},
+		},
+		{
+			// x is used by the closure, so it gets a cell; y stays a slot
+			"fn(x, y) { fn() { x } }",
+			[]string{"x FREE 0", "y LOCAL 0", "x FREE 0"},
+		},
+		{
+			// the middle function passes a through to the inner one, after its
+			// own cell for b
+			"fn(a) { fn(b) { fn() { a + b } } }",
+			[]string{"a FREE 0", "b FREE 0", "a FREE 0", "b FREE 1"},
+		},
+		{
+			// the first x is the global, as the local is only bound after it
+			"let x = 1; fn() { let y = x; let x = 2; x }",
+			[]string{"x GLOBAL", "y LOCAL 0", "x GLOBAL", "x LOCAL 1", "x LOCAL 1"},
+		},
+		{
+			"fn() { let f = fn() { g() }; let g = fn() { f() }; }",
+			[]string{"f FREE 0", "g FREE 0", "g FREE 1", "f FREE 0"},
+		},
+		{
+			// quoted code is not evaluated, apart from unquote arguments
+			"fn(x) { quote(x + unquote(x)) }",
+			[]string{"x LOCAL 0", "x UNRESOLVED", "x LOCAL 0"},
+		},
+		{
+			"let len = fn(x) { x }; len",
+			[]string{"len GLOBAL", "x LOCAL 0", "x LOCAL 0", "len GLOBAL"},
+		},
+		{
+			// the catch parameter is a local of the function around the try
+			"fn() { try { 1 } catch (e) { e; fn() { e } } }",
+			[]string{"e FREE 0", "e FREE 0", "e FREE 0"},
+		},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		resolve(program)
+
+		got := []string{}
+		ast.Inspect(program, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Identifier); ok && ident.Value != "quote" && ident.Value != "unquote" {
+				entry := ident.Value + " " + ident.Scope.String()
+				if ident.Scope == ast.LocalScope || ident.Scope == ast.FreeScope {
+					entry += " " + string(rune('0'+ident.Index))
+				}
+				got = append(got, entry)
+			}
+			return true
+		})
+
+		if strings.Join(got, ", ") != strings.Join(tt.expected, ", ") {
+			t.Errorf("wrong resolution for %q.\nexpected=%v\ngot=%v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestResolvedClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn() { let n = 10; let g = fn() { n }; let n = 20; g() }; f()", 20},
+		{"let f = fn(n) { let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(n) }; f(10)", true},
+		{"let f = fn() { let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(5) }; f()", 120},
+		{"let counter = fn() { let c = 0; fn() { c + 1 } }; let a = counter(); let b = counter(); a() + b()", 2},
+		{"let x = 1; let f = fn() { let y = x; let x = 5; y + x }; f()", 6},
+		{"let f = fn(c) { if (c) { let v = 1; } v }; f(false)", "identifier not found: v"},
+		{"let outer = fn(a) { fn(b) { fn(c) { a + b + c } } }; outer(1)(2)(3)", 6},
+		{"let f = fn() { map([1, 2], fn(x) { x * 2 }) }; len(f())", 2},
+		// g runs before the x of f is bound, so it finds the global until then
+		{"let x = 10; let f = fn() { let g = fn() { x }; let r = g(); let x = 5; r }; f()", 10},
+		{"let x = 10; let f = fn() { let g = fn() { x }; let r = g(); let x = 5; r + g() }; f()", 15},
+		{"let f = fn(x) { fn() { let g = fn() { x }; let r = g(); let x = 5; r } }; f(1)()", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("expected error %q for %q. got=%v", expected, tt.input, evaluated)
+			}
+		}
+	}
+}
+
+// Each REPL line is resolved on its own, so a function resolved before a
+// global is defined still finds it.
+func TestResolveAcrossPrograms(t *testing.T) {
+	env := object.NewEnvironment()
+	lines := []string{
+		"let f = fn(a) { len(a) }",
+		"let len = fn(a) { 42 }",
+		"f([1])",
+	}
+
+	var result object.Object
+	for _, line := range lines {
+		result = Eval(parser.New(lexer.New(line)).ParseProgram(), env)
+	}
+	testIntegerObject(t, result, 42)
+}
+
+func TestClosuresCaptureUsedVariables(t *testing.T) {
+	input := "let f = fn(a, b) { let c = 3; fn() { a + 1 } }; f(1, 2)"
+
+	fn, ok := testEval(input).(*object.Function)
+	if !ok {
+		t.Fatalf("expected a function")
+	}
+	if len(fn.Free) != 1 || fn.Free[0].Value.Inspect() != "1" {
+		t.Errorf("closure should capture only a. got=%d cells", len(fn.Free))
+	}
+}
+
+func TestTrace(t *testing.T) {
+	input := `let count = fn(n) { if (n == 0) { 0 } else { count(n - 1) } };
+let twice = fn(x) { x * 2 };
+twice(count(1))`
+
+	tests := []struct {
+		functions []string
+		expected  string
+	}{
+		{nil, `1:13 FunctionLiteral fn(n)if(n == 0) 0else count((n - 1)) => fn(n) { if(n == 0) 0else count((n - 1)) }
+1:1 LetStatement let count = fn(n)if(n == 0) 0else cou...
+2:13 FunctionLiteral fn(x)(x * 2) => fn(x) { (x * 2) }
+2:1 LetStatement let twice = fn(x)(x * 2);
+3:1 Identifier twice => fn(x) { (x * 2) }
+3:7 Identifier count => fn(n) { if(n == 0) 0else count((n - 1)) }
+3:13 IntegerLiteral 1 => 1
+call count(1)
+  1:25 Identifier n => 1
+  1:30 IntegerLiteral 0 => 0
+  1:27 InfixExpression (n == 0) => false
+  1:46 Identifier count => fn(n) { if(n == 0) 0else count((n - 1)) }
+  1:52 Identifier n => 1
+  1:56 IntegerLiteral 1 => 1
+  1:54 InfixExpression (n - 1) => 0
+tail call count(0)
+  1:25 Identifier n => 0
+  1:30 IntegerLiteral 0 => 0
+  1:27 InfixExpression (n == 0) => true
+  1:35 IntegerLiteral 0 => 0
+  1:35 ExpressionStatement 0 => 0
+  1:21 IfExpression if(n == 0) 0else count((n - 1)) => 0
+  1:21 ExpressionStatement if(n == 0) 0else count((n - 1)) => 0
+  1:51 CallExpression count((n - 1)) => 0
+  1:46 ExpressionStatement count((n - 1)) => 0
+  1:21 IfExpression if(n == 0) 0else count((n - 1)) => 0
+  1:21 ExpressionStatement if(n == 0) 0else count((n - 1)) => 0
+count returned 0
+3:12 CallExpression count(1) => 0
+call twice(0)
+  2:21 Identifier x => 0
+  2:25 IntegerLiteral 2 => 2
+  2:23 InfixExpression (x * 2) => 0
+  2:21 ExpressionStatement (x * 2) => 0
+twice returned 0
+3:6 CallExpression twice(count(1)) => 0
+3:1 ExpressionStatement twice(count(1)) => 0
+`},
+		{[]string{"twice"}, `call twice(0)
+  2:21 Identifier x => 0
+  2:25 IntegerLiteral 2 => 2
+  2:23 InfixExpression (x * 2) => 0
+  2:21 ExpressionStatement (x * 2) => 0
+twice returned 0
+`},
+		{[]string{"missing"}, ``},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		SetTrace(&out, tt.functions...)
+		result := testEval(input)
+		SetTrace(nil)
+
+		testIntegerObject(t, result, 0)
+		if out.String() != tt.expected {
+			t.Errorf("wrong trace for %v.\nexpected:\n%s\ngot:\n%s", tt.functions, tt.expected, out.String())
+		}
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	passing := []string{
+		`assert(true)`,
+		`assert(1, "truthy")`,
+		`assert_eq([1, {"a": 2}], [1, {"a": 2}])`,
+		`assert_eq(1, 1.0)`,
+		`assert_error(fn() { 1 + "a" })`,
+		`assert_error(fn() { 1 + "a" }, "type mismatch")`,
+		`let f = fn() { assert_eq(1, 1) }; f()`,
+	}
+	for _, input := range passing {
+		if result := testEval(input); result != NULL {
+			t.Errorf("%s did not pass. got=%s", input, result.Inspect())
+		}
+	}
+
+	tests := []struct {
+		input   string
+		line    int
+		column  int
+		message string
+	}{
+		{`assert(false)`, 1, 1, "assertion failed"},
+		{`assert(1 > 2, "must be set")`, 1, 1, "assertion failed: must be set"},
+		{"let x = 1;\n  assert_eq(x, 2)", 2, 3, "assert_eq failed\n  expected: 2\n    actual: 1\n            ^"},
+		{`assert_eq([1, 2, 3], [1, 2, 4], "lists")`, 1, 1, "assert_eq failed: lists\n  expected: [1, 2, 4]\n    actual: [1, 2, 3]\n                   ^"},
+		{`assert_eq("é1", "é2")`, 1, 1, "assert_eq failed\n  expected: é2\n    actual: é1\n             ^"},
+		{`assert_eq(1, "1")`, 1, 1, "assert_eq failed\n  expected: 1 (STRING)\n    actual: 1 (INTEGER)"},
+		{"assert_eq(\"a\\nb\\nc\", \"a\\nc\\nd\")", 1, 1, "assert_eq failed\n  --- expected\n  +++ actual\n    a\n  + b\n    c\n  - d"},
+		{`assert_error(fn() { 1 })`, 1, 1, "assert_error failed: got 1, want an error"},
+		{`assert_error(fn() { 1 + "a" }, "unknown")`, 1, 1, `assert_error failed: error "type mismatch: INTEGER + STRING" does not contain "unknown"`},
+		{`assert_error(1)`, 1, 1, "first argument to `assert_error` must be FUNCTION, got INTEGER"},
+		// the assertion in tail position is still located
+		{"let check = fn(x) {\n  assert(x > 1)\n};\ncheck(1)", 2, 3, "assertion failed"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s did not fail", tt.input)
+			continue
+		}
+		if errObj.Message != tt.message {
+			t.Errorf("wrong message for %s.\nwant=%q\ngot= %q", tt.input, tt.message, errObj.Message)
+		}
+		if errObj.Line != tt.line || errObj.Column != tt.column {
+			t.Errorf("wrong position for %s. want=%d:%d, got=%d:%d", tt.input, tt.line, tt.column, errObj.Line, errObj.Column)
+		}
+	}
+
+	// other errors have no position
+	if errObj := testEval(`1 + "a"`).(*object.Error); errObj.Line != 0 {
+		t.Errorf("runtime error has a position: %d:%d", errObj.Line, errObj.Column)
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { throw 1; 2 } catch (e) { 3 }`, 3},
+		{`try { 1 + "a" } catch (e) { e["message"] }`, "type mismatch: INTEGER + STRING"},
+		{`try { 1 / 0 } catch (e) { e["kind"] }`, "runtime"},
+		{`try { throw "boom" } catch (e) { e["message"] + " " + e["kind"] }`, "boom error"},
+		{`try { throw error("gone", "io") } catch (e) { e["kind"] }`, "io"},
+		{`try { assert(false) } catch (e) { e["kind"] }`, "assertion"},
+		{`let f = fn() { throw error("deep") }; try { f() } catch (e) { e["message"] }`, "deep"},
+		// the catch parameter is bound in the scope around the try
+		{`try { throw 5 } catch (e) { 1 }; e["message"]`, "5"},
+		{`let f = fn(n) { let r = try { throw n } catch (e) { e["message"] }; r }; f(7)`, "7"},
+		// errors raised while catching go on
+		{`try { throw "a" } catch (e) { throw "b" }`, "b"},
+		{`try { try { throw "in" } catch (e) { throw e } } catch (e) { e["message"] }`, "in"},
+		// return leaves the function from inside a try
+		{`let f = fn() { try { return 1; } catch (e) { 2 } 3 }; f()`, 1},
+		{`let f = fn() { try { throw "x" } catch (e) { return 2; } 3 }; f()`, 2},
+	}
+
+	// empty blocks give null
+	for _, input := range []string{`try { } catch (e) { }`, `try { throw 1 } catch (e) { }`, `try { } finally { }`, `let x = try { } catch (e) { }; x`} {
+		if result := testEval(input); result != NULL {
+			t.Errorf("%s did not give null. got=%v", input, result)
+		}
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch result := evaluated.(type) {
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("wrong result for %s. want=%q, got=%q", tt.input, expected, result.Value)
+				}
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("wrong error for %s. want=%q, got=%q", tt.input, expected, result.Message)
+				}
+			default:
+				t.Errorf("wrong result for %s. want=%q, got=%s", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestFinally(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { push(log, "try") } finally { push(log, "finally") }`, "[try, finally]"},
+		{`try { throw "x" } catch (e) { push(log, "catch") } finally { push(log, "finally") }`, "[catch, finally]"},
+		{`try { try { throw "x" } finally { push(log, "inner") } } catch (e) { push(log, "outer") }`, "[inner, outer]"},
+		{`let f = fn() { try { return 1; } finally { push(log, "finally") } }; f()`, "[finally]"},
+	}
+
+	for _, tt := range tests {
+		log := &object.Array{}
+		env := object.NewEnvironment()
+		env.Set("log", log)
+		env.Set("push", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			log.Elements = append(log.Elements, args[1])
+			return NULL
+		}})
+
+		Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+		if log.Inspect() != tt.expected {
+			t.Errorf("wrong order for %s. want=%s, got=%s", tt.input, tt.expected, log.Inspect())
+		}
+	}
+
+	// the result of finally is dropped, unless it fails or returns
+	results := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } finally { 2 }`, 1},
+		{`try { throw "x" } finally { 2 }`, "x"},
+		{`try { 1 } finally { throw "f" }`, "f"},
+		{`let f = fn() { try { throw "x" } finally { return 2; } }; f()`, 2},
+	}
+	for _, tt := range results {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testEvalError(t, tt.input, expected)
+		}
+	}
+}
+
+func TestThrow(t *testing.T) {
+	input := "let check = fn(n) {\n  if (n < 0) { throw error(\"negative\", \"range\") }\n  n\n};\nlet outer = fn() { let r = check(-1); r };\nouter()"
+
+	errObj, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned")
+	}
+	if errObj.Message != "negative" || errObj.Kind != "range" {
+		t.Errorf("wrong error. got=%q of kind %q", errObj.Message, errObj.Kind)
+	}
+	if errObj.Line != 2 || errObj.Column != 16 {
+		t.Errorf("wrong position. want=2:16, got=%d:%d", errObj.Line, errObj.Column)
+	}
+	if got := strings.Join(errObj.Stack, ", "); got != "check 5:28, outer 6:1" {
+		t.Errorf("wrong stack. got=%q", got)
+	}
+	// calls in tail position leave no frame of their own
+	errObj = testEval("let f = fn() { g() }; let g = fn() { throw 1 }; f()").(*object.Error)
+	if got := strings.Join(errObj.Stack, ", "); got != "g 1:16" {
+		t.Errorf("wrong stack with tail calls. got=%q", got)
+	}
+
+	// values other than errors become the message
+	testEvalError(t, `throw [1, "a"]`, "[1, a]")
+	testEvalError(t, `throw 1 + "a"`, "type mismatch: INTEGER + STRING")
+}
+
+func TestErrorValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`error("bad")["message"]`, "bad"},
+		{`error("bad")["kind"]`, "error"},
+		{`error("bad", "io")["kind"]`, "io"},
+		{`len(error("bad")["stack"])`, "0"},
+		{`let f = fn() { 1 / 0 }; try { f() } catch (e) { e["stack"] }`, "[f 1:31]"},
+	}
+
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("wrong value of %s. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+
+	testEvalError(t, `error()`, "wrong number of arguments. got=0, want=1 or 2")
+	testEvalError(t, `error(1)`, "argument to `error` must be STRING, got INTEGER")
+	testEvalError(t, `error("a", 1)`, "second argument to `error` must be STRING, got INTEGER")
+}
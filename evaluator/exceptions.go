@@ -0,0 +1,119 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/object"
+)
+
+// Errors are values programs can look into once caught: hashes with the
+// message, kind and stack of the error. The error builtin makes one to
+// throw.
+func init() {
+	builtins["error"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			message, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `error` must be STRING, got %s", args[0].Type())
+			}
+
+			err := &object.Error{Message: message.Value, Kind: "error"}
+			if len(args) == 2 {
+				kind, ok := args[1].(*object.String)
+				if !ok {
+					return newError("second argument to `error` must be STRING, got %s", args[1].Type())
+				}
+				err.Kind = kind.Value
+			}
+			return errorValue(err)
+		},
+	}
+}
+
+// Returns err as the hash catch binds.
+func errorValue(err *object.Error) *object.Hash {
+	stack := []object.Object{}
+	for _, frame := range err.Stack {
+		stack = append(stack, &object.String{Value: frame})
+	}
+
+	hash := object.NewHash()
+	hash.Set(&object.String{Value: "message"}, &object.String{Value: err.Message})
+	hash.Set(&object.String{Value: "kind"}, &object.String{Value: err.Kind})
+	hash.Set(&object.String{Value: "stack"}, &object.Array{Elements: stack})
+	return hash
+}
+
+// Returns the error a throw statement raises with val. A hash with a
+// message, like the ones error returns and catch binds, gives its message,
+// kind and stack, so a caught error can be thrown again. Any other value
+// becomes the message of an error of kind error.
+func throw(stmt *ast.ThrowStatement, val object.Object) *object.Error {
+	err := &object.Error{Message: val.Inspect(), Kind: "error", Line: stmt.Token.Line, Column: stmt.Token.Column}
+
+	hash, ok := val.(*object.Hash)
+	if !ok {
+		return err
+	}
+	field := func(name string) object.Object {
+		pair, _ := hash.Get(&object.String{Value: name})
+		return pair.Value
+	}
+
+	message, ok := field("message").(*object.String)
+	if !ok {
+		return err
+	}
+	err.Message = message.Value
+	if kind, ok := field("kind").(*object.String); ok {
+		err.Kind = kind.Value
+	}
+	if stack, ok := field("stack").(*object.Array); ok {
+		for _, frame := range stack.Elements {
+			if frame, ok := frame.(*object.String); ok {
+				err.Stack = append(err.Stack, frame.Value)
+			}
+		}
+	}
+	return err
+}
+
+// An error raised in the try block, by a throw statement or by the
+// interpreter itself, runs the catch block with the error bound to its
+// parameter instead of ending the program. The finally block runs last
+// either way; an error it raises or a return in it takes the place of the
+// result.
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(te.Block, env)
+
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		bind(te.Param, errorValue(err), env)
+		result = Eval(te.Catch, env)
+	}
+
+	if te.Finally != nil {
+		final := Eval(te.Finally, env)
+		if final != nil && (final.Type() == object.ERROR_OBJ || final.Type() == object.RETURN_VALUE_OBJ) {
+			return final
+		}
+	}
+	// empty blocks have no value of their own
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+// Describes a call an error returned from, for its stack: the name the
+// function was called by and where.
+func stackFrame(call *ast.CallExpression) string {
+	if call == nil {
+		return CallName(call)
+	}
+	tok := ast.TokenOf(call.Function)
+	return fmt.Sprintf("%s %d:%d", CallName(call), tok.Line, tok.Column)
+}
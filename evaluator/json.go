@@ -0,0 +1,228 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"example/sawan/goInterpreter/object"
+)
+
+func init() {
+	for name, builtin := range jsonBuiltins {
+		builtins[name] = builtin
+	}
+}
+
+var jsonBuiltins = map[string]*object.Builtin{
+	"json_parse": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `json_parse` must be STRING, got %s", args[0].Type())
+			}
+
+			dec := json.NewDecoder(strings.NewReader(str.Value))
+			dec.UseNumber()
+
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return newError("json_parse: %s", err)
+			}
+			if _, err := dec.Token(); err != io.EOF {
+				return newError("json_parse: unexpected data after top-level value")
+			}
+			return value
+		},
+	},
+	"json_stringify": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			indent := ""
+			if len(args) == 2 {
+				switch arg := args[1].(type) {
+				case *object.Integer:
+					if arg.IsBig() || arg.Value < 0 || arg.Value > 10 {
+						return newError("indent passed to `json_stringify` must be between 0 and 10, got %s", arg.Inspect())
+					}
+					indent = strings.Repeat(" ", int(arg.Value))
+				case *object.String:
+					indent = arg.Value
+				default:
+					return newError("indent passed to `json_stringify` must be INTEGER or STRING, got %s", args[1].Type())
+				}
+			}
+
+			var out bytes.Buffer
+			if err := encodeJSON(&out, args[0], indent, ""); err != nil {
+				return err
+			}
+			return &object.String{Value: out.String()}
+		},
+	},
+}
+
+// Reads one JSON value from dec. Objects are read key by key so the hash
+// keeps the order of the document.
+func decodeJSON(dec *json.Decoder) (object.Object, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			elements := []object.Object{}
+			for dec.More() {
+				el, err := decodeJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, el)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return &object.Array{Elements: elements}, nil
+		}
+
+		hash := object.NewHash()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(&object.String{Value: key.(string)}, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return hash, nil
+
+	case json.Number:
+		return jsonNumber(tok)
+	case string:
+		return &object.String{Value: tok}, nil
+	case bool:
+		return nativeBoolToBooleanObject(tok), nil
+	default:
+		return NULL, nil
+	}
+}
+
+// Numbers without a fraction or exponent become integers, big ones
+// included. Everything else becomes a float.
+func jsonNumber(num json.Number) (object.Object, error) {
+	if !strings.ContainsAny(string(num), ".eE") {
+		if value, ok := new(big.Int).SetString(string(num), 10); ok {
+			return object.NewBigInteger(value), nil
+		}
+	}
+
+	value, err := strconv.ParseFloat(string(num), 64)
+	if err != nil {
+		return nil, err
+	}
+	return &object.Float{Value: value}, nil
+}
+
+// Writes obj as JSON. With an empty indent the output is compact, otherwise
+// every element goes on its own line, nested by prefix.
+func encodeJSON(out *bytes.Buffer, obj object.Object, indent, prefix string) object.Object {
+	switch obj := obj.(type) {
+	case *object.Null:
+		out.WriteString("null")
+	case *object.Boolean, *object.Integer:
+		out.WriteString(obj.Inspect())
+	case *object.Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return newError("json_stringify: cannot serialize %s", obj.Inspect())
+		}
+		out.WriteString(obj.Inspect())
+	case *object.String:
+		encodeJSONString(out, obj.Value)
+
+	case *object.Array:
+		if len(obj.Elements) == 0 {
+			out.WriteString("[]")
+			return nil
+		}
+
+		out.WriteString("[")
+		for i, el := range obj.Elements {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			newlineJSON(out, indent, prefix+indent)
+			if err := encodeJSON(out, el, indent, prefix+indent); err != nil {
+				return err
+			}
+		}
+		newlineJSON(out, indent, prefix)
+		out.WriteString("]")
+
+	case *object.Hash:
+		if obj.Len() == 0 {
+			out.WriteString("{}")
+			return nil
+		}
+
+		out.WriteString("{")
+		for i, pair := range obj.Pairs() {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return newError("json_stringify: hash keys must be STRING, got %s", pair.Key.Type())
+			}
+
+			if i > 0 {
+				out.WriteString(",")
+			}
+			newlineJSON(out, indent, prefix+indent)
+			encodeJSONString(out, key.Value)
+			out.WriteString(":")
+			if indent != "" {
+				out.WriteString(" ")
+			}
+			if err := encodeJSON(out, pair.Value, indent, prefix+indent); err != nil {
+				return err
+			}
+		}
+		newlineJSON(out, indent, prefix)
+		out.WriteString("}")
+
+	default:
+		return newError("json_stringify: cannot serialize %s", obj.Type())
+	}
+	return nil
+}
+
+func newlineJSON(out *bytes.Buffer, indent, prefix string) {
+	if indent != "" {
+		out.WriteString("\n")
+		out.WriteString(prefix)
+	}
+}
+
+func encodeJSONString(out *bytes.Buffer, str string) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(str)
+	// Encode always terminates the value with a newline
+	out.Truncate(out.Len() - 1)
+}
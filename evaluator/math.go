@@ -0,0 +1,239 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"example/sawan/goInterpreter/object"
+)
+
+func init() {
+	for name, builtin := range mathBuiltins {
+		builtins[name] = builtin
+	}
+}
+
+// Named values that are looked up like builtins.
+var constants = map[string]object.Object{
+	"PI": &object.Float{Value: math.Pi},
+	"E":  &object.Float{Value: math.E},
+}
+
+// The generator behind `random`. It is seeded from the clock unless a script
+// calls `seed` or the embedder calls SeedRandom, which makes runs repeatable.
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// The most bits an integer `pow` computes may have, so a runaway exponent is
+// an error rather than a hang.
+const maxPowBits = 1 << 24
+
+func SeedRandom(seed int64) {
+	rng = rand.New(rand.NewSource(seed))
+}
+
+var mathBuiltins = map[string]*object.Builtin{
+	"abs": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				if arg.IsBig() || arg.Value == math.MinInt64 {
+					value := arg.BigValue()
+					return object.NewBigInteger(value.Abs(value))
+				}
+				if arg.Value < 0 {
+					return &object.Integer{Value: -arg.Value}
+				}
+				return arg
+			case *object.Float:
+				return &object.Float{Value: math.Abs(arg.Value)}
+			default:
+				return newError("argument to `abs` must be INTEGER or FLOAT, got %s", args[0].Type())
+			}
+		},
+	},
+	"min": {
+		Fn: func(args ...object.Object) object.Object {
+			return extremum("min", "<", args)
+		},
+	},
+	"max": {
+		Fn: func(args ...object.Object) object.Object {
+			return extremum("max", ">", args)
+		},
+	},
+	"pow": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			if !isNumeric(args[0]) || !isNumeric(args[1]) {
+				return newError("arguments to `pow` must be INTEGER or FLOAT, got %s and %s", args[0].Type(), args[1].Type())
+			}
+
+			// integer powers stay exact, anything else goes through floats
+			base, baseInt := args[0].(*object.Integer)
+			exponent, expInt := args[1].(*object.Integer)
+			if baseInt && expInt && exponent.BigValue().Sign() >= 0 {
+				value := base.BigValue()
+				// the result has at least (bits of the base - 1) * exponent
+				// bits, and 0, 1 and -1 stay that small
+				if bits := value.BitLen() - 1; bits > 0 {
+					if !exponent.BigValue().IsUint64() || exponent.BigValue().Uint64() > uint64(maxPowBits/bits) {
+						return newError("result of `pow` would be longer than %d bits", maxPowBits)
+					}
+				}
+				return object.NewBigInteger(value.Exp(value, exponent.BigValue(), nil))
+			}
+			return &object.Float{Value: math.Pow(toFloat(args[0]), toFloat(args[1]))}
+		},
+	},
+	"sqrt":  unaryFloatBuiltin("sqrt", math.Sqrt),
+	"sin":   unaryFloatBuiltin("sin", math.Sin),
+	"cos":   unaryFloatBuiltin("cos", math.Cos),
+	"tan":   unaryFloatBuiltin("tan", math.Tan),
+	"asin":  unaryFloatBuiltin("asin", math.Asin),
+	"acos":  unaryFloatBuiltin("acos", math.Acos),
+	"atan":  unaryFloatBuiltin("atan", math.Atan),
+	"log":   unaryFloatBuiltin("log", math.Log),
+	"exp":   unaryFloatBuiltin("exp", math.Exp),
+	"floor": roundingBuiltin("floor", math.Floor),
+	"ceil":  roundingBuiltin("ceil", math.Ceil),
+	"round": roundingBuiltin("round", math.Round),
+	"atan2": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			if !isNumeric(args[0]) || !isNumeric(args[1]) {
+				return newError("arguments to `atan2` must be INTEGER or FLOAT, got %s and %s", args[0].Type(), args[1].Type())
+			}
+			return &object.Float{Value: math.Atan2(toFloat(args[0]), toFloat(args[1]))}
+		},
+	},
+	"random": {
+		Fn: func(args ...object.Object) object.Object {
+			// random() is a float in [0, 1), random(n) an integer in
+			// [0, n) and random(a, b) an integer in [a, b)
+			if len(args) == 0 {
+				return &object.Float{Value: rng.Float64()}
+			}
+			if len(args) > 2 {
+				return newError("wrong number of arguments. got=%d, want=0 to 2", len(args))
+			}
+
+			bounds := []int64{0}
+			for _, arg := range args {
+				integer, ok := arg.(*object.Integer)
+				if !ok || integer.IsBig() {
+					return newError("bounds passed to `random` must be INTEGER, got %s", arg.Inspect())
+				}
+				bounds = append(bounds, integer.Value)
+			}
+			low, high := bounds[len(bounds)-2], bounds[len(bounds)-1]
+
+			if high <= low {
+				return newError("empty range passed to `random`: [%d, %d)", low, high)
+			}
+			// the span of the widest ranges only fits in a uint64, which the
+			// sum below wraps back into range
+			span := uint64(high) - uint64(low)
+			if span <= math.MaxInt64 {
+				return &object.Integer{Value: low + rng.Int63n(int64(span))}
+			}
+			offset := rng.Uint64()
+			for offset >= span {
+				offset = rng.Uint64()
+			}
+			return &object.Integer{Value: int64(uint64(low) + offset)}
+		},
+	},
+	"seed": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			integer, ok := args[0].(*object.Integer)
+			if !ok || integer.IsBig() {
+				return newError("argument to `seed` must be INTEGER, got %s", args[0].Type())
+			}
+			SeedRandom(integer.Value)
+			return NULL
+		},
+	},
+}
+
+// Implements min and max. They take the numbers either as separate
+// arguments or as a single array.
+func extremum(name, operator string, args []object.Object) object.Object {
+	if len(args) == 1 {
+		if arr, ok := args[0].(*object.Array); ok {
+			args = arr.Elements
+		}
+	}
+	if len(args) == 0 {
+		return newError("`%s` needs at least one number", name)
+	}
+
+	result := args[0]
+	for _, arg := range args {
+		if !isNumeric(arg) {
+			return newError("argument to `%s` must be INTEGER or FLOAT, got %s", name, arg.Type())
+		}
+		if evalInfixExpression(operator, arg, result) == TRUE {
+			result = arg
+		}
+	}
+	return result
+}
+
+func unaryFloatBuiltin(name string, fn func(float64) float64) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if !isNumeric(args[0]) {
+				return newError("argument to `%s` must be INTEGER or FLOAT, got %s", name, args[0].Type())
+			}
+			return &object.Float{Value: fn(toFloat(args[0]))}
+		},
+	}
+}
+
+// floor, ceil and round return integers. Integers are returned unchanged.
+func roundingBuiltin(name string, fn func(float64) float64) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return arg
+			case *object.Float:
+				return floatToInteger(name, fn(arg.Value))
+			default:
+				return newError("argument to `%s` must be INTEGER or FLOAT, got %s", name, args[0].Type())
+			}
+		},
+	}
+}
+
+func floatToInteger(name string, f float64) object.Object {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return newError("cannot convert %v to INTEGER in `%s`", f, name)
+	}
+	if f >= math.MinInt64 && f < math.MaxInt64 {
+		return &object.Integer{Value: int64(f)}
+	}
+	value, _ := big.NewFloat(f).Int(nil)
+	return object.NewBigInteger(value)
+}
@@ -0,0 +1,149 @@
+package evaluator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/object"
+	"example/sawan/goInterpreter/parser"
+)
+
+// Finds the source of the modules named in import expressions. Embedders
+// can provide their own to serve modules from somewhere other than disk.
+type ModuleLoader interface {
+	// Returns the source of the module at path, together with a name that
+	// identifies it. Two paths leading to the same module must give the same
+	// name, as modules are cached by it. importer is the name of the module
+	// making the import, empty for the program being run.
+	Load(path, importer string) (name string, source string, err error)
+}
+
+// Loads modules from disk. Relative paths are looked up in the directory of
+// the importing module first, then in each directory of SearchPath in turn.
+type FileLoader struct {
+	SearchPath []string
+}
+
+func (fl *FileLoader) Load(path, importer string) (string, string, error) {
+	candidates := []string{path}
+	if !filepath.IsAbs(path) {
+		candidates = nil
+		if importer != "" {
+			candidates = append(candidates, filepath.Join(filepath.Dir(importer), path))
+		}
+		for _, dir := range fl.SearchPath {
+			candidates = append(candidates, filepath.Join(dir, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		source, err := os.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+
+		name, err := filepath.Abs(candidate)
+		if err != nil {
+			name = candidate
+		}
+		return name, string(source), nil
+	}
+
+	return "", "", fmt.Errorf("module not found in %s", strings.Join(fl.SearchPath, string(os.PathListSeparator)))
+}
+
+// Serves modules from memory, keyed by the path used to import them.
+type MemoryLoader map[string]string
+
+func (ml MemoryLoader) Load(path, importer string) (string, string, error) {
+	source, ok := ml[path]
+	if !ok {
+		return "", "", fmt.Errorf("module not found")
+	}
+	return path, source, nil
+}
+
+var (
+	moduleLoader ModuleLoader = &FileLoader{SearchPath: []string{"."}}
+
+	// exports of every module evaluated so far, by module name
+	moduleCache = map[string]*object.Hash{}
+
+	// names of the modules currently being evaluated, innermost last
+	importStack []string
+)
+
+// Replaces the loader used by import expressions and forgets every module
+// loaded through the previous one.
+func SetModuleLoader(loader ModuleLoader) {
+	moduleLoader = loader
+	moduleCache = map[string]*object.Hash{}
+	importStack = nil
+}
+
+// Evaluates the module the first time it is imported and returns the hash of
+// its exports. Later imports of the same module get the cached hash.
+func evalImportExpression(node *ast.ImportExpression) object.Object {
+	importer := ""
+	if len(importStack) > 0 {
+		importer = importStack[len(importStack)-1]
+	}
+	name, source, err := moduleLoader.Load(node.Path.Value, importer)
+	if err != nil {
+		return newError("import %q: %s", node.Path.Value, err)
+	}
+
+	if exports, ok := moduleCache[name]; ok {
+		return exports
+	}
+
+	for i, loading := range importStack {
+		if loading == name {
+			cycle := append(append([]string{}, importStack[i:]...), name)
+			return newError("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	l := lexer.New(source)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError("import %q: %s", node.Path.Value, strings.Join(p.Errors(), "; "))
+	}
+
+	importStack = append(importStack, name)
+	defer func() { importStack = importStack[:len(importStack)-1] }()
+
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+	expanded, expandErr := ExpandMacros(program, macroEnv)
+	if expandErr != nil {
+		return expandErr
+	}
+
+	if coverage != nil {
+		coverage.Module(name, source, expanded.(*ast.Program))
+	}
+
+	env := object.NewEnvironment()
+	if result := Eval(expanded, env); isError(result) {
+		return result
+	}
+
+	// an export the module returned before is left out
+	exports := object.NewHash()
+	for _, stmt := range program.Statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			if value, ok := env.Get(export.Statement.Name.Value); ok {
+				exports.Set(&object.String{Value: export.Statement.Name.Value}, value)
+			}
+		}
+	}
+
+	moduleCache[name] = exports
+	return exports
+}
@@ -0,0 +1,102 @@
+package evaluator
+
+import (
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/object"
+	"example/sawan/goInterpreter/token"
+)
+
+// Reports whether call is quote(...), which is handled by the evaluator
+// itself instead of being looked up as a function.
+func isQuoteCall(call *ast.CallExpression) bool {
+	return call.Function.TokenLiteral() == "quote"
+}
+
+func quote(call *ast.CallExpression, env *object.Environment) object.Object {
+	if len(call.Arguments) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(call.Arguments))
+	}
+
+	node, err := evalUnquoteCalls(call.Arguments[0], env)
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node}
+}
+
+// Replaces every unquote(...) inside a copy of the quoted node with the AST
+// form of its evaluated argument. The quoted node itself is left as written,
+// as the same quote runs again on every call of the function or macro it is
+// in.
+func evalUnquoteCalls(quoted ast.Node, env *object.Environment) (ast.Node, object.Object) {
+	var err object.Object
+
+	node := ast.Modify(ast.Copy(quoted), func(node ast.Node) ast.Node {
+		if err != nil || !isUnquoteCall(node) {
+			return node
+		}
+
+		call := node.(*ast.CallExpression)
+		if len(call.Arguments) != 1 {
+			err = newError("wrong number of arguments to unquote. got=%d, want=1", len(call.Arguments))
+			return node
+		}
+
+		unquoted := Eval(call.Arguments[0], env)
+		if isError(unquoted) {
+			err = unquoted
+			return node
+		}
+
+		converted := convertObjectToASTNode(unquoted)
+		if converted == nil {
+			err = newError("cannot unquote %s", unquoted.Type())
+			return node
+		}
+		return converted
+	})
+
+	return node, err
+}
+
+func isUnquoteCall(node ast.Node) bool {
+	call, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+
+	return call.Function.TokenLiteral() == "unquote"
+}
+
+// Turns a value back into the literal that evaluates to it. Returns nil for
+// values that have no literal form.
+func convertObjectToASTNode(obj object.Object) ast.Node {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		t := token.Token{Type: token.INT, Literal: obj.Inspect()}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value, Big: obj.Big}
+
+	case *object.Float:
+		t := token.Token{Type: token.FLOAT, Literal: obj.Inspect()}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}
+
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}
+
+	case *object.Boolean:
+		var t token.Token
+		if obj.Value {
+			t = token.Token{Type: token.TRUE, Literal: "true"}
+		} else {
+			t = token.Token{Type: token.FALSE, Literal: "false"}
+		}
+		return &ast.Boolean{Token: t, Value: obj.Value}
+
+	case *object.Quote:
+		return obj.Node
+
+	default:
+		return nil
+	}
+}
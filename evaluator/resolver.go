@@ -0,0 +1,297 @@
+package evaluator
+
+import "example/sawan/goInterpreter/ast"
+
+/*
+The resolver decides before a program runs where each identifier finds its
+value, so the evaluator can skip looking names up through a chain of maps.
+
+Top level bindings are globals and stay in the environment by name. Within a
+function every let and parameter gets a slot in the frame of the call. The
+ones some closure uses get a cell instead, which the closure captures, so
+closures hold on to the variables they use and nothing else.
+
+Functions and if blocks share their environment, so a scope is either the
+program or one function. Lookups follow the order of the statements, as the
+bindings are made when they run, but function bodies only run once the
+statements around them have, so they are resolved last and see every
+binding of the enclosing scopes. A closure can still run before a binding
+it sees is made, so until then it looks the name up where it would
+without that binding: in the next function out binding it, and in the
+globals last.
+*/
+type resolver struct {
+	scopes []*funcScope // parents before their children
+}
+
+/*
+fn: The function, or nil for the program
+bindings: The symbol of every name bound so far
+declared: The symbols bound in the scope, in order
+free: Symbols of enclosing functions used in this one or in its closures
+functions: Function literals found in the scope, resolved last
+*/
+type funcScope struct {
+	parent    *funcScope
+	fn        *ast.FunctionLiteral
+	bindings  map[string]*symbol
+	declared  []*symbol
+	free      []*symbol
+	freeIndex map[*symbol]int
+	refs      []reference
+	functions []*ast.FunctionLiteral
+
+	locals int
+	cells  int
+}
+
+/*
+owner: The scope binding it, whose fn is nil for globals
+param: Whether it is a parameter, so bound before its function body runs
+*/
+type symbol struct {
+	owner    *funcScope
+	param    bool
+	captured bool
+	index    int
+}
+
+/*
+ident: The identifier to annotate
+syms: Where ident finds its value, nearest first, each one looked in while
+the ones before it are not bound yet. A nil symbol stands for the globals.
+*/
+type reference struct {
+	ident *ast.Identifier
+	syms  []*symbol
+}
+
+// Annotates the identifiers and function literals of program. Names bound
+// nowhere in program are globals, which the evaluator looks up by name and
+// then among the builtins.
+func resolve(program *ast.Program) {
+	r := &resolver{}
+	r.resolveScope(r.newScope(nil, nil), program)
+
+	for _, s := range r.scopes {
+		s.assignSlots()
+	}
+	for _, s := range r.scopes {
+		s.annotate()
+	}
+}
+
+func (r *resolver) newScope(parent *funcScope, fn *ast.FunctionLiteral) *funcScope {
+	s := &funcScope{
+		parent:    parent,
+		fn:        fn,
+		bindings:  map[string]*symbol{},
+		freeIndex: map[*symbol]int{},
+	}
+	r.scopes = append(r.scopes, s)
+	return s
+}
+
+// Resolves body in s, then the functions defined in it.
+func (r *resolver) resolveScope(s *funcScope, body ast.Node) {
+	ast.Walk(body, &resolveVisitor{r: r, scope: s})
+
+	for _, fn := range s.functions {
+		inner := r.newScope(s, fn)
+		for _, param := range fn.Parameters {
+			inner.declare(param).param = true
+		}
+		if fn.Body != nil {
+			r.resolveScope(inner, fn.Body)
+		}
+	}
+}
+
+type resolveVisitor struct {
+	r     *resolver
+	scope *funcScope
+}
+
+func (v *resolveVisitor) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+
+	case *ast.LetStatement:
+		v.let(node)
+		return nil
+
+	case *ast.ExportStatement:
+		if node.Statement != nil {
+			v.let(node.Statement)
+		}
+		return nil
+
+	case *ast.Identifier:
+		v.r.reference(v.scope, node)
+		return nil
+
+	case *ast.FunctionLiteral:
+		v.scope.functions = append(v.scope.functions, node)
+		return nil
+
+	// the error is bound once the try block has run
+	case *ast.TryExpression:
+		if node.Block != nil {
+			ast.Walk(node.Block, v)
+		}
+		if node.Param != nil {
+			v.scope.declare(node.Param)
+		}
+		if node.Catch != nil {
+			ast.Walk(node.Catch, v)
+		}
+		if node.Finally != nil {
+			ast.Walk(node.Finally, v)
+		}
+		return nil
+
+	// macros run while the program is expanded, before it is resolved
+	case *ast.MacroLiteral:
+		return nil
+
+	case *ast.CallExpression:
+		if isQuoteCall(node) {
+			v.quote(node)
+			return nil
+		}
+	}
+
+	return v
+}
+
+// The value is resolved first, as the name is only bound once it has run.
+func (v *resolveVisitor) let(stmt *ast.LetStatement) {
+	if stmt.Value != nil {
+		ast.Walk(stmt.Value, v)
+	}
+	if stmt.Name != nil {
+		v.scope.declare(stmt.Name)
+	}
+}
+
+// Only the arguments of unquote calls are evaluated inside quote.
+func (v *resolveVisitor) quote(call *ast.CallExpression) {
+	for _, arg := range call.Arguments {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			if !isUnquoteCall(node) {
+				return true
+			}
+			for _, arg := range node.(*ast.CallExpression).Arguments {
+				if arg != nil {
+					ast.Walk(arg, v)
+				}
+			}
+			return false
+		})
+	}
+}
+
+// Binding a name again in the same scope reuses its symbol, as the
+// environment would overwrite it.
+func (s *funcScope) declare(name *ast.Identifier) *symbol {
+	sym, ok := s.bindings[name.Value]
+	if !ok {
+		sym = &symbol{owner: s}
+		s.bindings[name.Value] = sym
+		s.declared = append(s.declared, sym)
+	}
+	s.refs = append(s.refs, reference{ident: name, syms: []*symbol{sym}})
+	return sym
+}
+
+// Finds every binding ident may get its value from, out to the first
+// parameter or global.
+func (r *resolver) reference(s *funcScope, ident *ast.Identifier) {
+	var syms []*symbol
+	for owner := s; owner != nil && owner.fn != nil; owner = owner.parent {
+		sym, ok := owner.bindings[ident.Value]
+		if !ok {
+			continue
+		}
+
+		// a binding of an enclosing function is captured by every function
+		// between it and the reference
+		if owner != s {
+			sym.captured = true
+			for inner := s; inner != owner; inner = inner.parent {
+				inner.capture(sym)
+			}
+		}
+		syms = append(syms, sym)
+		if sym.param {
+			break
+		}
+	}
+
+	if len(syms) == 0 || !syms[len(syms)-1].param {
+		syms = append(syms, nil)
+	}
+	s.refs = append(s.refs, reference{ident: ident, syms: syms})
+}
+
+func (s *funcScope) capture(sym *symbol) {
+	if _, ok := s.freeIndex[sym]; !ok {
+		s.freeIndex[sym] = len(s.free)
+		s.free = append(s.free, sym)
+	}
+}
+
+// Gives every symbol bound in a function a slot, or a cell when a closure
+// uses it, and lays out the frame its calls need.
+func (s *funcScope) assignSlots() {
+	if s.fn == nil {
+		return
+	}
+
+	for _, sym := range s.declared {
+		if sym.captured {
+			sym.index = s.cells
+			s.cells++
+		} else {
+			sym.index = s.locals
+			s.locals++
+		}
+	}
+
+	frame := &ast.FrameLayout{Locals: s.locals, Cells: s.cells, Free: []int{}}
+	for _, sym := range s.free {
+		frame.Free = append(frame.Free, s.parent.cellOf(sym))
+	}
+	s.fn.Frame = frame
+}
+
+// Returns the index of the cell holding sym in a call of the function.
+func (s *funcScope) cellOf(sym *symbol) int {
+	if sym.owner == s {
+		return sym.index
+	}
+	return s.cells + s.freeIndex[sym]
+}
+
+// Annotates the identifiers of the scope, giving each one that may be
+// looked up before its binding is made the identifier to look up instead.
+func (s *funcScope) annotate() {
+	for _, ref := range s.refs {
+		ident := ref.ident
+		ident.Outer = nil
+		for i, sym := range ref.syms {
+			if i > 0 {
+				ident.Outer = &ast.Identifier{Token: ident.Token, Value: ident.Value}
+				ident = ident.Outer
+			}
+
+			switch {
+			case sym == nil || sym.owner.fn == nil:
+				ident.Scope, ident.Index = ast.GlobalScope, 0
+			case sym.owner == s && !sym.captured:
+				ident.Scope, ident.Index = ast.LocalScope, sym.index
+			default:
+				ident.Scope, ident.Index = ast.FreeScope, s.cellOf(sym)
+			}
+		}
+	}
+}
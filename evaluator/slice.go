@@ -0,0 +1,150 @@
+package evaluator
+
+import (
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/object"
+)
+
+// Controls what happens when an index or slice bound lies outside of an
+// array or string.
+type OutOfRangeMode int
+
+const (
+	// Indexes outside of the value evaluate to null and slice bounds are
+	// clamped to the available elements.
+	OutOfRangeNull OutOfRangeMode = iota
+	// Indexes and slice bounds outside of the value are errors.
+	OutOfRangeError
+)
+
+var outOfRange = OutOfRangeNull
+
+// Sets what happens when an index or slice bound lies outside of an array or
+// string, for every program evaluated after it. The default is
+// OutOfRangeNull.
+func SetOutOfRange(mode OutOfRangeMode) {
+	outOfRange = mode
+}
+
+// Turns index into a position within length. Negative indexes count from the
+// end, so -1 is the last element. ok is false when the position lies outside.
+func resolveIndex(index *object.Integer, length int) (int, bool) {
+	if index.IsBig() {
+		return 0, false
+	}
+
+	idx := index.Value
+	if idx < 0 {
+		idx += int64(length)
+	}
+	if idx < 0 || idx >= int64(length) {
+		return 0, false
+	}
+	return int(idx), true
+}
+
+func indexOutOfRange(index object.Object, length int) object.Object {
+	if outOfRange == OutOfRangeError {
+		return newError("index out of range: %s with length %d", index.Inspect(), length)
+	}
+	return NULL
+}
+
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	var start, end object.Object
+	if node.Start != nil {
+		start = Eval(node.Start, env)
+		if isError(start) {
+			return start
+		}
+	}
+	if node.End != nil {
+		end = Eval(node.End, env)
+		if isError(end) {
+			return end
+		}
+	}
+
+	return sliceObject(left, start, end)
+}
+
+// Slices a string or an array. start and end are nil when they were left
+// out. Like indexes, negative bounds count from the end.
+func sliceObject(left, start, end object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.String:
+		runes := []rune(left.Value)
+		from, to, err := sliceBounds(start, end, len(runes))
+		if err != nil {
+			return err
+		}
+		return &object.String{Value: string(runes[from:to])}
+
+	case *object.Array:
+		from, to, err := sliceBounds(start, end, len(left.Elements))
+		if err != nil {
+			return err
+		}
+		elements := make([]object.Object, to-from)
+		copy(elements, left.Elements[from:to])
+		return &object.Array{Elements: elements}
+
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+}
+
+func sliceBounds(start, end object.Object, length int) (int, int, object.Object) {
+	from, err := sliceBound(start, 0, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := sliceBound(end, length, length)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if from > to {
+		from = to
+	}
+	return from, to, nil
+}
+
+func sliceBound(bound object.Object, missing, length int) (int, object.Object) {
+	if bound == nil {
+		return missing, nil
+	}
+
+	integer, ok := bound.(*object.Integer)
+	if !ok {
+		return 0, newError("slice bound must be INTEGER, got %s", bound.Type())
+	}
+
+	var idx int64
+	switch {
+	case integer.IsBig() && integer.Big.Sign() < 0:
+		idx = -1
+	case integer.IsBig():
+		idx = int64(length) + 1
+	case integer.Value < 0:
+		idx = integer.Value + int64(length)
+	default:
+		idx = integer.Value
+	}
+
+	if idx < 0 || idx > int64(length) {
+		if outOfRange == OutOfRangeError {
+			return 0, newError("slice bound out of range: %s with length %d", bound.Inspect(), length)
+		}
+		if idx < 0 {
+			return 0, nil
+		}
+		return length, nil
+	}
+	return int(idx), nil
+}
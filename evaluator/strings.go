@@ -0,0 +1,355 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"example/sawan/goInterpreter/object"
+)
+
+func init() {
+	for name, builtin := range stringBuiltins {
+		builtins[name] = builtin
+	}
+}
+
+// The longest string the builtins build, in bytes, so a runaway count is an
+// error rather than a crash.
+const maxStringLength = 1 << 30
+
+// All positions and lengths used by the string builtins count runes, not
+// bytes, so they behave the same for non-ASCII text.
+var stringBuiltins = map[string]*object.Builtin{
+	"split": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			strs, err := stringArgs("split", args)
+			if err != nil {
+				return err
+			}
+
+			var parts []string
+			if len(strs) == 1 {
+				parts = strings.Fields(strs[0])
+			} else {
+				parts = strings.Split(strs[0], strs[1])
+			}
+			return stringArray(parts)
+		},
+	},
+	"join": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to `join` must be ARRAY, got %s", args[0].Type())
+			}
+
+			sep := ""
+			if len(args) == 2 {
+				str, ok := args[1].(*object.String)
+				if !ok {
+					return newError("separator passed to `join` must be STRING, got %s", args[1].Type())
+				}
+				sep = str.Value
+			}
+
+			parts := []string{}
+			for _, el := range args[0].(*object.Array).Elements {
+				parts = append(parts, el.Inspect())
+			}
+			return &object.String{Value: strings.Join(parts, sep)}
+		},
+	},
+	"trim": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			strs, err := stringArgs("trim", args)
+			if err != nil {
+				return err
+			}
+
+			if len(strs) == 2 {
+				return &object.String{Value: strings.Trim(strs[0], strs[1])}
+			}
+			return &object.String{Value: strings.TrimSpace(strs[0])}
+		},
+	},
+	"upper": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			strs, err := stringArgs("upper", args)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: strings.ToUpper(strs[0])}
+		},
+	},
+	"lower": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			strs, err := stringArgs("lower", args)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: strings.ToLower(strs[0])}
+		},
+	},
+	"replace": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 && len(args) != 4 {
+				return newError("wrong number of arguments. got=%d, want=3 or 4", len(args))
+			}
+
+			strs, err := stringArgs("replace", args[:3])
+			if err != nil {
+				return err
+			}
+
+			n := int64(-1)
+			if len(args) == 4 {
+				count, ok := args[3].(*object.Integer)
+				if !ok || count.IsBig() {
+					return newError("count passed to `replace` must be INTEGER, got %s", args[3].Type())
+				}
+				n = count.Value
+			}
+			return &object.String{Value: strings.Replace(strs[0], strs[1], strs[2], int(n))}
+		},
+	},
+	"contains": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			strs, err := stringArgs("contains", args)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(strings.Contains(strs[0], strs[1]))
+		},
+	},
+	"starts_with": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			strs, err := stringArgs("starts_with", args)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(strings.HasPrefix(strs[0], strs[1]))
+		},
+	},
+	"ends_with": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			strs, err := stringArgs("ends_with", args)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(strings.HasSuffix(strs[0], strs[1]))
+		},
+	},
+	"index_of": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			strs, err := stringArgs("index_of", args)
+			if err != nil {
+				return err
+			}
+
+			i := strings.Index(strs[0], strs[1])
+			if i < 0 {
+				return &object.Integer{Value: -1}
+			}
+			return &object.Integer{Value: int64(utf8.RuneCountInString(strs[0][:i]))}
+		},
+	},
+	"repeat": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			strs, err := stringArgs("repeat", args[:1])
+			if err != nil {
+				return err
+			}
+
+			count, ok := args[1].(*object.Integer)
+			if !ok || count.IsBig() || count.Value < 0 {
+				return newError("count passed to `repeat` must be a non-negative INTEGER, got %s", args[1].Inspect())
+			}
+			if len(strs[0]) > 0 && count.Value > maxStringLength/int64(len(strs[0])) {
+				return newError("result of `repeat` would be longer than %d bytes", maxStringLength)
+			}
+			return &object.String{Value: strings.Repeat(strs[0], int(count.Value))}
+		},
+	},
+	"chars": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			strs, err := stringArgs("chars", args)
+			if err != nil {
+				return err
+			}
+			return stringArray(strings.Split(strs[0], ""))
+		},
+	},
+
+	"format": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 {
+				return newError("wrong number of arguments. got=%d, want at least 1", len(args))
+			}
+
+			strs, err := stringArgs("format", args[:1])
+			if err != nil {
+				return err
+			}
+			return formatString(strs[0], args[1:])
+		},
+	},
+}
+
+// Unwraps arguments that all have to be strings.
+func stringArgs(name string, args []object.Object) ([]string, object.Object) {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			return nil, newError("argument to `%s` must be STRING, got %s", name, arg.Type())
+		}
+		strs[i] = str.Value
+	}
+	return strs, nil
+}
+
+func stringArray(strs []string) *object.Array {
+	elements := make([]object.Object, len(strs))
+	for i, str := range strs {
+		elements[i] = &object.String{Value: str}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// Returns the character at index as a string, counting runes.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+
+	idx, ok := resolveIndex(index.(*object.Integer), len(runes))
+	if !ok {
+		return indexOutOfRange(index, len(runes))
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+// Implements the `format` builtin. Each verb is handed to fmt together with
+// its flags, width and precision after converting the argument to the Go
+// value the verb expects.
+func formatString(format string, args []object.Object) object.Object {
+	var out strings.Builder
+	argIdx := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+
+		// scan flags, width and precision up to the verb
+		j := i + 1
+		for j < len(format) && strings.IndexByte("+-# 0.123456789", format[j]) >= 0 {
+			j++
+		}
+		if j >= len(format) {
+			return newError("format: missing verb at end of %q", format)
+		}
+
+		spec, verb := format[i:j+1], format[j]
+		i = j
+
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+
+		if argIdx >= len(args) {
+			return newError("format: missing argument for %s", spec)
+		}
+		value, err := formatArgument(verb, args[argIdx])
+		if err != nil {
+			return err
+		}
+		argIdx++
+
+		fmt.Fprintf(&out, spec, value)
+	}
+
+	if argIdx < len(args) {
+		return newError("format: %d unused arguments", len(args)-argIdx)
+	}
+	return &object.String{Value: out.String()}
+}
+
+func formatArgument(verb byte, arg object.Object) (interface{}, object.Object) {
+	switch verb {
+	case 'v', 's':
+		return arg.Inspect(), nil
+	case 'q':
+		if str, ok := arg.(*object.String); ok {
+			return str.Value, nil
+		}
+		return arg.Inspect(), nil
+	case 'd', 'x', 'X', 'o', 'b', 'c':
+		if integer, ok := arg.(*object.Integer); ok {
+			if integer.IsBig() {
+				return integer.Big, nil
+			}
+			return integer.Value, nil
+		}
+		if str, ok := arg.(*object.String); ok && (verb == 'x' || verb == 'X') {
+			return str.Value, nil
+		}
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		if isNumeric(arg) {
+			return toFloat(arg), nil
+		}
+	case 't':
+		if boolean, ok := arg.(*object.Boolean); ok {
+			return boolean.Value, nil
+		}
+	default:
+		return nil, newError("format: unknown verb %%%c", verb)
+	}
+
+	return nil, newError("format: cannot use %s with %%%c", arg.Type(), verb)
+}
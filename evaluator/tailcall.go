@@ -0,0 +1,115 @@
+package evaluator
+
+import (
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/object"
+)
+
+const TAIL_CALL_OBJ = "TAIL_CALL"
+
+// A call that was found in tail position. It is handed back to
+// applyFunction, which makes the call itself instead of letting the body
+// nest another Eval for it. It never escapes to user code.
+type tailCall struct {
+	call *ast.CallExpression
+	fn   *object.Function
+	args []object.Object
+}
+
+func (tc *tailCall) Type() object.ObjectType { return TAIL_CALL_OBJ }
+func (tc *tailCall) Inspect() string         { return "tail call" }
+
+// Evaluates the statements of a function body (or of an if block inside one).
+// Every return statement is in tail position, and so is the last statement
+// when tail is set.
+func evalTailBlock(block *ast.BlockStatement, env *object.Environment, tail bool) object.Object {
+	var result object.Object
+
+	for i, statement := range block.Statements {
+		if debugger != nil {
+			debugger.Statement(statement, env)
+		}
+		if coverage != nil {
+			coverage.Statement(statement)
+		}
+		result = evalTailStatement(statement, env, tail && i == len(block.Statements)-1)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == TAIL_CALL_OBJ {
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+// The statements and expressions evaluated here instead of by Eval are
+// traced here too.
+func evalTailStatement(stmt ast.Statement, env *object.Environment, tail bool) object.Object {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement:
+		val := evalTailExpression(stmt.ReturnValue, env, true)
+		if isError(val) {
+			return traced(stmt, val)
+		}
+		return traced(stmt, &object.ReturnValue{Value: val})
+
+	case *ast.ExpressionStatement:
+		return traced(stmt, evalTailExpression(stmt.Expression, env, tail))
+
+	default:
+		return Eval(stmt, env)
+	}
+}
+
+// Like Eval, but a call to a user function in tail position is returned as a
+// tailCall, and if expressions pass the tail position on to their branches.
+func evalTailExpression(exp ast.Expression, env *object.Environment, tail bool) object.Object {
+	switch exp := exp.(type) {
+	case *ast.CallExpression:
+		if tail && !isQuoteCall(exp) {
+			return traced(exp, evalTailCall(exp, env))
+		}
+	case *ast.IfExpression:
+		return traced(exp, evalTailIf(exp, env, tail))
+	}
+	return Eval(exp, env)
+}
+
+func evalTailCall(exp *ast.CallExpression, env *object.Environment) object.Object {
+	function := Eval(exp.Function, env)
+	if isError(function) {
+		return function
+	}
+
+	args := evalExpressions(exp.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	if fn, ok := function.(*object.Function); ok {
+		return &tailCall{call: exp, fn: fn, args: args}
+	}
+	return applyFunction(exp, function, args)
+}
+
+func evalTailIf(exp *ast.IfExpression, env *object.Environment, tail bool) object.Object {
+	condition := Eval(exp.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if coverage != nil {
+		coverage.Branch(exp, isTruthy(condition))
+	}
+
+	if isTruthy(condition) {
+		return evalTailBlock(exp.Consequence, env, tail)
+	} else if exp.Alternative != nil {
+		return evalTailBlock(exp.Alternative, env, tail)
+	} else {
+		return NULL
+	}
+}
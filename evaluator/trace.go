@@ -0,0 +1,165 @@
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/object"
+)
+
+/*
+Logs the nodes the evaluator evaluates, once they have their value.
+
+functions: Names of the functions whose calls are traced, every call when
+empty
+calls: The running calls, innermost last
+inside: How many running calls are of one of functions
+*/
+type tracer struct {
+	out       io.Writer
+	functions map[string]bool
+	calls     []tracedCall
+	inside    int
+}
+
+/*
+name: The name the function was called by
+matched: Whether it is one of the traced functions
+waiting: The nodes in tail position, which only get their value once the
+call they end in returns, in the order they are logged. The last carried of
+them are of the calls this one took the place of, which end after it.
+*/
+type tracedCall struct {
+	name    string
+	matched bool
+	waiting []ast.Node
+	carried int
+}
+
+var tracing *tracer
+
+// Makes the evaluator log every node it evaluates to w, with its position
+// and value, and the calls it makes, indenting the nodes of each call by
+// how deep it is. When functions are given, only calls of functions called
+// by one of those names are logged, together with the calls they make. A
+// nil w turns tracing off.
+func SetTrace(w io.Writer, functions ...string) {
+	tracing = nil
+	if w == nil {
+		return
+	}
+
+	tracing = &tracer{out: w, functions: map[string]bool{}}
+	for _, name := range functions {
+		tracing.functions[name] = true
+	}
+}
+
+// Logs node with its result, when tracing, and returns the result.
+func traced(node ast.Node, result object.Object) object.Object {
+	if tracing != nil {
+		tracing.node(node, result)
+	}
+	return result
+}
+
+func (t *tracer) enabled() bool {
+	return len(t.functions) == 0 || t.inside > 0
+}
+
+func (t *tracer) print(format string, a ...interface{}) {
+	fmt.Fprintf(t.out, "%s%s\n", strings.Repeat("  ", len(t.calls)), fmt.Sprintf(format, a...))
+}
+
+func (t *tracer) node(node ast.Node, result object.Object) {
+	// the program has no position and its value is the one of its last
+	// statement
+	if _, ok := node.(*ast.Program); ok || !t.enabled() {
+		return
+	}
+
+	if returned, ok := result.(*object.ReturnValue); ok {
+		result = returned.Value
+	}
+	if _, ok := result.(*tailCall); ok && len(t.calls) > 0 {
+		call := &t.calls[len(t.calls)-1]
+		at := len(call.waiting) - call.carried
+		call.waiting = append(call.waiting[:at], append([]ast.Node{node}, call.waiting[at:]...)...)
+		return
+	}
+	t.log(node, result)
+}
+
+func (t *tracer) log(node ast.Node, result object.Object) {
+	tok := ast.TokenOf(node)
+	kind := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	line := fmt.Sprintf("%d:%d %s %s", tok.Line, tok.Column, kind, shorten(node.String(), 40))
+	if result != nil {
+		line += " => " + shorten(result.Inspect(), 60)
+	}
+	t.print("%s", line)
+}
+
+func (t *tracer) call(call *ast.CallExpression, args []object.Object, tail bool) {
+	name := CallName(call)
+	// a call in tail position takes the place of the one it ends, and its
+	// value is the value of the nodes waiting there
+	var waiting []ast.Node
+	if tail {
+		waiting = t.calls[len(t.calls)-1].waiting
+		t.leave()
+	}
+
+	matched := t.functions[name]
+	if matched {
+		t.inside++
+	}
+
+	if t.enabled() {
+		inspected := []string{}
+		for _, arg := range args {
+			inspected = append(inspected, shorten(arg.Inspect(), 20))
+		}
+		kind := "call"
+		if tail {
+			kind = "tail call"
+		}
+		t.print("%s %s(%s)", kind, name, strings.Join(inspected, ", "))
+	}
+
+	t.calls = append(t.calls, tracedCall{name: name, matched: matched, waiting: waiting, carried: len(waiting)})
+}
+
+func (t *tracer) ret(result object.Object) {
+	call := t.calls[len(t.calls)-1]
+	for _, node := range call.waiting {
+		t.log(node, result)
+	}
+
+	enabled := t.enabled()
+	t.leave()
+
+	if enabled && result == nil {
+		t.print("%s returned nothing", call.name)
+	} else if enabled {
+		t.print("%s returned %s", call.name, shorten(result.Inspect(), 60))
+	}
+}
+
+func (t *tracer) leave() {
+	if t.calls[len(t.calls)-1].matched {
+		t.inside--
+	}
+	t.calls = t.calls[:len(t.calls)-1]
+}
+
+// Puts s on one line, cut to at most max characters.
+func shorten(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > max {
+		return string(runes[:max-3]) + "..."
+	}
+	return s
+}
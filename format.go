@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"example/sawan/goInterpreter/format"
+)
+
+// Implements `monkey fmt`: prints, rewrites or diffs the canonical layout
+// of each file, or of stdin when no file is given.
+func formatCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: monkey fmt [flags] [script.mk ...]\n\n")
+		flags.PrintDefaults()
+	}
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	list := flags.Bool("l", false, "list files whose formatting differs")
+	diff := flags.Bool("d", false, "print a diff instead of the formatted source")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return formatFile("<stdin>", src, false, *list, *diff)
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		if formatFile(path, src, *write, *list, *diff) != 0 {
+			status = 1
+		}
+	}
+	return status
+}
+
+func formatFile(path string, src []byte, write, list, diff bool) int {
+	formatted, err := format.Source(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		return 1
+	}
+
+	changed := !bytes.Equal(src, formatted)
+
+	if list && changed {
+		fmt.Println(path)
+	}
+
+	if diff && changed {
+		fmt.Print(unifiedDiff(path, string(src), string(formatted)))
+	}
+
+	if write && changed {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	if !write && !list && !diff {
+		os.Stdout.Write(formatted)
+	}
+	return 0
+}
@@ -0,0 +1,604 @@
+// Package format implements the canonical layout of Monkey source code.
+package format
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/parser"
+	"example/sawan/goInterpreter/token"
+)
+
+// Lines are wrapped when they would get longer than MaxWidth runes.
+const MaxWidth = 80
+
+const indentUnit = "  "
+
+// Parses src and returns it in canonical layout. Source that does not parse
+// is returned as an error holding the parser messages.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	return []byte(Program(program, l.Comments())), nil
+}
+
+// Prints program, placing the comments collected by the lexer next to the
+// statements they were written by. Comments without a position are dropped.
+func Program(program *ast.Program, comments []token.Token) string {
+	p := &printer{comments: comments}
+	p.statements(program.Statements, 0)
+	p.writeComments(-1, 0)
+	return string(p.out)
+}
+
+/*
+out: The output of the statement list being printed
+comments, next: The comments and the first one not yet printed
+lastLine: The source line the last printed statement or comment ended on
+flat: Set while trying a list on one line, so nested lists and blocks do
+not break on their own first
+*/
+type printer struct {
+	out      []byte
+	comments []token.Token
+	next     int
+	lastLine int
+	flat     bool
+}
+
+func (p *printer) statements(statements []ast.Statement, indent int) {
+	// where a ; goes if the next statement needs one to keep the previous if
+	// expression from taking it as an operand
+	semicolonAt := -1
+
+	for _, stmt := range statements {
+		line := startLine(stmt)
+		p.writeComments(line, indent)
+		if line > p.lastLine+1 && p.lastLine > 0 && !p.atBlockStart() {
+			p.out = append(p.out, '\n')
+		}
+
+		text := p.statement(stmt, indent)
+		if semicolonAt >= 0 && strings.ContainsAny(text[:1], "([-") {
+			p.out = append(p.out[:semicolonAt], append([]byte{';'}, p.out[semicolonAt:]...)...)
+		}
+
+		p.out = append(p.out, strings.Repeat(indentUnit, indent)...)
+		p.out = append(p.out, text...)
+		semicolonAt = -1
+		if es, ok := stmt.(*ast.ExpressionStatement); ok && endsInBlock(es.Expression) {
+			semicolonAt = len(p.out)
+		}
+		p.out = append(p.out, '\n')
+
+		if end := ast.LastToken(stmt).Line; end > 0 {
+			p.lastLine = end
+		}
+	}
+}
+
+// Writes the pending comments that start before line, or all of them when
+// line is negative. A comment on the line the last statement ended on stays
+// at the end of that line.
+func (p *printer) writeComments(line, indent int) {
+	for ; p.next < len(p.comments); p.next++ {
+		comment := p.comments[p.next]
+		if line >= 0 && comment.Line >= line {
+			return
+		}
+
+		if comment.Line == p.lastLine && len(p.out) > 0 && p.out[len(p.out)-1] == '\n' {
+			end := len(p.out) - 1
+			p.out = append(p.out[:end], " "+comment.Literal+"\n"...)
+			continue
+		}
+
+		if comment.Line > p.lastLine+1 && len(p.out) > 0 && !p.atBlockStart() {
+			p.out = append(p.out, '\n')
+		}
+		p.out = append(p.out, strings.Repeat(indentUnit, indent)...)
+		p.out = append(p.out, comment.Literal...)
+		p.out = append(p.out, '\n')
+		p.lastLine = comment.Line
+	}
+}
+
+func (p *printer) atBlockStart() bool {
+	return len(p.out) == 0 || strings.HasSuffix(string(p.out), "{\n")
+}
+
+// Whether a comment is waiting to be printed before the given line.
+func (p *printer) commentBefore(line int) bool {
+	return p.next < len(p.comments) && (line <= 0 || p.comments[p.next].Line < line)
+}
+
+func (p *printer) statement(stmt ast.Statement, indent int) string {
+	col := len(indentUnit) * indent
+
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return p.let(stmt, indent, col) + ";"
+
+	case *ast.ExportStatement:
+		return "export " + p.let(stmt.Statement, indent, col+len("export ")) + ";"
+
+	case *ast.ReturnStatement:
+		return "return " + p.expression(stmt.ReturnValue, indent, col+len("return ")) + ";"
+
+	case *ast.ThrowStatement:
+		return "throw " + p.expression(stmt.Value, indent, col+len("throw ")) + ";"
+
+	case *ast.ExpressionStatement:
+		text := p.expression(stmt.Expression, indent, col)
+		if endsInBlock(stmt.Expression) {
+			return text
+		}
+		return text + ";"
+
+	case *ast.BlockStatement:
+		return p.block(stmt, indent, col, false)
+	}
+
+	return stmt.String()
+}
+
+// Whether exp ends in a block, so a statement of it needs no semicolon.
+func endsInBlock(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.IfExpression, *ast.TryExpression:
+		return true
+	}
+	return false
+}
+
+func (p *printer) let(stmt *ast.LetStatement, indent, col int) string {
+	prefix := "let " + stmt.Name.String() + " = "
+	return prefix + p.expression(stmt.Value, indent, col+width(prefix))
+}
+
+// Prints a block starting at column col. Blocks of a single expression or
+// return statement are kept on one line when allowed and they fit.
+func (p *printer) block(block *ast.BlockStatement, indent, col int, allowInline bool) string {
+	if len(block.Statements) == 0 && !p.commentBefore(block.Rbrace.Line) {
+		return "{}"
+	}
+
+	if allowInline && len(block.Statements) == 1 && !p.commentBefore(block.Rbrace.Line) {
+		if text, ok := p.inlineStatement(block.Statements[0], indent, col+2); ok {
+			if inline := "{ " + text + " }"; p.flat || col+width(inline) <= MaxWidth {
+				return inline
+			}
+		}
+	}
+
+	outer, outerLast, flat := p.out, p.lastLine, p.flat
+	p.out = []byte("{\n")
+	p.flat = false
+	if block.Token.Line > 0 {
+		p.lastLine = block.Token.Line
+	}
+
+	p.statements(block.Statements, indent+1)
+	if block.Rbrace.Line > 0 {
+		p.writeComments(block.Rbrace.Line, indent+1)
+	}
+
+	p.out = append(p.out, strings.Repeat(indentUnit, indent)...)
+	p.out = append(p.out, '}')
+	text := string(p.out)
+
+	p.out, p.flat = outer, flat
+	if block.Rbrace.Line > 0 {
+		p.lastLine = block.Rbrace.Line
+	} else {
+		p.lastLine = outerLast
+	}
+	return text
+}
+
+func (p *printer) inlineStatement(stmt ast.Statement, indent, col int) (string, bool) {
+	next, lastLine := p.next, p.lastLine
+
+	var text string
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		text = p.expression(stmt.Expression, indent, col)
+	case *ast.ReturnStatement:
+		text = "return " + p.expression(stmt.ReturnValue, indent, col+len("return "))
+	case *ast.ThrowStatement:
+		text = "throw " + p.expression(stmt.Value, indent, col+len("throw "))
+	default:
+		return "", false
+	}
+
+	if strings.Contains(text, "\n") || p.next != next {
+		p.next, p.lastLine = next, lastLine
+		return "", false
+	}
+	return text, true
+}
+
+func (p *printer) expression(exp ast.Expression, indent, col int) string {
+	switch exp := exp.(type) {
+	case nil:
+		return ""
+
+	case *ast.Identifier:
+		return exp.Value
+
+	case *ast.IntegerLiteral:
+		if exp.Token.Literal != "" {
+			return exp.Token.Literal
+		}
+		if exp.Big != nil {
+			return exp.Big.String()
+		}
+		return strconv.FormatInt(exp.Value, 10)
+
+	case *ast.FloatLiteral:
+		if exp.Token.Literal != "" {
+			return exp.Token.Literal
+		}
+		text := strconv.FormatFloat(exp.Value, 'f', -1, 64)
+		if !strings.Contains(text, ".") {
+			text += ".0"
+		}
+		return text
+
+	case *ast.StringLiteral:
+		return quote(exp.Value)
+
+	case *ast.Boolean:
+		return strconv.FormatBool(exp.Value)
+
+	case *ast.PrefixExpression:
+		return exp.Operator + p.operand(exp.Right, parser.PREFIX, indent, col+width(exp.Operator))
+
+	case *ast.InfixExpression:
+		precedence := parser.Precedence(token.TokenType(exp.Operator))
+		left := p.operand(exp.Left, precedence, indent, col)
+		if text, ok := p.commentedOperator(left, exp, precedence, indent); ok {
+			return text
+		}
+		op := " " + exp.Operator + " "
+		// the parser is left associative, so an equal right operand needs
+		// parentheses
+		right := p.operand(exp.Right, precedence+1, indent, endColumn(col, left)+width(op))
+		return left + op + right
+
+	case *ast.IfExpression:
+		next, lastLine := p.next, p.lastLine
+		text, mixed := p.ifExpression(exp, indent, col, true)
+		if mixed {
+			// both branches go on their own lines when one of them has to
+			p.next, p.lastLine = next, lastLine
+			text, _ = p.ifExpression(exp, indent, col, false)
+		}
+		return text
+
+	case *ast.TryExpression:
+		return p.tryExpression(exp, indent, col)
+
+	case *ast.FunctionLiteral:
+		return p.function("fn", exp.Parameters, exp.ReturnType, exp.Body, indent, col)
+
+	case *ast.MacroLiteral:
+		return p.function("macro", exp.Parameters, nil, exp.Body, indent, col)
+
+	case *ast.CallExpression:
+		callee := p.operand(exp.Function, parser.CALL, indent, col)
+		at := listSource{exp.Token, exp.Rparen, func(i int) (int, int) { return lineSpan(exp.Arguments[i]) }}
+		return callee + p.list("(", ")", at, len(exp.Arguments), indent, endColumn(col, callee),
+			func(i, indent, col int) string {
+				return p.expression(exp.Arguments[i], indent, col)
+			})
+
+	case *ast.ArrayLiteral:
+		at := listSource{exp.Token, exp.Rbracket, func(i int) (int, int) { return lineSpan(exp.Elements[i]) }}
+		return p.list("[", "]", at, len(exp.Elements), indent, col,
+			func(i, indent, col int) string {
+				return p.expression(exp.Elements[i], indent, col)
+			})
+
+	case *ast.HashLiteral:
+		at := listSource{exp.Token, exp.Rbrace, func(i int) (int, int) { return lineSpan(exp.Pairs[i].Key, exp.Pairs[i].Value) }}
+		return p.list("{", "}", at, len(exp.Pairs), indent, col,
+			func(i, indent, col int) string {
+				key := p.expression(exp.Pairs[i].Key, indent, col) + ": "
+				return key + p.expression(exp.Pairs[i].Value, indent, endColumn(col, key))
+			})
+
+	case *ast.IndexExpression:
+		left := p.operand(exp.Left, parser.INDEX, indent, col)
+		return left + "[" + p.expression(exp.Index, indent, endColumn(col, left)+1) + "]"
+
+	case *ast.SliceExpression:
+		text := p.operand(exp.Left, parser.INDEX, indent, col) + "["
+		text += p.expression(exp.Start, indent, endColumn(col, text)) + ":"
+		text += p.expression(exp.End, indent, endColumn(col, text))
+		return text + "]"
+
+	case *ast.ImportExpression:
+		return "import " + quote(exp.Path.Value)
+	}
+
+	return exp.String()
+}
+
+// Also reports whether one branch ended up on one line and the other did
+// not.
+func (p *printer) ifExpression(exp *ast.IfExpression, indent, col int, allowInline bool) (string, bool) {
+	text := "if (" + p.expression(exp.Condition, indent, col+len("if (")) + ") "
+	consequence := p.block(exp.Consequence, indent, endColumn(col, text), allowInline)
+	text += consequence
+	if exp.Alternative == nil {
+		return text, false
+	}
+
+	text += " else "
+	alternative := p.block(exp.Alternative, indent, endColumn(col, text), allowInline)
+	mixed := strings.Contains(consequence, "\n") != strings.Contains(alternative, "\n")
+	return text + alternative, mixed
+}
+
+// The blocks of a try expression always go on lines of their own.
+func (p *printer) tryExpression(exp *ast.TryExpression, indent, col int) string {
+	text := "try " + p.block(exp.Block, indent, col+len("try "), false)
+	if exp.Catch != nil {
+		text += " catch (" + exp.Param.Value + ") "
+		text += p.block(exp.Catch, indent, endColumn(col, text), false)
+	}
+	if exp.Finally != nil {
+		text += " finally "
+		text += p.block(exp.Finally, indent, endColumn(col, text), false)
+	}
+	return text
+}
+
+// Prints the operator and right operand of exp after left when comments
+// were written between the operands. The comments stay behind the operator
+// or on lines of their own, with the right operand on the next line.
+func (p *printer) commentedOperator(left string, exp *ast.InfixExpression, precedence, indent int) (string, bool) {
+	_, last := lineSpan(exp.Left)
+	first, _ := lineSpan(exp.Right)
+	if last <= 0 || p.next >= len(p.comments) || p.comments[p.next].Line < last || p.comments[p.next].Line >= first {
+		return "", false
+	}
+
+	inner := strings.Repeat(indentUnit, indent+1)
+	text := left + " " + exp.Operator
+	for ; p.next < len(p.comments) && p.comments[p.next].Line < first; p.next++ {
+		if comment := p.comments[p.next]; comment.Line == last {
+			text += " " + comment.Literal
+		} else {
+			text += "\n" + inner + comment.Literal
+		}
+	}
+	return text + "\n" + inner + p.operand(exp.Right, precedence+1, indent+1, width(inner)), true
+}
+
+// Prints exp in a position that binds with the given precedence, adding
+// parentheses only when the parser would otherwise group it differently.
+func (p *printer) operand(exp ast.Expression, precedence, indent, col int) string {
+	if bindingPower(exp) < precedence {
+		return "(" + p.expression(exp, indent, col+1) + ")"
+	}
+	return p.expression(exp, indent, col)
+}
+
+func bindingPower(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(token.TokenType(exp.Operator))
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	// folded constants can be negative
+	case *ast.IntegerLiteral:
+		if strings.HasPrefix(exp.Token.Literal, "-") {
+			return parser.PREFIX
+		}
+	case *ast.FloatLiteral:
+		if strings.HasPrefix(exp.Token.Literal, "-") {
+			return parser.PREFIX
+		}
+	}
+	return parser.INDEX + 1
+}
+
+func (p *printer) function(keyword string, params []*ast.Identifier, result ast.TypeExpression, body *ast.BlockStatement, indent, col int) string {
+	names := []string{}
+	for _, param := range params {
+		names = append(names, param.String())
+	}
+
+	head := keyword + "(" + strings.Join(names, ", ") + ") "
+	if result != nil {
+		head = keyword + "(" + strings.Join(names, ", ") + "): " + result.String() + " "
+	}
+	return head + p.block(body, indent, col+width(head), true)
+}
+
+/*
+Where a list was written, so the comments inside it can stay there.
+
+open, close: The brackets around the list, without a position when the
+list was not parsed
+items: The first and last source lines of each item
+*/
+type listSource struct {
+	open, close token.Token
+	items       func(i int) (first, last int)
+}
+
+// Prints n items between open and close on one line, or one item per line
+// when that line would get longer than MaxWidth or comments were written
+// inside the list.
+func (p *printer) list(open, close string, at listSource, n, indent, col int, item func(i, indent, col int) string) string {
+	if p.commentInside(at) {
+		return p.commentedList(open, close, at, n, indent, item)
+	}
+
+	next, lastLine, flat := p.next, p.lastLine, p.flat
+
+	p.flat = true
+	text := open
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			text += ", "
+		}
+		text += item(i, indent, endColumn(col, text))
+	}
+	text += close
+	p.flat = flat
+
+	if n == 0 || flat || strings.Contains(text, "\n") || col+width(text) <= MaxWidth {
+		return text
+	}
+
+	p.next, p.lastLine = next, lastLine
+	inner := strings.Repeat(indentUnit, indent+1)
+	text = open + "\n"
+	for i := 0; i < n; i++ {
+		text += inner + item(i, indent+1, width(inner))
+		if i < n-1 {
+			text += ","
+		}
+		text += "\n"
+	}
+	return text + strings.Repeat(indentUnit, indent) + close
+}
+
+// Whether the next comment to print was written between the brackets of the
+// list.
+func (p *printer) commentInside(at listSource) bool {
+	if at.open.Line <= 0 || at.close.Line <= 0 || p.next >= len(p.comments) {
+		return false
+	}
+	line := p.comments[p.next].Line
+	return line >= at.open.Line && line < at.close.Line
+}
+
+// Prints one item per line, with each comment of the list before the item it
+// was written above, or after the item or bracket it was written behind.
+func (p *printer) commentedList(open, close string, at listSource, n, indent int, item func(i, indent, col int) string) string {
+	inner := strings.Repeat(indentUnit, indent+1)
+	text := open
+	// a comment on line goes behind what text ends in, unless the next item
+	// starts on that line too
+	behind := func(line, next int) {
+		if p.next < len(p.comments) && p.comments[p.next].Line == line && next > line {
+			text += " " + p.comments[p.next].Literal
+			p.next++
+		}
+	}
+	before := func(line int) {
+		for ; p.next < len(p.comments) && p.comments[p.next].Line < line; p.next++ {
+			text += inner + p.comments[p.next].Literal + "\n"
+		}
+	}
+	start := func(i int) int {
+		if i == n {
+			return at.close.Line
+		}
+		first, _ := at.items(i)
+		return first
+	}
+
+	behind(at.open.Line, start(0))
+	text += "\n"
+	for i := 0; i < n; i++ {
+		before(start(i))
+		text += inner + item(i, indent+1, width(inner))
+		if i < n-1 {
+			text += ","
+		}
+		_, last := at.items(i)
+		behind(last, start(i+1))
+		text += "\n"
+	}
+	before(at.close.Line)
+	p.lastLine = at.close.Line
+	return text + strings.Repeat(indentUnit, indent) + close
+}
+
+// The first and last source lines of nodes.
+func lineSpan(nodes ...ast.Node) (int, int) {
+	first, last := 0, 0
+	for _, node := range nodes {
+		ast.Inspect(node, func(n ast.Node) bool {
+			if n == nil {
+				return false
+			}
+			if line := ast.TokenOf(n).Line; line > 0 && (first == 0 || line < first) {
+				first = line
+			}
+			return true
+		})
+		if line := ast.LastToken(node).Line; line > last {
+			last = line
+		}
+	}
+	return first, last
+}
+
+// Quotes s using only the escapes the lexer understands.
+func quote(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
+func width(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// The column after printing s starting at col.
+func endColumn(col int, s string) int {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return width(s[i+1:])
+	}
+	return col + width(s)
+}
+
+func startLine(stmt ast.Statement) int {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token.Line
+	case *ast.ExportStatement:
+		return stmt.Token.Line
+	case *ast.ReturnStatement:
+		return stmt.Token.Line
+	case *ast.ThrowStatement:
+		return stmt.Token.Line
+	case *ast.ExpressionStatement:
+		return stmt.Token.Line
+	case *ast.BlockStatement:
+		return stmt.Token.Line
+	}
+	return 0
+}
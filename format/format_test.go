@@ -0,0 +1,231 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=5", "let x = 5;\n"},
+		{"let add = fn(a,b){a+b};", "let add = fn(a, b) { a + b };\n"},
+		{"(1 + 2) * 3 - (4 - 5)", "(1 + 2) * 3 - (4 - 5);\n"},
+		{"((1 * 2) + 3)", "1 * 2 + 3;\n"},
+		{"a - (b - c); (a - b) - c", "a - (b - c);\na - b - c;\n"},
+		{"-(a + b); (-a)[0]; -a[0]; !(a == b)", "-(a + b);\n(-a)[0];\n-a[0];\n!(a == b);\n"},
+		{"(a + b)(c); (fn(x){x})(1)", "(a + b)(c);\nfn(x) { x }(1);\n"},
+		{`"a\"b\\c\nd"`, `"a\"b\\c\nd";` + "\n"},
+		{"arr[1 : ]; arr[:2]; arr[ : ]", "arr[1:];\narr[:2];\narr[:];\n"},
+		{"{ }; {1:2,}", "{};\n{1: 2};\n"},
+		{"fn(){}", "fn() {};\n"},
+		{"if(a){b}else{c}", "if (a) { b } else { c }\n"},
+		{"if (a) { b }; -c", "if (a) { b };\n-c;\n"},
+		{"if (a) { b }; c", "if (a) { b }\nc;\n"},
+		{"let f = fn(x) { let y = x; y }", "let f = fn(x) {\n  let y = x;\n  y;\n};\n"},
+		{"export let a = import \"lib.mk\"", "export let a = import \"lib.mk\";\n"},
+		{"let m = macro(a) { quote(unquote(a)) }", "let m = macro(a) { quote(unquote(a)) };\n"},
+		{"1.50 + 2", "1.50 + 2;\n"},
+		{"let a = 1;\n\n\n\nlet b = 2;", "let a = 1;\n\nlet b = 2;\n"},
+		{"if(a){throw error(\"bad\")}", "if (a) { throw error(\"bad\") }\n"},
+		{"try{f()}catch(e){g(e)}finally{h()}", "try {\n  f();\n} catch (e) {\n  g(e);\n} finally {\n  h();\n}\n"},
+		{"let x = try { f() } finally { h() }", "let x = try {\n  f();\n} finally {\n  h();\n};\n"},
+	}
+
+	for _, tt := range tests {
+		formatted, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("Source(%q) failed: %s", tt.input, err)
+			continue
+		}
+
+		if string(formatted) != tt.expected {
+			t.Errorf("Source(%q) wrong.\nexpected=%q\ngot=%q", tt.input, tt.expected, formatted)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// header
+let a = 1; // one
+let f = fn(x) { // opens
+  // leading
+  x
+
+  // closing
+};
+
+
+// before b
+let b = 2;
+// the end`
+
+	expected := `// header
+let a = 1; // one
+let f = fn(x) { // opens
+  // leading
+  x;
+
+  // closing
+};
+
+// before b
+let b = 2;
+// the end
+`
+
+	formatted, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("Source failed: %s", err)
+	}
+
+	if string(formatted) != expected {
+		t.Errorf("comments not preserved.\nexpected=%q\ngot=%q", expected, formatted)
+	}
+}
+
+// Comments inside a list keep it on several lines, each comment by the item
+// it was written next to.
+func TestCommentsInLists(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let h = {\n  \"a\": 1, // first\n  \"b\": 2\n};",
+			"let h = {\n  \"a\": 1, // first\n  \"b\": 2\n};\n",
+		},
+		{
+			"puts(1, // one\n2)",
+			"puts(\n  1, // one\n  2\n);\n",
+		},
+		{
+			"let xs = [ // numbers\n  // leading\n  1, 2, // two\n  [3, // inner\n  4]\n  // trailing\n]; // after",
+			"let xs = [ // numbers\n  // leading\n  1,\n  2, // two\n  [\n    3, // inner\n    4\n  ]\n  // trailing\n]; // after\n",
+		},
+		{
+			"f([1,\n  2]); // after",
+			"f([1, 2]); // after\n",
+		},
+		{
+			"let x = 1 + // c\n 2;\nputs(x);",
+			"let x = 1 + // c\n  2;\nputs(x);\n",
+		},
+		{
+			"let y = a * b // first\n  // second\n  - c;",
+			"let y = a * b - // first\n  // second\n  c;\n",
+		},
+		{
+			"if (a == // both\n  b) { [1 + // one\n 1] }",
+			"if (a == // both\n  b) {\n  [\n    1 + // one\n      1\n  ];\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		formatted, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("Source(%q) failed: %s", tt.input, err)
+		}
+		if string(formatted) != tt.expected {
+			t.Errorf("comments in %q moved.\nexpected=%q\ngot=%q", tt.input, tt.expected, formatted)
+		}
+
+		again, _ := Source(formatted)
+		if string(again) != string(formatted) {
+			t.Errorf("formatting is not idempotent.\nfirst=%q\nsecond=%q", formatted, again)
+		}
+	}
+}
+
+func TestLineWrapping(t *testing.T) {
+	input := `let names = ["alpha", "beta", "gamma", "delta", "epsilon", "zeta", "eta", "theta"];
+f(1, 2);`
+
+	expected := `let names = [
+  "alpha",
+  "beta",
+  "gamma",
+  "delta",
+  "epsilon",
+  "zeta",
+  "eta",
+  "theta"
+];
+f(1, 2);
+`
+
+	formatted, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("Source failed: %s", err)
+	}
+
+	if string(formatted) != expected {
+		t.Errorf("wrong wrapping.\nexpected=%q\ngot=%q", expected, formatted)
+	}
+
+	for _, line := range strings.Split(string(formatted), "\n") {
+		if len(line) > MaxWidth {
+			t.Errorf("line longer than %d: %q", MaxWidth, line)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	inputs := []string{
+		`let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; puts(fib(10));`,
+		`let m = {"a": [1, 2, 3][1:], "b": {true: -5 * (2 + 3)}, 3: fn(x, y) { x / y }};`,
+		`let reduce = fn(arr, initial, f) {
+  let iter = fn(arr, result) { if (len(arr) == 0) { result } else { iter(rest(arr), f(result, first(arr))); } };
+  iter(arr, initial);
+};
+reduce([1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23], 0, fn(a, b) { a + b });`,
+		`if (a) { 1 } else { if (b) { 2 } else { 3 } }; (-1)[0]; !-a; a * -b; (a < b) == (c > d);`,
+		`let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a); } else { unquote(b); }); };`,
+		`let s = "tab\there \"quoted\" back\\slash"; s[1:-1]; s[:2][0];`,
+		`let add = fn(a: int, b: [string], f: fn(int): {string: bool}, g: fn): int { a }; let x: float = 1.5;`,
+		`let safe = fn(f) { try { f() } catch (e) { if (e["kind"] == "io") { throw e; } null } finally { puts("done") } }; try { throw "x" } finally { 1 } (-1);`,
+	}
+
+	for _, input := range inputs {
+		l := lexer.New(input)
+		p := parser.New(l)
+		original := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("input %q has parser errors: %v", input, p.Errors())
+		}
+
+		formatted, err := Source([]byte(input))
+		if err != nil {
+			t.Fatalf("Source(%q) failed: %s", input, err)
+		}
+
+		reparsed := parser.New(lexer.New(string(formatted))).ParseProgram()
+		if reparsed.String() != original.String() {
+			t.Errorf("formatting changed the program.\noriginal=%q\nformatted=%q", original.String(), reparsed.String())
+		}
+
+		again, err := Source(formatted)
+		if err != nil {
+			t.Fatalf("formatted source does not parse: %s\n%s", err, formatted)
+		}
+		if string(again) != string(formatted) {
+			t.Errorf("formatting is not idempotent.\nfirst=%q\nsecond=%q", formatted, again)
+		}
+	}
+}
+
+func TestSourceParseError(t *testing.T) {
+	_, err := Source([]byte("let = 5;"))
+	if err == nil {
+		t.Fatalf("expected an error for invalid source")
+	}
+
+	if !strings.Contains(err.Error(), "expected next token to be IDENT") {
+		t.Errorf("wrong error. got=%q", err.Error())
+	}
+}
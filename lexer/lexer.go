@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"example/sawan/goInterpreter/token"
 )
 
@@ -9,12 +11,18 @@ Input: Contains the string of Code
 Position: Has the position of the previous position
 readPosition: Has the positon of the character to go through
 ch: Contains the character
+line, column: Where ch is in the source, both starting at 1
+comments: The // comments skipped so far, in source order
 */
 type Lexer struct {
 	input        string
 	position     int
 	readPosition int
 	ch           byte
+
+	line     int
+	column   int
+	comments []token.Token
 }
 
 // Checks whether current character is a space type character.
@@ -24,11 +32,37 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
+// Skips whitespace and // comments, recording the comments on the way.
+func (l *Lexer) skipWhitespaceAndComments() {
+	l.skipWhitespace()
+	for l.ch == '/' && l.peekChar() == '/' {
+		l.comments = append(l.comments, l.readComment())
+		l.skipWhitespace()
+	}
+}
+
+// Reads a comment up to, but not including, the end of the line.
+func (l *Lexer) readComment() token.Token {
+	tok := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	tok.Literal = strings.TrimRight(l.input[position:l.position], " \t\r")
+	return tok
+}
+
+// Returns the comments skipped by NextToken so far.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
 // Converts the literal input into constant Tokens and returns it
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
-	l.skipWhitespace()
+	l.skipWhitespaceAndComments()
+	line, column := l.line, l.column
 
 	switch l.ch {
 	case '=':
@@ -72,8 +106,14 @@ func (l *Lexer) NextToken() token.Token {
 	case '}':
 		tok = newToken(token.RBRACES, l.ch)
 	case '"':
+		start := l.position
 		tok.Type = token.STRING
 		tok.Literal = l.readString()
+		// up to the closing quote, or the end of an unterminated string
+		tok.Length = l.position + 1 - start
+		if l.ch == 0 {
+			tok.Length = len(l.input) - start
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -87,10 +127,11 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Line, tok.Column = line, column
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
+			tok.Line, tok.Column = line, column
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -98,34 +139,48 @@ func (l *Lexer) NextToken() token.Token {
 	}
 
 	l.readChar()
+	tok.Line, tok.Column = line, column
 	return tok
 }
 
 func (l *Lexer) peekChar() byte {
-	if l.position >= len(l.input) {
+	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
 		return l.input[l.readPosition]
 	}
 }
 
-func (l *Lexer) readNumber() string {
+// Reads an integer, or a float when the digits are followed by a dot and
+// more digits.
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	position := l.position
+	tokenType := token.TokenType(token.INT)
 
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+	return tokenType, l.input[position:l.position]
 }
 
 func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+// Identifiers start with a letter and may contain digits after that, as in
+// atan2.
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
@@ -142,13 +197,20 @@ func newToken(tokenType token.TokenType, ch byte) token.Token {
 
 // Creates a new Lexer Struct and returns it with default positions and character set
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 // Moves the position forward only if it is within the bounds.
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -159,15 +221,40 @@ func (l *Lexer) readChar() {
 	l.readPosition += 1
 }
 
+// Reads up to the closing quote, replacing the escape sequences \n, \t, \r,
+// \" and \\. Any other backslash is kept as it is.
 func (l *Lexer) readString() string {
-	position := l.position + 1
+	var out strings.Builder
 	for {
 		l.readChar()
 
 		if l.ch == '"' || l.ch == 0 {
 			break
 		}
+
+		if l.ch == '\\' {
+			l.readChar()
+			switch l.ch {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case 'r':
+				out.WriteByte('\r')
+			case '"', '\\':
+				out.WriteByte(l.ch)
+			case 0:
+				out.WriteByte('\\')
+				return out.String()
+			default:
+				out.WriteByte('\\')
+				out.WriteByte(l.ch)
+			}
+			continue
+		}
+
+		out.WriteByte(l.ch)
 	}
 
-	return l.input[position:l.position]
+	return out.String()
 }
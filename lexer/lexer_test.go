@@ -142,3 +142,137 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"tab\there"`, "tab\there"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"\d"`, `\d`},
+		{`"héllo 世界"`, "héllo 世界"},
+		{`"unterminated\"`, `unterminated"`},
+	}
+
+	for i, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != token.STRING {
+			t.Fatalf("tests[%d] - tokenType wrong, expected=%q, got=%q", i, token.STRING, tok.Type)
+		}
+
+		if tok.Literal != tt.expected {
+			t.Errorf("tests[%d] - tokenLiteral wrong, expected=%q, got=%q", i, tt.expected, tok.Literal)
+		}
+
+		if tok.Width() != len(tt.input) {
+			t.Errorf("tests[%d] - width wrong, expected=%d, got=%d", i, len(tt.input), tok.Width())
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `5 3.14 10.0 7. a[1:2] atan2 ==`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "5"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "10.0"},
+		{token.INT, "7"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "a"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COLON, ":"},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.IDENT, "atan2"},
+		{token.EQ, "=="},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token, expected=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	input := "let x = 5;\n  x + \"ab\"\n"
+
+	tests := []struct {
+		expectedLiteral string
+		line, column    int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"+", 2, 5},
+		{"ab", 2, 7},
+		{"", 3, 1},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.line || tok.Column != tt.column {
+			t.Errorf("tests[%d] - position of %q wrong, expected=%d:%d, got=%d:%d",
+				i, tok.Literal, tt.line, tt.column, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// first
+let a = 10 / 2; // second
+// third`
+
+	expected := []token.TokenType{
+		token.LET, token.IDENT, token.ASSIGN, token.INT, token.SLASH, token.INT, token.SEMICOLON, token.EOF,
+	}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokenType wrong, expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+
+	comments := l.Comments()
+	if len(comments) != 3 {
+		t.Fatalf("wrong number of comments. got=%d", len(comments))
+	}
+
+	expectedComments := []token.Token{
+		{Type: token.COMMENT, Literal: "// first", Line: 1, Column: 1},
+		{Type: token.COMMENT, Literal: "// second", Line: 2, Column: 17},
+		{Type: token.COMMENT, Literal: "// third", Line: 3, Column: 1},
+	}
+	for i, comment := range comments {
+		if comment != expectedComments[i] {
+			t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", i, expectedComments[i], comment)
+		}
+	}
+}
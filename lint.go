@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/lint"
+	"example/sawan/goInterpreter/parser"
+)
+
+// Implements `monkey lint`: reports the diagnostics of each file and exits
+// with 1 when there are any.
+func lintCommand(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: monkey lint [flags] script.mk ...\n\n")
+		flags.PrintDefaults()
+	}
+	enabled := map[string]*bool{}
+	for _, check := range lint.Checks {
+		enabled[check.Name] = flags.Bool(check.Name, true, "report "+check.Description)
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	checks := map[string]bool{}
+	for name, on := range enabled {
+		checks[name] = *on
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		p := parser.New(lexer.New(string(source)))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			for _, e := range p.ParseErrors() {
+				fmt.Printf("%s:%d:%d: %s\n", path, e.Token.Line, e.Token.Column, e.Message)
+			}
+			status = 1
+			continue
+		}
+
+		for _, d := range lint.Lint(program, checks) {
+			fmt.Printf("%s:%s\n", path, d)
+			status = 1
+		}
+	}
+	return status
+}
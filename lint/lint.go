@@ -0,0 +1,394 @@
+// Package lint finds common mistakes in Monkey programs without running them.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/token"
+)
+
+// Names of the checks, used to turn them on and off.
+const (
+	Unused       = "unused"
+	Shadow       = "shadow"
+	Undefined    = "undefined"
+	Unreachable  = "unreachable"
+	Arity        = "arity"
+	DuplicateKey = "duplicate-key"
+)
+
+// Every check with a one line description, in the order they are listed to
+// users.
+var Checks = []struct {
+	Name        string
+	Description string
+}{
+	{Unused, "let bindings that are never used"},
+	{Shadow, "names that hide a binding of an enclosing function or a builtin"},
+	{Undefined, "references to names that are not defined"},
+	{Unreachable, "statements after a return"},
+	{Arity, "calls with the wrong number of arguments to known functions and builtins"},
+	{DuplicateKey, "keys repeated in a hash literal"},
+}
+
+// Line and Column locate the token the diagnostic is about, which is Length
+// bytes long.
+type Diagnostic struct {
+	Check   string
+	Line    int
+	Column  int
+	Length  int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", d.Line, d.Column, d.Message, d.Check)
+}
+
+// Runs the checks set to true in enabled, or all of them when enabled is
+// nil, and returns the diagnostics ordered by position.
+func Lint(program *ast.Program, enabled map[string]bool) []Diagnostic {
+	l := &linter{enabled: enabled}
+	l.analyze(newScope(nil), program)
+
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		a, b := l.diagnostics[i], l.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.diagnostics
+}
+
+// Maps every identifier that refers to a let binding or a parameter to the
+// identifier declaring it. References to builtins and undefined names are
+// left out.
+func Resolve(program *ast.Program) map[*ast.Identifier]*ast.Identifier {
+	l := &linter{enabled: map[string]bool{}, definitions: map[*ast.Identifier]*ast.Identifier{}}
+	l.analyze(newScope(nil), program)
+	return l.definitions
+}
+
+type linter struct {
+	enabled     map[string]bool
+	diagnostics []Diagnostic
+	definitions map[*ast.Identifier]*ast.Identifier
+}
+
+func (l *linter) report(check string, tok token.Token, format string, a ...interface{}) {
+	if l.enabled != nil && !l.enabled[check] {
+		return
+	}
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Check:   check,
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Length:  tok.Width(),
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+/*
+A scope holds the names of the program or of one function. if blocks do
+not get their own, as they share the environment of the function.
+
+bindings: The current binding of each name
+all: Every binding made, including ones replaced by a later let
+functions: Function literals whose bodies are analyzed once the scope is
+complete, since they can only run after the statements around them
+*/
+type scope struct {
+	parent    *scope
+	bindings  map[string]*binding
+	all       []*binding
+	functions []ast.Expression
+}
+
+type binding struct {
+	name   *ast.Identifier
+	isLet  bool
+	used   bool
+	isFunc bool // bound to a function literal taking params
+	params []*ast.Identifier
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent, bindings: map[string]*binding{}}
+}
+
+func (s *scope) lookup(name string) (*binding, bool) {
+	for ; s != nil; s = s.parent {
+		if b, ok := s.bindings[name]; ok {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// Analyzes the statements of body in s, then the functions defined in it,
+// and finally reports the bindings of s nothing used.
+func (l *linter) analyze(s *scope, body ast.Node) {
+	ast.Walk(body, &visitor{l: l, scope: s})
+
+	for len(s.functions) > 0 {
+		fn := s.functions[0]
+		s.functions = s.functions[1:]
+
+		params, body := functionParts(fn)
+		inner := newScope(s)
+		for _, param := range params {
+			l.declare(inner, param, &binding{name: param})
+		}
+		if body != nil {
+			l.analyze(inner, body)
+		}
+	}
+
+	for _, b := range s.all {
+		if b.isLet && !b.used && !strings.HasPrefix(b.name.Value, "_") {
+			l.report(Unused, b.name.Token, "%s declared and not used", b.name.Value)
+		}
+	}
+}
+
+func (l *linter) declare(s *scope, name *ast.Identifier, b *binding) {
+	if _, ok := s.bindings[name.Value]; !ok {
+		if outer, ok := s.parent.lookup(name.Value); ok {
+			l.report(Shadow, name.Token, "%s shadows the declaration at %d:%d",
+				name.Value, outer.name.Token.Line, outer.name.Token.Column)
+		} else if evaluator.IsPredeclared(name.Value) {
+			l.report(Shadow, name.Token, "%s shadows the builtin", name.Value)
+		}
+	}
+
+	s.bindings[name.Value] = b
+	s.all = append(s.all, b)
+}
+
+func functionParts(fn ast.Expression) ([]*ast.Identifier, *ast.BlockStatement) {
+	switch fn := fn.(type) {
+	case *ast.FunctionLiteral:
+		return fn.Parameters, fn.Body
+	case *ast.MacroLiteral:
+		return fn.Parameters, fn.Body
+	}
+	return nil, nil
+}
+
+type visitor struct {
+	l     *linter
+	scope *scope
+}
+
+func (v *visitor) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+
+	case *ast.Program:
+		v.l.checkUnreachable(node.Statements)
+
+	case *ast.BlockStatement:
+		v.l.checkUnreachable(node.Statements)
+
+	case *ast.LetStatement:
+		v.let(node)
+		return nil
+
+	case *ast.ExportStatement:
+		if node.Statement != nil {
+			v.let(node.Statement)
+			if b, ok := v.scope.bindings[node.Statement.Name.Value]; ok {
+				b.used = true
+			}
+		}
+		return nil
+
+	case *ast.Identifier:
+		v.resolve(node)
+		return nil
+
+	case *ast.TryExpression:
+		v.try(node)
+		return nil
+
+	case *ast.FunctionLiteral, *ast.MacroLiteral:
+		v.scope.functions = append(v.scope.functions, node.(ast.Expression))
+		return nil
+
+	case *ast.CallExpression:
+		if v.isQuote(node) {
+			v.quote(node)
+			return nil
+		}
+		v.checkArity(node)
+
+	case *ast.HashLiteral:
+		v.l.checkDuplicateKeys(node)
+	}
+
+	return v
+}
+
+// Function values are declared before their body is looked at, so they can
+// call themselves. Other values cannot see the name they are bound to.
+func (v *visitor) let(stmt *ast.LetStatement) {
+	if stmt.Name == nil {
+		return
+	}
+
+	b := &binding{name: stmt.Name, isLet: true}
+	if isFunction(stmt.Value) {
+		b.params, _ = functionParts(stmt.Value)
+		b.isFunc = true
+		v.l.declare(v.scope, stmt.Name, b)
+		ast.Walk(stmt.Value, v)
+		return
+	}
+
+	if stmt.Value != nil {
+		ast.Walk(stmt.Value, v)
+	}
+	v.l.declare(v.scope, stmt.Name, b)
+}
+
+// The catch parameter is bound in the scope around the try, like a let, but
+// need not be used.
+func (v *visitor) try(exp *ast.TryExpression) {
+	ast.Walk(exp.Block, v)
+	if exp.Catch != nil {
+		v.l.declare(v.scope, exp.Param, &binding{name: exp.Param})
+		ast.Walk(exp.Catch, v)
+	}
+	if exp.Finally != nil {
+		ast.Walk(exp.Finally, v)
+	}
+}
+
+func isFunction(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.FunctionLiteral, *ast.MacroLiteral:
+		return true
+	}
+	return false
+}
+
+func (v *visitor) resolve(ident *ast.Identifier) {
+	if b, ok := v.scope.lookup(ident.Value); ok {
+		b.used = true
+		if v.l.definitions != nil {
+			v.l.definitions[ident] = b.name
+		}
+		return
+	}
+
+	if !evaluator.IsPredeclared(ident.Value) {
+		v.l.report(Undefined, ident.Token, "undefined: %s", ident.Value)
+	}
+}
+
+func (v *visitor) isQuote(call *ast.CallExpression) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok || ident.Value != "quote" {
+		return false
+	}
+	_, shadowed := v.scope.lookup("quote")
+	return !shadowed
+}
+
+// Code inside quote is not evaluated, apart from the arguments of unquote.
+func (v *visitor) quote(call *ast.CallExpression) {
+	for _, arg := range call.Arguments {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			unquote, ok := node.(*ast.CallExpression)
+			if !ok {
+				return true
+			}
+			if ident, ok := unquote.Function.(*ast.Identifier); ok && ident.Value == "unquote" {
+				for _, arg := range unquote.Arguments {
+					if arg != nil {
+						ast.Walk(arg, v)
+					}
+				}
+				return false
+			}
+			return true
+		})
+	}
+}
+
+func (v *visitor) checkArity(call *ast.CallExpression) {
+	got := len(call.Arguments)
+
+	switch fn := call.Function.(type) {
+	case *ast.FunctionLiteral:
+		if got != len(fn.Parameters) {
+			v.l.report(Arity, call.Token, "wrong number of arguments to function literal: got %d, want %d",
+				got, len(fn.Parameters))
+		}
+
+	case *ast.Identifier:
+		if b, ok := v.scope.lookup(fn.Value); ok {
+			if b.isFunc && got != len(b.params) {
+				v.l.report(Arity, fn.Token, "wrong number of arguments to %s: got %d, want %d",
+					fn.Value, got, len(b.params))
+			}
+			return
+		}
+
+		doc, ok := evaluator.LookupBuiltinDoc(fn.Value)
+		if !ok || (got >= doc.MinArgs && (doc.MaxArgs < 0 || got <= doc.MaxArgs)) {
+			return
+		}
+
+		want := fmt.Sprintf("%d to %d", doc.MinArgs, doc.MaxArgs)
+		switch {
+		case doc.MaxArgs < 0:
+			want = fmt.Sprintf("at least %d", doc.MinArgs)
+		case doc.MinArgs == doc.MaxArgs:
+			want = fmt.Sprint(doc.MinArgs)
+		case doc.MinArgs+1 == doc.MaxArgs:
+			want = fmt.Sprintf("%d or %d", doc.MinArgs, doc.MaxArgs)
+		}
+		v.l.report(Arity, fn.Token, "wrong number of arguments to %s: got %d, want %s", fn.Value, got, want)
+	}
+}
+
+func (l *linter) checkUnreachable(statements []ast.Statement) {
+	for i, stmt := range statements {
+		switch stmt.(type) {
+		case *ast.ReturnStatement, *ast.ThrowStatement:
+			if i+1 < len(statements) {
+				l.report(Unreachable, ast.TokenOf(statements[i+1]), "unreachable code")
+				return
+			}
+		}
+	}
+}
+
+// Only literal keys can be compared without running the program.
+func (l *linter) checkDuplicateKeys(hash *ast.HashLiteral) {
+	seen := map[string]bool{}
+
+	for _, pair := range hash.Pairs {
+		var key string
+		switch k := pair.Key.(type) {
+		case *ast.StringLiteral:
+			key = fmt.Sprintf("%q", k.Value)
+		case *ast.IntegerLiteral:
+			key = k.String()
+		case *ast.Boolean:
+			key = k.String()
+		default:
+			continue
+		}
+
+		if seen[key] {
+			l.report(DuplicateKey, ast.TokenOf(pair.Key), "duplicate key %s in hash literal", key)
+		}
+		seen[key] = true
+	}
+}
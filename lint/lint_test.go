@@ -0,0 +1,136 @@
+package lint
+
+import (
+	"testing"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/parser"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let x = 1; puts(x);`, nil},
+		{`let x = 1;`, []string{"1:5: x declared and not used (unused)"}},
+		{`let _x = 1; export let y = 2;`, nil},
+		{`let x = 1; let x = 2; puts(x);`, []string{"1:5: x declared and not used (unused)"}},
+		{`let x = 1; let x = x + 1; puts(x);`, nil},
+		{`let x = 1; let f = fn(x) { x }; f(x);`, []string{"1:23: x shadows the declaration at 1:5 (shadow)"}},
+		{`let f = fn() { let len = 1; len }; f();`, []string{"1:20: len shadows the builtin (shadow)"}},
+		{`puts(y);`, []string{"1:6: undefined: y (undefined)"}},
+		{`puts(y); let y = 1;`, []string{
+			"1:6: undefined: y (undefined)",
+			"1:14: y declared and not used (unused)",
+		}},
+		{`let f = fn() { g() }; let g = fn() { f() }; f();`, nil},
+		{`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(3);`, nil},
+		{`let x = if (true) { let y = 1; y } else { 2 }; puts(x, PI, len);`, nil},
+		{"let f = fn() {\n  return 1;\n  puts(2);\n  3\n};\nf();", []string{"3:3: unreachable code (unreachable)"}},
+		{`let f = fn(a, b) { a + b }; f(1);`, []string{"1:29: wrong number of arguments to f: got 1, want 2 (arity)"}},
+		{`fn(a) { a }(1, 2);`, []string{"1:12: wrong number of arguments to function literal: got 2, want 1 (arity)"}},
+		{`len(); split(); merge(); puts();`, []string{
+			"1:1: wrong number of arguments to len: got 0, want 1 (arity)",
+			"1:8: wrong number of arguments to split: got 0, want 1 or 2 (arity)",
+			"1:17: wrong number of arguments to merge: got 0, want at least 1 (arity)",
+		}},
+		{`let len = fn(a, b) { a }; len(1, 2);`, []string{"1:5: len shadows the builtin (shadow)"}},
+		{`puts({"a": 1, "b": 2, "a": 3, 1: 1, true: 1, 1: 2});`, []string{
+			`1:23: duplicate key "a" in hash literal (duplicate-key)`,
+			"1:46: duplicate key 1 in hash literal (duplicate-key)",
+		}},
+		{`let m = macro(c) { quote(unquote(c) + free) }; m(1);`, nil},
+		{`let m = macro(c) { quote(unquote(missing)) }; m(1);`, []string{"1:34: undefined: missing (undefined)"}},
+		{`try { puts(1) } catch (err) { puts(2) } finally { puts(3) }`, nil},
+		{`try { puts(err) } catch (err) { puts(err) }`, []string{"1:12: undefined: err (undefined)"}},
+		{"let f = fn() {\n  throw error(\"no\");\n  1\n};\nf();", []string{"3:3: unreachable code (unreachable)"}},
+		{`let f = fn() { try { 1 } catch (len) { len } }; f();`, []string{"1:33: len shadows the builtin (shadow)"}},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		diagnostics := Lint(program, nil)
+
+		got := []string{}
+		for _, d := range diagnostics {
+			got = append(got, d.String())
+		}
+
+		if len(got) != len(tt.expected) {
+			t.Errorf("wrong diagnostics for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("wrong diagnostic for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected[i], got[i])
+			}
+		}
+	}
+}
+
+func TestLintToggles(t *testing.T) {
+	program := parse(t, `let x = 1; puts(y);`)
+
+	diagnostics := Lint(program, map[string]bool{Undefined: true})
+	if len(diagnostics) != 1 || diagnostics[0].Check != Undefined {
+		t.Fatalf("expected only the undefined check to run. got=%v", diagnostics)
+	}
+
+	diagnostics = Lint(program, map[string]bool{Undefined: false, Unused: true})
+	if len(diagnostics) != 1 || diagnostics[0].Check != Unused {
+		t.Fatalf("expected only the unused check to run. got=%v", diagnostics)
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestResolve(t *testing.T) {
+	program := parse(t, `let x = 1; let f = fn(x) { x + y }; f(x); let y = 2;`)
+
+	var outer, param, inner, call, global *ast.Identifier
+	ast.Inspect(program, func(node ast.Node) bool {
+		ident, ok := node.(*ast.Identifier)
+		if !ok {
+			return true
+		}
+		switch {
+		case ident.Value == "x" && ident.Token.Column == 5:
+			outer = ident
+		case ident.Value == "x" && ident.Token.Column == 23:
+			param = ident
+		case ident.Value == "x" && ident.Token.Column == 28:
+			inner = ident
+		case ident.Value == "f" && ident.Token.Column == 37:
+			call = ident
+		case ident.Value == "x" && ident.Token.Column == 39:
+			global = ident
+		}
+		return true
+	})
+
+	definitions := Resolve(program)
+
+	if definitions[inner] != param {
+		t.Errorf("x in the function body should resolve to the parameter")
+	}
+	if definitions[global] != outer {
+		t.Errorf("x in the call should resolve to the global")
+	}
+	if decl := definitions[call]; decl == nil || decl.Token.Column != 16 {
+		t.Errorf("f should resolve to its let. got=%v", decl)
+	}
+	if _, ok := definitions[param]; ok {
+		t.Errorf("declarations should not be in the map")
+	}
+}
@@ -0,0 +1,434 @@
+package lsp
+
+import (
+	"strings"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/format"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/lint"
+	"example/sawan/goInterpreter/parser"
+	"example/sawan/goInterpreter/token"
+)
+
+/*
+An open file, parsed once per version. The parser carries on after an
+error, so half typed files still give a program for everything that parsed.
+
+lines: The text split at newlines, used to convert positions
+definitions: The declaring identifier of every resolved reference
+*/
+type document struct {
+	text        string
+	lines       []string
+	program     *ast.Program
+	comments    []token.Token
+	errors      []parser.ParseError
+	definitions map[*ast.Identifier]*ast.Identifier
+}
+
+func newDocument(text string) *document {
+	l := lexer.New(text)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	return &document{
+		text:        text,
+		lines:       strings.Split(text, "\n"),
+		program:     program,
+		comments:    l.Comments(),
+		errors:      p.ParseErrors(),
+		definitions: lint.Resolve(program),
+	}
+}
+
+var keywords = []string{"fn", "let", "return", "if", "else", "true", "false", "import", "export", "macro", "try", "catch", "finally", "throw"}
+
+// Converts a 1-based line and byte column to a protocol position.
+func (d *document) position(line, column int) Position {
+	if line < 1 {
+		return Position{}
+	}
+	if line > len(d.lines) {
+		return d.endPosition()
+	}
+
+	text := d.lines[line-1]
+	offset := column - 1
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(text) {
+		offset = len(text)
+	}
+	return Position{Line: line - 1, Character: utf16Length(text[:offset])}
+}
+
+// Converts a protocol position to a 1-based line and byte column.
+func (d *document) location(pos Position) (int, int) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return pos.Line + 1, 1
+	}
+
+	text := d.lines[pos.Line]
+	units := 0
+	for i, r := range text {
+		if units >= pos.Character {
+			return pos.Line + 1, i + 1
+		}
+		units += utf16Units(r)
+	}
+	return pos.Line + 1, len(text) + 1
+}
+
+func (d *document) endPosition() Position {
+	last := len(d.lines) - 1
+	return Position{Line: last, Character: utf16Length(d.lines[last])}
+}
+
+func utf16Length(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16Units(r)
+	}
+	return n
+}
+
+// Characters outside the Basic Multilingual Plane take a surrogate pair.
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+func (d *document) tokenRange(tok token.Token) Range {
+	return Range{
+		Start: d.position(tok.Line, tok.Column),
+		End:   d.position(tok.Line, tok.Column+tok.Width()),
+	}
+}
+
+// The range from the first to the last token of node.
+func (d *document) nodeRange(start token.Token, node ast.Node) Range {
+	last := ast.LastToken(node)
+	return Range{
+		Start: d.position(start.Line, start.Column),
+		End:   d.position(last.Line, last.Column+last.Width()),
+	}
+}
+
+// Parser errors, or the lint warnings once the file parses.
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	for _, e := range d.errors {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.tokenRange(e.Token),
+			Severity: SeverityError,
+			Source:   "monkey",
+			Message:  e.Message,
+		})
+	}
+	if len(d.errors) != 0 {
+		return diagnostics
+	}
+
+	for _, l := range lint.Lint(d.program, nil) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range: Range{
+				Start: d.position(l.Line, l.Column),
+				End:   d.position(l.Line, l.Column+l.Length),
+			},
+			Severity: SeverityWarning,
+			Code:     l.Check,
+			Source:   "monkey-lint",
+			Message:  l.Message,
+		})
+	}
+	return diagnostics
+}
+
+// Returns the identifier under pos, including the position right after it
+// so a cursor at the end of a name still finds it.
+func (d *document) identifierAt(pos Position) *ast.Identifier {
+	line, column := d.location(pos)
+
+	var found *ast.Identifier
+	ast.Inspect(d.program, func(node ast.Node) bool {
+		if found != nil {
+			return false
+		}
+		ident, ok := node.(*ast.Identifier)
+		if ok && ident.Token.Line == line &&
+			column >= ident.Token.Column && column <= ident.Token.Column+len(ident.Value) {
+			found = ident
+		}
+		return true
+	})
+	return found
+}
+
+// Returns the let name or parameter that the identifier under pos refers
+// to, which is the identifier itself for declarations.
+func (d *document) definition(pos Position) *ast.Identifier {
+	ident := d.identifierAt(pos)
+	if ident == nil {
+		return nil
+	}
+	if decl, ok := d.definitions[ident]; ok {
+		return decl
+	}
+	if _, ok := d.declarations()[ident]; ok {
+		return ident
+	}
+	return nil
+}
+
+// Maps the name of every let and parameter to the value it is bound to,
+// which is nil for parameters, catch parameters included.
+func (d *document) declarations() map[*ast.Identifier]ast.Expression {
+	decls := map[*ast.Identifier]ast.Expression{}
+	ast.Inspect(d.program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			if node.Name != nil {
+				decls[node.Name] = node.Value
+			}
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				decls[param] = nil
+			}
+		case *ast.MacroLiteral:
+			for _, param := range node.Parameters {
+				decls[param] = nil
+			}
+		case *ast.TryExpression:
+			if node.Param != nil {
+				decls[node.Param] = nil
+			}
+		}
+		return true
+	})
+	return decls
+}
+
+func (d *document) hover(pos Position) *Hover {
+	ident := d.identifierAt(pos)
+	if ident == nil {
+		return nil
+	}
+
+	var text string
+	decl := d.definition(pos)
+	if decl != nil {
+		value, isLet := d.declarations()[decl]
+		text = "```monkey\n" + describe(decl, value, isLet && value != nil) + "\n```"
+	} else if doc, ok := evaluator.LookupBuiltinDoc(ident.Value); ok {
+		text = "```monkey\n" + doc.Signature + "\n```\n" + doc.Doc
+	} else {
+		return nil
+	}
+
+	r := d.tokenRange(ident.Token)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: text}, Range: &r}
+}
+
+// Shows functions by their signature and other bindings by their kind.
+func describe(name *ast.Identifier, value ast.Expression, isLet bool) string {
+	switch value := value.(type) {
+	case *ast.FunctionLiteral:
+		signature := "fn " + name.Value + "(" + parameterList(value.Parameters) + ")"
+		if value.ReturnType != nil {
+			signature += ": " + value.ReturnType.String()
+		}
+		return signature
+	case *ast.MacroLiteral:
+		return "macro " + name.Value + "(" + parameterList(value.Parameters) + ")"
+	}
+	if isLet {
+		return "let " + name.String()
+	}
+	return "parameter " + name.String()
+}
+
+func parameterList(params []*ast.Identifier) string {
+	names := []string{}
+	for _, param := range params {
+		names = append(names, param.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// Offers the names visible at pos, then the builtins and keywords.
+func (d *document) completion(pos Position) []CompletionItem {
+	line, column := d.location(pos)
+	items := []CompletionItem{}
+	seen := map[string]bool{}
+
+	add := func(item CompletionItem) {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+
+	for _, decl := range d.visibleDeclarations(line, column) {
+		kind, detail := completionVariable, describe(decl.name, decl.value, !decl.param)
+		if _, ok := decl.value.(*ast.FunctionLiteral); ok {
+			kind = completionFunction
+		}
+		add(CompletionItem{Label: decl.name.Value, Kind: kind, Detail: detail})
+	}
+
+	for _, name := range evaluator.BuiltinNames() {
+		item := CompletionItem{Label: name, Kind: completionConstant}
+		if doc, ok := evaluator.LookupBuiltinDoc(name); ok {
+			item.Kind = completionFunction
+			item.Detail = doc.Signature
+			item.Documentation = &MarkupContent{Kind: "markdown", Value: doc.Doc}
+		}
+		add(item)
+	}
+
+	for _, keyword := range keywords {
+		add(CompletionItem{Label: keyword, Kind: completionKeyword})
+	}
+	return items
+}
+
+type declaration struct {
+	name  *ast.Identifier
+	value ast.Expression
+	param bool
+}
+
+// Collects the lets of the program and of every function around the
+// position, together with the parameters of those functions. Inner
+// declarations come first, so they win over outer ones with the same name.
+func (d *document) visibleDeclarations(line, column int) []declaration {
+	scopes := [][]declaration{collectLets(d.program.Statements)}
+
+	ast.Inspect(d.program, func(node ast.Node) bool {
+		params, body := functionParts(node)
+		if body == nil {
+			return true
+		}
+		if !contains(ast.TokenOf(node), body.Rbrace, line, column) {
+			return false
+		}
+
+		decls := []declaration{}
+		for _, param := range params {
+			decls = append(decls, declaration{name: param, param: true})
+		}
+		scopes = append(scopes, append(decls, collectLets(body.Statements)...))
+		return true
+	})
+
+	visible := []declaration{}
+	for i := len(scopes) - 1; i >= 0; i-- {
+		visible = append(visible, scopes[i]...)
+	}
+	return visible
+}
+
+func functionParts(node ast.Node) ([]*ast.Identifier, *ast.BlockStatement) {
+	switch node := node.(type) {
+	case *ast.FunctionLiteral:
+		return node.Parameters, node.Body
+	case *ast.MacroLiteral:
+		return node.Parameters, node.Body
+	}
+	return nil, nil
+}
+
+// Returns the lets among statements, including those in if and try blocks,
+// which share the environment of the function, and the catch parameters,
+// but not those of nested functions.
+func collectLets(statements []ast.Statement) []declaration {
+	decls := []declaration{}
+	for _, stmt := range statements {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.LetStatement:
+				if node.Name != nil {
+					decls = append(decls, declaration{name: node.Name, value: node.Value})
+				}
+			case *ast.TryExpression:
+				if node.Param != nil {
+					decls = append(decls, declaration{name: node.Param})
+				}
+			case *ast.FunctionLiteral, *ast.MacroLiteral:
+				return false
+			}
+			return true
+		})
+	}
+	return decls
+}
+
+// Whether line and column are between the start and end tokens. An end
+// without a position, as when the closing brace is still missing, reaches
+// to the end of the file.
+func contains(start, end token.Token, line, column int) bool {
+	if line < start.Line || line == start.Line && column < start.Column {
+		return false
+	}
+	if end.Type != token.RBRACES {
+		return true
+	}
+	return line < end.Line || line == end.Line && column <= end.Column
+}
+
+// Top level lets, with the lets of the functions they define as children.
+func (d *document) symbols() []DocumentSymbol {
+	return d.letSymbols(d.program.Statements)
+}
+
+func (d *document) letSymbols(statements []ast.Statement) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+
+	for _, stmt := range statements {
+		start := ast.TokenOf(stmt)
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || let.Name == nil {
+			continue
+		}
+
+		symbol := DocumentSymbol{
+			Name:           let.Name.Value,
+			Kind:           symbolVariable,
+			Range:          d.nodeRange(start, let),
+			SelectionRange: d.tokenRange(let.Name.Token),
+		}
+		if _, body := functionParts(let.Value); body != nil {
+			symbol.Kind = symbolFunction
+			symbol.Children = d.letSymbols(body.Statements)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// Replaces the whole document with its formatted text. Files that do not
+// parse are left alone.
+func (d *document) formatting() []TextEdit {
+	if len(d.errors) != 0 {
+		return []TextEdit{}
+	}
+
+	formatted := format.Program(d.program, d.comments)
+	if formatted == d.text {
+		return []TextEdit{}
+	}
+
+	return []TextEdit{{
+		Range:   Range{Start: Position{}, End: d.endPosition()},
+		NewText: formatted,
+	}}
+}
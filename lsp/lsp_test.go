@@ -0,0 +1,306 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testURI = "file:///test.mk"
+
+const testSource = `let add = fn(a, b) { a + b };
+let result = add(1, 2);
+puts(len("héllo"), result);
+`
+
+// Runs the server over the given requests and returns everything it wrote,
+// keyed by request id, with notifications under their method name.
+func runServer(t *testing.T, requests ...map[string]interface{}) (map[string]json.RawMessage, int) {
+	t.Helper()
+
+	var in bytes.Buffer
+	for _, req := range requests {
+		req["jsonrpc"] = "2.0"
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	var out bytes.Buffer
+	code := Serve(&in, &out)
+
+	responses := map[string]json.RawMessage{}
+	r := bufio.NewReader(&out)
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		if msg.ID != nil {
+			if msg.Error != nil {
+				responses[string(*msg.ID)] = json.RawMessage(`{"error":"` + msg.Error.Message + `"}`)
+			} else {
+				responses[string(*msg.ID)] = msg.Result
+			}
+		} else {
+			responses[msg.Method] = msg.Params
+		}
+	}
+	return responses, code
+}
+
+func open(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"method": "textDocument/didOpen",
+		"params": map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": testURI, "languageId": "monkey", "version": 1, "text": text},
+		},
+	}
+}
+
+func request(id int, method string, params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	params["textDocument"] = map[string]interface{}{"uri": testURI}
+	return map[string]interface{}{"id": id, "method": method, "params": params}
+}
+
+func at(line, character int) map[string]interface{} {
+	return map[string]interface{}{"position": map[string]interface{}{"line": line, "character": character}}
+}
+
+func TestLifecycle(t *testing.T) {
+	responses, code := runServer(t,
+		map[string]interface{}{"id": 1, "method": "initialize", "params": map[string]interface{}{}},
+		map[string]interface{}{"method": "initialized", "params": map[string]interface{}{}},
+		map[string]interface{}{"id": 2, "method": "unknown/method"},
+		map[string]interface{}{"id": 3, "method": "shutdown"},
+		map[string]interface{}{"method": "exit"},
+	)
+
+	if code != 0 {
+		t.Errorf("exit code should be 0 after shutdown. got=%d", code)
+	}
+
+	var init struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	json.Unmarshal(responses["1"], &init)
+	for _, capability := range []string{"definitionProvider", "hoverProvider", "completionProvider",
+		"documentSymbolProvider", "documentFormattingProvider"} {
+		if init.Capabilities[capability] == nil {
+			t.Errorf("capability %s missing", capability)
+		}
+	}
+
+	if !strings.Contains(string(responses["2"]), "method not found") {
+		t.Errorf("unknown method should fail. got=%s", responses["2"])
+	}
+	if string(responses["3"]) != "null" {
+		t.Errorf("shutdown should return null. got=%s", responses["3"])
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Diagnostic
+	}{
+		{testSource, []Diagnostic{}},
+		{"let x = ;", []Diagnostic{{
+			Range:    Range{Start: Position{0, 8}, End: Position{0, 9}},
+			Severity: SeverityError,
+			Source:   "monkey",
+			Message:  "no prefix parse function for ; found",
+		}}},
+		{"let 🐒 = 1;\nlet é = 2; puts(y);", []Diagnostic{
+			{
+				Range:    Range{Start: Position{0, 4}, End: Position{0, 6}},
+				Severity: SeverityError,
+				Source:   "monkey",
+				Message:  "expected next token to be IDENT, got ILLEGAL instead",
+			},
+		}},
+		{`let s = "é"; puts(y, s);`, []Diagnostic{{
+			Range:    Range{Start: Position{0, 18}, End: Position{0, 19}},
+			Severity: SeverityWarning,
+			Code:     "undefined",
+			Source:   "monkey-lint",
+			Message:  "undefined: y",
+		}}},
+	}
+
+	for _, tt := range tests {
+		responses, _ := runServer(t, open(tt.input))
+
+		var params publishDiagnosticsParams
+		if err := json.Unmarshal(responses["textDocument/publishDiagnostics"], &params); err != nil {
+			t.Fatalf("no diagnostics published for %q: %s", tt.input, err)
+		}
+
+		if len(params.Diagnostics) < len(tt.expected) || len(tt.expected) == 0 && len(params.Diagnostics) != 0 {
+			t.Errorf("wrong diagnostics for %q. got=%+v", tt.input, params.Diagnostics)
+			continue
+		}
+		for i, expected := range tt.expected {
+			if params.Diagnostics[i] != expected {
+				t.Errorf("wrong diagnostic for %q.\nexpected=%+v\ngot=%+v", tt.input, expected, params.Diagnostics[i])
+			}
+		}
+	}
+}
+
+func TestDefinitionAndHover(t *testing.T) {
+	responses, _ := runServer(t,
+		open(testSource),
+		request(1, "textDocument/definition", at(1, 14)),
+		request(2, "textDocument/definition", at(0, 22)),
+		request(3, "textDocument/definition", at(2, 0)),
+		request(4, "textDocument/hover", at(1, 15)),
+		request(5, "textDocument/hover", at(2, 6)),
+		request(6, "textDocument/hover", at(0, 22)),
+		request(7, "textDocument/hover", at(2, 24)),
+	)
+
+	tests := []struct {
+		id       string
+		expected string
+	}{
+		{"1", `{"uri":"file:///test.mk","range":{"start":{"line":0,"character":4},"end":{"line":0,"character":7}}}`},
+		{"2", `{"uri":"file:///test.mk","range":{"start":{"line":0,"character":13},"end":{"line":0,"character":14}}}`},
+		{"3", `null`},
+	}
+	for _, tt := range tests {
+		if string(responses[tt.id]) != tt.expected {
+			t.Errorf("definition %s wrong.\nexpected=%s\ngot=%s", tt.id, tt.expected, responses[tt.id])
+		}
+	}
+
+	hovers := []struct {
+		id       string
+		expected string
+	}{
+		{"4", "```monkey\nfn add(a, b)\n```"},
+		{"5", "```monkey\nlen(value)\n```\nReturns the number of characters of a string or of elements of an array."},
+		{"6", "```monkey\nparameter a\n```"},
+		{"7", "```monkey\nlet result\n```"},
+	}
+	for _, tt := range hovers {
+		var hover Hover
+		json.Unmarshal(responses[tt.id], &hover)
+		if hover.Contents.Value != tt.expected {
+			t.Errorf("hover %s wrong.\nexpected=%q\ngot=%q", tt.id, tt.expected, hover.Contents.Value)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	source := "let outer = 1;\nlet f = fn(param) {\n  let local = param;\n  \n};\nlet g = fn(other) { other };\n"
+
+	responses, _ := runServer(t, open(source), request(1, "textDocument/completion", at(3, 2)))
+
+	var items []CompletionItem
+	if err := json.Unmarshal(responses["1"], &items); err != nil {
+		t.Fatalf("bad completion response %s", responses["1"])
+	}
+
+	labels := map[string]CompletionItem{}
+	for _, item := range items {
+		labels[item.Label] = item
+	}
+
+	for _, label := range []string{"local", "param", "outer", "f", "g", "map", "PI", "let", "fn"} {
+		if _, ok := labels[label]; !ok {
+			t.Errorf("completion %q missing", label)
+		}
+	}
+	if _, ok := labels["other"]; ok {
+		t.Errorf("parameter of another function should not be offered")
+	}
+	if labels["map"].Detail != "map(array, fn)" || labels["f"].Kind != completionFunction {
+		t.Errorf("wrong completion details. map=%+v f=%+v", labels["map"], labels["f"])
+	}
+	if items[0].Label != "param" {
+		t.Errorf("innermost names should come first. got=%q", items[0].Label)
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	source := "let f = fn() {\n  let inner = 1;\n  inner\n};\nexport let x = \"a\\\"b\";\n"
+
+	responses, _ := runServer(t, open(source), request(1, "textDocument/documentSymbol", nil))
+
+	var symbols []DocumentSymbol
+	json.Unmarshal(responses["1"], &symbols)
+
+	if len(symbols) != 2 {
+		t.Fatalf("wrong number of symbols. got=%s", responses["1"])
+	}
+
+	f := symbols[0]
+	if f.Name != "f" || f.Kind != symbolFunction || f.Range.End != (Position{3, 1}) {
+		t.Errorf("wrong symbol for f. got=%+v", f)
+	}
+	if len(f.Children) != 1 || f.Children[0].Name != "inner" {
+		t.Errorf("wrong children of f. got=%+v", f.Children)
+	}
+
+	x := symbols[1]
+	// the range ends after the string as written, escapes and all
+	if x.Name != "x" || x.Kind != symbolVariable || x.Range.Start != (Position{4, 0}) || x.Range.End != (Position{4, 21}) {
+		t.Errorf("wrong symbol for x. got=%+v", x)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	source := "let x=1 // one\nputs( x )"
+
+	responses, _ := runServer(t, open(source), request(1, "textDocument/formatting", nil))
+
+	var edits []TextEdit
+	json.Unmarshal(responses["1"], &edits)
+
+	if len(edits) != 1 {
+		t.Fatalf("expected one edit. got=%s", responses["1"])
+	}
+	if edits[0].NewText != "let x = 1; // one\nputs(x);\n" {
+		t.Errorf("wrong formatted text. got=%q", edits[0].NewText)
+	}
+	if edits[0].Range.End != (Position{1, 9}) {
+		t.Errorf("edit should cover the whole document. got=%+v", edits[0].Range)
+	}
+
+	responses, _ = runServer(t, open("let x = ;"), request(1, "textDocument/formatting", nil))
+	if string(responses["1"]) != "[]" {
+		t.Errorf("files with errors should not be formatted. got=%s", responses["1"])
+	}
+}
+
+// Every prefix of a program is what an editor sends while it is typed.
+func TestHalfTypedFiles(t *testing.T) {
+	source := `let f = fn(a, b) { if (a > b) { return [a, b][0:1]; } else { {"k": b}["k"] } };
+let m = macro(x) { quote(unquote(x) + 1) };
+export let r = f(1, 2) + import "lib.mk";
+`
+
+	for i := 0; i <= len(source); i++ {
+		doc := newDocument(source[:i])
+		doc.diagnostics()
+		doc.symbols()
+		doc.formatting()
+		for line := range doc.lines {
+			for character := 0; character <= len(doc.lines[line]); character++ {
+				pos := Position{line, character}
+				doc.definition(pos)
+				doc.hover(pos)
+			}
+			doc.completion(Position{line, 0})
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Package lsp implements a Language Server Protocol server for Monkey.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+/*
+out: Where responses and notifications are written
+documents: The text of every open document by URI
+shutdown: Set once the client asked the server to shut down
+*/
+type Server struct {
+	out       io.Writer
+	documents map[string]*document
+	shutdown  bool
+}
+
+// Serves requests read from in until the client sends exit or in is closed.
+// Returns the process exit code the protocol asks for.
+func Serve(in io.Reader, out io.Writer) int {
+	s := &Server{out: out, documents: map[string]*document{}}
+	r := bufio.NewReader(in)
+
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			return 1
+		}
+
+		if msg.Method == "exit" {
+			if s.shutdown {
+				return 0
+			}
+			return 1
+		}
+
+		s.handle(msg)
+	}
+}
+
+func (s *Server) handle(msg *message) {
+	handler, ok := requests[msg.Method]
+	if !ok {
+		if notification, ok := notifications[msg.Method]; ok {
+			notification(s, msg.Params)
+		} else if msg.ID != nil {
+			s.reply(msg.ID, nil, &responseError{Code: methodNotFound, Message: "method not found: " + msg.Method})
+		}
+		return
+	}
+
+	// a request sent as a notification gets no answer
+	if msg.ID == nil {
+		return
+	}
+
+	result, err := handler(s, msg.Params)
+	s.reply(msg.ID, result, err)
+}
+
+type requestHandler func(s *Server, params json.RawMessage) (interface{}, *responseError)
+
+var requests = map[string]requestHandler{
+	"initialize":                  (*Server).initialize,
+	"shutdown":                    (*Server).shutdownRequest,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/completion":     (*Server).completion,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+	"textDocument/formatting":     (*Server).formatting,
+}
+
+var notifications = map[string]func(s *Server, params json.RawMessage){
+	"initialized":            func(*Server, json.RawMessage) {},
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didClose":  (*Server).didClose,
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, err *responseError) {
+	msg := &message{ID: id, Error: err}
+	if err == nil {
+		body, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			msg.Error = &responseError{Code: invalidParams, Message: marshalErr.Error()}
+		} else {
+			msg.Result = body
+		}
+	}
+	writeMessage(s.out, msg)
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	writeMessage(s.out, &message{Method: method, Params: body})
+}
+
+func (s *Server) initialize(json.RawMessage) (interface{}, *responseError) {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			// full text on every change
+			"textDocumentSync":           1,
+			"definitionProvider":         true,
+			"hoverProvider":              true,
+			"completionProvider":         map[string]interface{}{},
+			"documentSymbolProvider":     true,
+			"documentFormattingProvider": true,
+		},
+		"serverInfo": map[string]string{"name": "monkey"},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(json.RawMessage) (interface{}, *responseError) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) {
+	var p didOpenParams
+	if json.Unmarshal(params, &p) != nil {
+		return
+	}
+	s.documents[p.TextDocument.URI] = newDocument(p.TextDocument.Text)
+	s.publishDiagnostics(p.TextDocument.URI)
+}
+
+func (s *Server) didChange(params json.RawMessage) {
+	var p didChangeParams
+	if json.Unmarshal(params, &p) != nil || len(p.ContentChanges) == 0 {
+		return
+	}
+	text := p.ContentChanges[len(p.ContentChanges)-1].Text
+	s.documents[p.TextDocument.URI] = newDocument(text)
+	s.publishDiagnostics(p.TextDocument.URI)
+}
+
+func (s *Server) didClose(params json.RawMessage) {
+	var p documentParams
+	if json.Unmarshal(params, &p) != nil {
+		return
+	}
+	delete(s.documents, p.TextDocument.URI)
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+}
+
+func (s *Server) publishDiagnostics(uri string) {
+	doc := s.documents[uri]
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: doc.diagnostics()})
+}
+
+// Decodes the parameters of a request about a position in an open document.
+func (s *Server) positionParams(params json.RawMessage) (*document, TextDocumentPositionParams, *responseError) {
+	var p TextDocumentPositionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, p, &responseError{Code: invalidParams, Message: err.Error()}
+	}
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, p, &responseError{Code: invalidParams, Message: "unknown document " + p.TextDocument.URI}
+	}
+	return doc, p, nil
+}
+
+func (s *Server) documentParams(params json.RawMessage) (*document, string, *responseError) {
+	var p documentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, "", &responseError{Code: invalidParams, Message: err.Error()}
+	}
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, "", &responseError{Code: invalidParams, Message: "unknown document " + p.TextDocument.URI}
+	}
+	return doc, p.TextDocument.URI, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, *responseError) {
+	doc, p, err := s.positionParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	decl := doc.definition(p.Position)
+	if decl == nil {
+		return nil, nil
+	}
+	return Location{URI: p.TextDocument.URI, Range: doc.tokenRange(decl.Token)}, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, *responseError) {
+	doc, p, err := s.positionParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	hover := doc.hover(p.Position)
+	if hover == nil {
+		return nil, nil
+	}
+	return hover, nil
+}
+
+func (s *Server) completion(params json.RawMessage) (interface{}, *responseError) {
+	doc, p, err := s.positionParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return doc.completion(p.Position), nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (interface{}, *responseError) {
+	doc, _, err := s.documentParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return doc.symbols(), nil
+}
+
+func (s *Server) formatting(params json.RawMessage) (interface{}, *responseError) {
+	doc, _, err := s.documentParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return doc.formatting(), nil
+}
@@ -1,21 +1,104 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-  "os"
+	"os"
 	"os/user"
-  "example/sawan/goInterpreter/repl"
+	"path/filepath"
+	"strings"
+
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/lsp"
+	"example/sawan/goInterpreter/repl"
 )
 
+// Subcommands, selected by the first argument.
+var commands = map[string]func(args []string) int{
+	"check": checkCommand,
+	"debug": debugCommand,
+	"fmt":   formatCommand,
+	"lint":  lintCommand,
+	"test":  testCommand,
+	"lsp": func([]string) int {
+		return lsp.Serve(os.Stdin, os.Stdout)
+	},
+}
+
 func main() {
-  user, err := user.Current() 
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
 
-  if err != nil {
-    panic(err)
-  }
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: monkey [flags] [script.mk]\n")
+		fmt.Fprintf(os.Stderr, "       monkey check script.mk ...\n")
+		fmt.Fprintf(os.Stderr, "       monkey debug [flags] script.mk\n")
+		fmt.Fprintf(os.Stderr, "       monkey fmt [flags] [script.mk ...]\n")
+		fmt.Fprintf(os.Stderr, "       monkey lint [flags] script.mk ...\n")
+		fmt.Fprintf(os.Stderr, "       monkey test [flags] [file_test.mk | directory ...]\n")
+		fmt.Fprintf(os.Stderr, "       monkey lsp\n\n")
+		fmt.Fprintf(os.Stderr, "Runs the script, or starts the REPL when none is given.\n\n")
+		flag.PrintDefaults()
+	}
+	searchPath := flag.String("path", "", "directories searched by import, separated by "+string(os.PathListSeparator)+
+		"\n(MONKEYPATH is searched after them)")
+	optimize := flag.Bool("optimize", false, "fold constants and drop dead branches before running")
+	dumpAST := flag.Bool("dump-ast", false, "print the program as it would run, after -optimize, instead of running it")
+	trace := flag.Bool("trace", false, "log every node evaluated, with its position and value, to stderr")
+	traceFunctions := flag.String("trace-fn", "", "with -trace, only log calls of the functions with these `names`, separated by commas")
+	traceParser := flag.Bool("trace-parser", false, "log the parse functions called for each expression, with their precedence, to stderr")
+	cpuProfile := flag.String("cpuprofile", "", "write a pprof profile of the Monkey functions run to `file`")
+	profile := flag.Bool("profile", false, "print the time spent in each Monkey function and line to stderr")
+	strictIndex := flag.Bool("strict-index", false, "make indexes and slice bounds outside of arrays and strings errors instead of null")
+	flag.Parse()
 
-  fmt.Printf("Hello %s! This is the Monkey Programming Language \n", user.Username)
-  fmt.Printf("Feel free to type anything")
+	if *strictIndex {
+		evaluator.SetOutOfRange(evaluator.OutOfRangeError)
+	}
+
+	if flag.NArg() == 0 {
+		evaluator.SetModuleLoader(&evaluator.FileLoader{SearchPath: importPath(".", *searchPath)})
+		startRepl()
+		return
+	}
+
+	script := flag.Arg(0)
+	evaluator.SetModuleLoader(&evaluator.FileLoader{SearchPath: importPath(filepath.Dir(script), *searchPath)})
+	opts := runOptions{
+		optimize:    *optimize,
+		dumpAST:     *dumpAST,
+		trace:       *trace,
+		traceParser: *traceParser,
+		cpuProfile:  *cpuProfile,
+		profile:     *profile,
+	}
+	if *traceFunctions != "" {
+		opts.traceFunctions = strings.Split(*traceFunctions, ",")
+	}
+	os.Exit(run(script, opts))
+}
+
+func startRepl() {
+	user, err := user.Current()
+
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Hello %s! This is the Monkey Programming Language \n", user.Username)
+	fmt.Printf("Feel free to type anything")
+
+	repl.Start(os.Stdin, os.Stdout)
+}
 
-  repl.Start(os.Stdin, os.Stdout)
+// Imports are looked up next to the script first, then in the directories
+// given with -path and finally in the ones listed in MONKEYPATH.
+func importPath(scriptDir, searchPath string) []string {
+	dirs := []string{scriptDir}
+	dirs = append(dirs, filepath.SplitList(searchPath)...)
+	dirs = append(dirs, filepath.SplitList(os.Getenv("MONKEYPATH"))...)
+	return dirs
 }
@@ -1,5 +1,7 @@
 package object
 
+import "sort"
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
@@ -12,9 +14,30 @@ func NewEnvironment() *Environment {
 	return &Environment{store: s, outer: nil}
 }
 
+// Creates the environment for one call of a resolved function. Its bindings
+// live in locals slots and in cells, so only globals are looked up by name,
+// in the environment the function was defined in.
+func NewFrameEnvironment(outer *Environment, locals int, cells []*Cell) *Environment {
+	// frames never hold named bindings, so skip straight to the globals
+	for outer != nil && outer.frame {
+		outer = outer.outer
+	}
+	return &Environment{outer: outer, frame: true, locals: make([]Object, locals), cells: cells}
+}
+
 type Environment struct {
 	store map[string]Object
 	outer *Environment
+
+	frame  bool
+	locals []Object
+	cells  []*Cell
+}
+
+// A binding shared between a function and the closures that use it, so
+// they all see the latest value.
+type Cell struct {
+	Value Object
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -26,6 +49,34 @@ func (e *Environment) Get(name string) (Object, bool) {
 }
 
 func (e *Environment) Set(name string, val Object) Object {
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[name] = val
 	return val
 }
+
+// Returns the value in slot index, which is nil before it is bound.
+func (e *Environment) Local(index int) Object {
+	return e.locals[index]
+}
+
+func (e *Environment) SetLocal(index int, val Object) Object {
+	e.locals[index] = val
+	return val
+}
+
+func (e *Environment) Cell(index int) *Cell {
+	return e.cells[index]
+}
+
+// Returns the names bound in e itself, not in its outer environments, in
+// sorted order.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
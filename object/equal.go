@@ -0,0 +1,112 @@
+package object
+
+// Reports whether two objects are structurally equal. Arrays are compared
+// element by element, hashes by their key/value pairs and everything that has
+// no value semantics (functions, builtins, errors) by identity.
+func Equal(a, b Object) bool {
+	return equal(a, b, map[[2]Object]bool{})
+}
+
+// seen holds the container pairs that are already being compared further up.
+// Reaching one of them again means we went around a cycle, and since nothing
+// on the way has differed so far the pair is treated as equal.
+func equal(a, b Object, seen map[[2]Object]bool) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+
+	// integers and floats compare by numeric value, as they do with ==
+	if af, bf, ok := numericPair(a, b); ok {
+		return af == bf
+	}
+
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		b := b.(*Integer)
+		if a.IsBig() || b.IsBig() {
+			return a.BigValue().Cmp(b.BigValue()) == 0
+		}
+		return a.Value == b.Value
+
+	case *Float:
+		return a.Value == b.(*Float).Value
+
+	case *String:
+		return a.Value == b.(*String).Value
+
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+
+	case *Null:
+		return true
+
+	case *Array:
+		b := b.(*Array)
+		if len(a.Elements) != len(b.Elements) {
+			return false
+		}
+
+		pair := [2]Object{a, b}
+		if seen[pair] {
+			return true
+		}
+		seen[pair] = true
+		defer delete(seen, pair)
+
+		for i := range a.Elements {
+			if !equal(a.Elements[i], b.Elements[i], seen) {
+				return false
+			}
+		}
+		return true
+
+	case *Hash:
+		b := b.(*Hash)
+		if a.Len() != b.Len() {
+			return false
+		}
+
+		pair := [2]Object{a, b}
+		if seen[pair] {
+			return true
+		}
+		seen[pair] = true
+		defer delete(seen, pair)
+
+		for _, aPair := range a.Pairs() {
+			bPair, ok := b.Get(aPair.Key.(Hashable))
+			if !ok || !equal(aPair.Key, bPair.Key, seen) || !equal(aPair.Value, bPair.Value, seen) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return false
+	}
+}
+
+// Converts a mixed integer/float pair to floats. ok is false unless exactly
+// one of them is a float and the other an integer.
+func numericPair(a, b Object) (float64, float64, bool) {
+	af, aFloat := a.(*Float)
+	bf, bFloat := b.(*Float)
+	ai, aInt := a.(*Integer)
+	bi, bInt := b.(*Integer)
+
+	switch {
+	case aFloat && bInt:
+		return af.Value, bi.Float(), true
+	case aInt && bFloat:
+		return ai.Float(), bf.Value, true
+	default:
+		return 0, 0, false
+	}
+}
@@ -2,8 +2,11 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"math/big"
+	"strconv"
 	"strings"
 
 	"example/sawan/goInterpreter/ast"
@@ -13,6 +16,7 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
@@ -22,6 +26,8 @@ const (
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	QUOTE_OBJ        = "QUOTE"
+	MACRO_OBJ        = "MACRO"
 )
 
 type Object interface {
@@ -29,13 +35,66 @@ type Object interface {
 	Inspect() string
 }
 
+// Integers hold their value in an int64 and only switch over to Big when the
+// value no longer fits. Big is nil for every value that fits in an int64, so
+// each number has exactly one representation.
 type Integer struct {
 	Value int64
+	Big   *big.Int
 }
 
-func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
+func (i *Integer) Inspect() string {
+	if i.Big != nil {
+		return i.Big.String()
+	}
+	return fmt.Sprintf("%d", i.Value)
+}
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 
+// Creates an Integer from a big.Int, demoting it to the int64 representation
+// when it fits.
+func NewBigInteger(value *big.Int) *Integer {
+	if value.IsInt64() {
+		return &Integer{Value: value.Int64()}
+	}
+	return &Integer{Big: value}
+}
+
+func (i *Integer) IsBig() bool { return i.Big != nil }
+
+// Returns the nearest float64 to the integer.
+func (i *Integer) Float() float64 {
+	if i.Big != nil {
+		f, _ := new(big.Float).SetInt(i.Big).Float64()
+		return f
+	}
+	return float64(i.Value)
+}
+
+// Returns the value as a big.Int. The result is always a fresh copy so callers
+// are free to use it as the receiver of big.Int operations.
+func (i *Integer) BigValue() *big.Int {
+	if i.Big != nil {
+		return new(big.Int).Set(i.Big)
+	}
+	return big.NewInt(i.Value)
+}
+
+type Float struct {
+	Value float64
+}
+
+// Floats always print with a decimal point or exponent so they cannot be
+// mistaken for integers.
+func (f *Float) Inspect() string {
+	str := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(str, ".eIN") {
+		str += ".0"
+	}
+	return str
+}
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
 type Boolean struct {
 	Value bool
 }
@@ -57,6 +116,18 @@ func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Error struct {
 	Message string
+
+	// What went wrong: runtime for the errors of the interpreter itself,
+	// assertion for failed assertions, or the kind a program threw.
+	Kind string
+
+	// Where the error was thrown or the failed assertion made. Zero for the
+	// errors of the interpreter.
+	Line   int
+	Column int
+
+	// The calls the error returned from so far, innermost first.
+	Stack []string
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
@@ -66,6 +137,11 @@ type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+
+	// Set for functions the resolver has seen: the frame a call needs and
+	// the cells of the variables the function captured.
+	Frame *ast.FrameLayout
+	Free  []*Cell
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -141,6 +217,15 @@ func (b *Boolean) HashKey() HashKey {
 }
 
 func (i *Integer) HashKey() HashKey {
+	if i.Big != nil {
+		h := fnv.New64a()
+		if i.Big.Sign() < 0 {
+			h.Write([]byte{'-'})
+		}
+		h.Write(i.Big.Bytes())
+
+		return HashKey{Type: i.Type(), Value: h.Sum64()}
+	}
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
@@ -151,31 +236,187 @@ func (s *String) HashKey() HashKey {
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+// Combines the hash keys of the elements in order, so arrays can be used as
+// composite keys. Check AsHashable before calling it.
+func (ao *Array) HashKey() HashKey {
+	h := fnv.New64a()
+	var buf [8]byte
+
+	for _, e := range ao.Elements {
+		key := e.(Hashable).HashKey()
+		h.Write([]byte(key.Type))
+		binary.LittleEndian.PutUint64(buf[:], key.Value)
+		h.Write(buf[:])
+	}
+
+	return HashKey{Type: ao.Type(), Value: h.Sum64()}
+}
+
 type HashPair struct {
 	Key   Object
 	Value Object
 }
 
+// Hashes remember the order in which keys were first inserted. Inspect and
+// every builtin that walks a hash use that order, so output is stable.
+//
+// HashKey only picks the bucket a pair lives in. Different keys can share a
+// HashKey, so every lookup also checks the keys in the bucket for equality.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	buckets map[HashKey][]int
+	entries []HashPair
+
+	// picks the bucket of a key in place of its HashKey when set, so keys
+	// can be made to collide
+	hashKey func(Hashable) HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{buckets: make(map[HashKey][]int)}
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
 func (h *Hash) Inspect() string {
-  var out bytes.Buffer
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.entries {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Returns the bucket for key and the position of key in h.entries, or -1
+// when it is not in the hash.
+func (h *Hash) find(key Hashable) (HashKey, int) {
+	hashed := h.bucketOf(key)
+	for _, i := range h.buckets[hashed] {
+		if Equal(h.entries[i].Key, key.(Object)) {
+			return hashed, i
+		}
+	}
+	return hashed, -1
+}
+
+// Stores value under key. A key that is already present keeps its position
+// and only has its value replaced.
+func (h *Hash) Set(key Hashable, value Object) {
+	hashed, i := h.find(key)
+	if i >= 0 {
+		h.entries[i].Value = value
+		return
+	}
+
+	h.buckets[hashed] = append(h.buckets[hashed], len(h.entries))
+	h.entries = append(h.entries, HashPair{Key: key.(Object), Value: value})
+}
+
+func (h *Hash) Get(key Hashable) (HashPair, bool) {
+	_, i := h.find(key)
+	if i < 0 {
+		return HashPair{}, false
+	}
+	return h.entries[i], true
+}
+
+// Removes key from the hash, shifting the pairs after it up by one.
+func (h *Hash) Delete(key Hashable) {
+	_, i := h.find(key)
+	if i < 0 {
+		return
+	}
+
+	h.entries = append(h.entries[:i], h.entries[i+1:]...)
+	h.buckets = make(map[HashKey][]int, len(h.entries))
+	for j, pair := range h.entries {
+		hashed := h.bucketOf(pair.Key.(Hashable))
+		h.buckets[hashed] = append(h.buckets[hashed], j)
+	}
+}
 
-  pairs := []string{}
-  for _, pair := range h.Pairs {
-    pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
-  }
+func (h *Hash) bucketOf(key Hashable) HashKey {
+	if h.hashKey != nil {
+		return h.hashKey(key)
+	}
+	return key.HashKey()
+}
+
+func (h *Hash) Len() int { return len(h.entries) }
 
-  out.WriteString("{")
-  out.WriteString(strings.Join(pairs, ", "))
-  out.WriteString("}")
+// Returns the pairs in insertion order. The slice is shared with the hash and
+// must not be modified.
+func (h *Hash) Pairs() []HashPair { return h.entries }
 
-  return out.String()
+// Returns a shallow copy of the hash that can be changed without affecting
+// the original.
+func (h *Hash) Copy() *Hash {
+	hash := &Hash{
+		buckets: make(map[HashKey][]int, len(h.buckets)),
+		entries: make([]HashPair, len(h.entries)),
+		hashKey: h.hashKey,
+	}
+	copy(hash.entries, h.entries)
+	for key, bucket := range h.buckets {
+		hash.buckets[key] = append([]int(nil), bucket...)
+	}
+	return hash
 }
 
 type Hashable interface {
-  HashKey() HashKey
+	HashKey() HashKey
+}
+
+// Returns obj as a Hashable if it can be used as a hash key. Arrays implement
+// HashKey but are only usable as keys when all of their elements are.
+func AsHashable(obj Object) (Hashable, bool) {
+	if array, ok := obj.(*Array); ok {
+		for _, element := range array.Elements {
+			if _, ok := AsHashable(element); !ok {
+				return nil, false
+			}
+		}
+	}
+
+	hashable, ok := obj.(Hashable)
+	return hashable, ok
+}
+
+// A piece of unevaluated code, as returned by quote.
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("macro")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
 }
@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+  "math/big"
+  "testing"
+)
 
 func TestStringHashKey(t *testing.T) {
   hello1 := &String{Value: "Hello World"}
@@ -20,3 +23,106 @@ func TestStringHashKey(t *testing.T) {
     t.Errorf("strings with different content have same hash keys")
   }
 }
+
+func TestBigIntegerHashKey(t *testing.T) {
+  value, _ := new(big.Int).SetString("18446744073709551616", 10)
+  big1 := NewBigInteger(value)
+  big2 := NewBigInteger(new(big.Int).Set(value))
+  negative := NewBigInteger(new(big.Int).Neg(value))
+
+  if big1.HashKey() != big2.HashKey() {
+    t.Errorf("integers with same value have different hash keys")
+  }
+
+  if big1.HashKey() == negative.HashKey() {
+    t.Errorf("integers with different sign have same hash keys")
+  }
+
+  small := NewBigInteger(big.NewInt(42))
+  if small.IsBig() || small.HashKey() != (&Integer{Value: 42}).HashKey() {
+    t.Errorf("small value created from big.Int was not demoted")
+  }
+}
+
+func TestEqualCyclicArrays(t *testing.T) {
+  a := &Array{Elements: []Object{&Integer{Value: 1}}}
+  a.Elements = append(a.Elements, a)
+  b := &Array{Elements: []Object{&Integer{Value: 1}}}
+  b.Elements = append(b.Elements, b)
+  c := &Array{Elements: []Object{&Integer{Value: 2}}}
+  c.Elements = append(c.Elements, c)
+
+  if !Equal(a, b) {
+    t.Errorf("cyclic arrays with same shape are not equal")
+  }
+
+  if Equal(a, c) {
+    t.Errorf("cyclic arrays with different elements are equal")
+  }
+}
+
+func TestHashKeyCollisions(t *testing.T) {
+  // every key goes in the same bucket, as two strings whose FNV hashes
+  // collide would
+  hash := NewHash()
+  hash.hashKey = func(Hashable) HashKey { return HashKey{Type: STRING_OBJ, Value: 42} }
+
+  a := &String{Value: "a"}
+  b := &String{Value: "b"}
+  hash.Set(a, &Integer{Value: 1})
+  hash.Set(b, &Integer{Value: 2})
+
+  if hash.Len() != 2 {
+    t.Fatalf("colliding keys overwrote each other. got len=%d", hash.Len())
+  }
+
+  // lookups compare the contents of the strings, not the pointers
+  pair, ok := hash.Get(&String{Value: "a"})
+  if !ok || pair.Value.(*Integer).Value != 1 {
+    t.Errorf("wrong value for first colliding key. got=%+v", pair)
+  }
+
+  pair, ok = hash.Get(&String{Value: "b"})
+  if !ok || pair.Value.(*Integer).Value != 2 {
+    t.Errorf("wrong value for second colliding key. got=%+v", pair)
+  }
+
+  if _, ok := hash.Get(&String{Value: "c"}); ok {
+    t.Errorf("found a key that was never set")
+  }
+
+  hash.Set(&String{Value: "a"}, &Integer{Value: 3})
+  if pair, _ := hash.Get(a); hash.Len() != 2 || pair.Value.(*Integer).Value != 3 {
+    t.Errorf("setting an equal key did not replace the value. got len=%d", hash.Len())
+  }
+
+  hash.Delete(&String{Value: "a"})
+  if _, ok := hash.Get(a); ok {
+    t.Errorf("deleted key still present")
+  }
+  if _, ok := hash.Get(b); !ok {
+    t.Errorf("deleting a colliding key removed the other one")
+  }
+  if len(hash.buckets) != 1 {
+    t.Errorf("keys did not share a bucket. got %d buckets", len(hash.buckets))
+  }
+}
+
+func TestArrayHashKey(t *testing.T) {
+  one := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "x"}}}
+  two := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "x"}}}
+  swapped := &Array{Elements: []Object{&String{Value: "x"}, &Integer{Value: 1}}}
+
+  if one.HashKey() != two.HashKey() {
+    t.Errorf("arrays with same elements have different hash keys")
+  }
+
+  if one.HashKey() == swapped.HashKey() {
+    t.Errorf("arrays with elements in different order have same hash keys")
+  }
+
+  withFunction := &Array{Elements: []Object{&Function{}}}
+  if _, ok := AsHashable(withFunction); ok {
+    t.Errorf("array containing a function is hashable")
+  }
+}
@@ -0,0 +1,336 @@
+// Package optimizer rewrites Monkey programs into equivalent ones that do
+// less work when they run.
+package optimizer
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/object"
+	"example/sawan/goInterpreter/token"
+)
+
+/*
+Optimizes program in place and returns it:
+
+  - operators applied to literals are replaced by their result, found by
+    running them through the evaluator so the result is always the same as
+    at run time. Operations that fail, like a division by zero, are kept so
+    the error still happens when the program runs.
+  - if expressions with a literal condition are replaced by the branch that
+    would run.
+  - a name bound only once in the whole program, by a let to a literal, is
+    replaced by the literal in the statements after the let.
+
+Macros must be expanded before, as the optimizer does not look inside
+quoted code other than the arguments of unquote.
+*/
+func Optimize(program *ast.Program) *ast.Program {
+	o := &optimizer{bindings: countBindings(program)}
+	program.Statements = o.statements(program.Statements, map[string]ast.Expression{})
+	return program
+}
+
+/*
+bindings: How often each name is bound by a let or as a parameter, anywhere
+in the program. Only names bound once can be inlined, as every reference to
+them is then a reference to the same binding.
+*/
+type optimizer struct {
+	bindings map[string]int
+}
+
+func countBindings(program *ast.Program) map[string]int {
+	bindings := map[string]int{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			if node.Name != nil {
+				bindings[node.Name.Value]++
+			}
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				bindings[param.Value]++
+			}
+		case *ast.MacroLiteral:
+			for _, param := range node.Parameters {
+				bindings[param.Value]++
+			}
+		case *ast.TryExpression:
+			if node.Param != nil {
+				bindings[node.Param.Value]++
+			}
+		}
+		return true
+	})
+	return bindings
+}
+
+// Optimizes a list of statements that run one after the other. A constant
+// is only inlined into the statements after its let, and into the functions
+// defined there, as only those run once the let has.
+func (o *optimizer) statements(statements []ast.Statement, constants map[string]ast.Expression) []ast.Statement {
+	scope := map[string]ast.Expression{}
+	for name, value := range constants {
+		scope[name] = value
+	}
+
+	optimized := []ast.Statement{}
+	for i, stmt := range statements {
+		stmt = o.statement(stmt, scope)
+
+		added := []ast.Statement{stmt}
+		if branch, ok := deadIf(stmt); ok {
+			added = branch
+			// the value of an empty branch is the value of the whole list
+			// when it is last, and nothing else gives the same
+			if len(branch) == 0 && i == len(statements)-1 {
+				added = []ast.Statement{stmt}
+			}
+		}
+
+		for _, stmt := range added {
+			if name, value, ok := o.constant(stmt); ok {
+				scope[name] = value
+			}
+		}
+		optimized = append(optimized, added...)
+	}
+	return optimized
+}
+
+func (o *optimizer) statement(stmt ast.Statement, constants map[string]ast.Expression) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		stmt.Value = o.expression(stmt.Value, constants)
+	case *ast.ExportStatement:
+		if stmt.Statement != nil {
+			stmt.Statement.Value = o.expression(stmt.Statement.Value, constants)
+		}
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = o.expression(stmt.ReturnValue, constants)
+	case *ast.ThrowStatement:
+		stmt.Value = o.expression(stmt.Value, constants)
+	case *ast.ExpressionStatement:
+		stmt.Expression = o.expression(stmt.Expression, constants)
+	}
+	return stmt
+}
+
+// Reports whether stmt binds a name that can be inlined, and to what.
+func (o *optimizer) constant(stmt ast.Statement) (string, ast.Expression, bool) {
+	if export, ok := stmt.(*ast.ExportStatement); ok {
+		stmt = export.Statement
+	}
+	let, ok := stmt.(*ast.LetStatement)
+	if !ok || let.Name == nil || !isLiteral(let.Value) || o.bindings[let.Name.Value] != 1 {
+		return "", nil, false
+	}
+	return let.Name.Value, let.Value, true
+}
+
+func (o *optimizer) block(block *ast.BlockStatement, constants map[string]ast.Expression) *ast.BlockStatement {
+	if block != nil {
+		block.Statements = o.statements(block.Statements, constants)
+	}
+	return block
+}
+
+func (o *optimizer) expression(exp ast.Expression, constants map[string]ast.Expression) ast.Expression {
+	switch exp := exp.(type) {
+
+	case *ast.Identifier:
+		if value, ok := constants[exp.Value]; ok {
+			return value
+		}
+
+	case *ast.PrefixExpression:
+		exp.Right = o.expression(exp.Right, constants)
+		if isLiteral(exp.Right) {
+			return fold(exp)
+		}
+
+	case *ast.InfixExpression:
+		exp.Left = o.expression(exp.Left, constants)
+		exp.Right = o.expression(exp.Right, constants)
+		if isLiteral(exp.Left) && isLiteral(exp.Right) {
+			return fold(exp)
+		}
+
+	case *ast.IfExpression:
+		exp.Condition = o.expression(exp.Condition, constants)
+		exp.Consequence = o.block(exp.Consequence, constants)
+		exp.Alternative = o.block(exp.Alternative, constants)
+		return pruneIf(exp)
+
+	case *ast.TryExpression:
+		exp.Block = o.block(exp.Block, constants)
+		exp.Catch = o.block(exp.Catch, constants)
+		exp.Finally = o.block(exp.Finally, constants)
+
+	case *ast.FunctionLiteral:
+		exp.Body = o.block(exp.Body, constants)
+
+	case *ast.CallExpression:
+		if ident, ok := exp.Function.(*ast.Identifier); ok && ident.Value == "quote" {
+			o.unquotes(exp, constants)
+			return exp
+		}
+		exp.Function = o.expression(exp.Function, constants)
+		for i, arg := range exp.Arguments {
+			exp.Arguments[i] = o.expression(arg, constants)
+		}
+
+	case *ast.ArrayLiteral:
+		for i, el := range exp.Elements {
+			exp.Elements[i] = o.expression(el, constants)
+		}
+
+	case *ast.IndexExpression:
+		exp.Left = o.expression(exp.Left, constants)
+		exp.Index = o.expression(exp.Index, constants)
+
+	case *ast.SliceExpression:
+		exp.Left = o.expression(exp.Left, constants)
+		exp.Start = o.expression(exp.Start, constants)
+		exp.End = o.expression(exp.End, constants)
+
+	case *ast.HashLiteral:
+		for i, pair := range exp.Pairs {
+			exp.Pairs[i].Key = o.expression(pair.Key, constants)
+			exp.Pairs[i].Value = o.expression(pair.Value, constants)
+		}
+	}
+
+	return exp
+}
+
+// Quoted code is left as written, apart from the arguments of unquote,
+// which are evaluated.
+func (o *optimizer) unquotes(quote *ast.CallExpression, constants map[string]ast.Expression) {
+	for _, arg := range quote.Arguments {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpression)
+			if !ok {
+				return true
+			}
+			if ident, ok := call.Function.(*ast.Identifier); !ok || ident.Value != "unquote" {
+				return true
+			}
+			for i, arg := range call.Arguments {
+				call.Arguments[i] = o.expression(arg, constants)
+			}
+			return false
+		})
+	}
+}
+
+func isLiteral(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	}
+	return false
+}
+
+// Evaluates an operator applied to literals, and returns the literal of the
+// result. The expression is returned unchanged when evaluating it fails.
+func fold(exp ast.Expression) ast.Expression {
+	result := evaluator.Eval(exp, object.NewEnvironment())
+
+	// the literal takes the place of the whole expression
+	tok := ast.TokenOf(exp)
+	if infix, ok := exp.(*ast.InfixExpression); ok {
+		tok = ast.TokenOf(infix.Left)
+	}
+	switch result := result.(type) {
+	case *object.Integer:
+		tok.Type, tok.Literal = token.INT, result.Inspect()
+		return &ast.IntegerLiteral{Token: tok, Value: result.Value, Big: result.Big}
+
+	case *object.Float:
+		if math.IsInf(result.Value, 0) || math.IsNaN(result.Value) {
+			return exp
+		}
+		// the lexer does not read exponents, so write the digits out
+		literal := strconv.FormatFloat(result.Value, 'f', -1, 64)
+		if !strings.Contains(literal, ".") {
+			literal += ".0"
+		}
+		tok.Type, tok.Literal = token.FLOAT, literal
+		return &ast.FloatLiteral{Token: tok, Value: result.Value}
+
+	case *object.String:
+		tok.Type, tok.Literal = token.STRING, result.Value
+		return &ast.StringLiteral{Token: tok, Value: result.Value}
+
+	case *object.Boolean:
+		tok.Type, tok.Literal = token.FALSE, "false"
+		if result.Value {
+			tok.Type, tok.Literal = token.TRUE, "true"
+		}
+		return &ast.Boolean{Token: tok, Value: result.Value}
+	}
+
+	return exp
+}
+
+// Returns the branch that runs when the condition of exp is a literal. Any
+// literal but false is truthy.
+func takenBranch(exp *ast.IfExpression) (*ast.BlockStatement, bool) {
+	if !isLiteral(exp.Condition) {
+		return nil, false
+	}
+	if b, ok := exp.Condition.(*ast.Boolean); ok && !b.Value {
+		return exp.Alternative, true
+	}
+	return exp.Consequence, true
+}
+
+// An if whose branch is a single expression becomes that expression.
+// Otherwise only the branch that cannot run is dropped.
+func pruneIf(exp *ast.IfExpression) ast.Expression {
+	branch, ok := takenBranch(exp)
+	if !ok {
+		return exp
+	}
+
+	if branch != nil && len(branch.Statements) == 1 {
+		if stmt, ok := branch.Statements[0].(*ast.ExpressionStatement); ok && stmt.Expression != nil {
+			return stmt.Expression
+		}
+	}
+
+	if branch == exp.Consequence {
+		exp.Alternative = nil
+	} else if exp.Consequence != nil {
+		exp.Consequence = &ast.BlockStatement{Token: exp.Consequence.Token, Rbrace: exp.Consequence.Rbrace}
+	}
+	return exp
+}
+
+// Returns the statements of the branch that runs when stmt is an if with a
+// literal condition. Blocks share the environment around them, so the
+// branch can take the place of the whole if.
+func deadIf(stmt ast.Statement) ([]ast.Statement, bool) {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+	exp, ok := es.Expression.(*ast.IfExpression)
+	if !ok {
+		return nil, false
+	}
+
+	branch, ok := takenBranch(exp)
+	if !ok {
+		return nil, false
+	}
+	if branch == nil {
+		return []ast.Statement{}, true
+	}
+	return branch.Statements, true
+}
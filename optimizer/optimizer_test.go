@@ -0,0 +1,94 @@
+package optimizer
+
+import (
+	"testing"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/format"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/object"
+	"example/sawan/goInterpreter/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"60 * 60 * 24", "86400;\n"},
+		{`"a" + "b" + "c"`, "\"abc\";\n"},
+		{"!true == false", "true;\n"},
+		{"1 < 2 == (3 > 4)", "false;\n"},
+		{"-(2 - 5) * 2.5", "7.5;\n"},
+		{"x - (1 - 5)", "x - -4;\n"},
+		{"(1 - 5)[0]", "(-4)[0];\n"},
+		{"9223372036854775807 + 1", "9223372036854775808;\n"},
+		// failures are kept for the evaluator to report
+		{"1 / 0", "1 / 0;\n"},
+		{`1 + "a"`, "1 + \"a\";\n"},
+		{"x + 1 + 2", "x + 1 + 2;\n"},
+
+		{"if (true) { 1 } else { 2 }", "1;\n"},
+		{"if (1 > 2) { 1 } else { f(); 2 }", "f();\n2;\n"},
+		{"if (false) { puts(1); } x", "x;\n"},
+		{"x; if (false) { puts(1); }", "x;\nif (false) {}\n"},
+		{"let y = if (0) { a; b } else { c; d };", "let y = if (0) {\n  a;\n  b;\n};\n"},
+		{"let y = if (false) { a; b } else { c; d };", "let y = if (false) {} else {\n  c;\n  d;\n};\n"},
+
+		{"let a = 2; let b = a * 3; fn(x) { x + b }", "let a = 2;\nlet b = 6;\nfn(x) { x + 6 };\n"},
+		{"let debug = false; if (debug) { puts(1); } run()", "let debug = false;\nrun();\n"},
+		// bound twice, or used before the let
+		{"let a = 1; let a = 2; a", "let a = 1;\nlet a = 2;\na;\n"},
+		{"let f = fn(a) { a }; let a = 1; a", "let f = fn(a) { a };\nlet a = 1;\na;\n"},
+		{"puts(a); let a = 1; a", "puts(a);\nlet a = 1;\n1;\n"},
+		{"if (c) { let a = 1; a } a", "if (c) {\n  let a = 1;\n  1;\n}\na;\n"},
+		{"let a = 1; quote(a + unquote(a + 1))", "let a = 1;\nquote(a + unquote(2));\n"},
+		{"export let a = 1; a", "export let a = 1;\n1;\n"},
+		{"let n = 2; try { throw n * 3 } catch (e) { e }", "let n = 2;\ntry {\n  throw 6;\n} catch (e) {\n  e;\n}\n"},
+		// the catch parameter is a binding of its own
+		{"let e = 1; try { x } catch (e) { e }", "let e = 1;\ntry {\n  x;\n} catch (e) {\n  e;\n}\n"},
+	}
+
+	for _, tt := range tests {
+		program := Optimize(parse(t, tt.input))
+		if got := format.Program(program, nil); got != tt.expected {
+			t.Errorf("wrong optimization of %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// Optimized programs must give the same result as the original ones.
+func TestOptimizePreservesResults(t *testing.T) {
+	inputs := []string{
+		"let seconds = 60 * 60 * 24; let f = fn(days) { days * seconds }; f(7)",
+		`let name = "monkey"; let greet = fn() { "hello " + name }; greet()`,
+		"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(20)",
+		"let f = fn() { if (true) { return 1; } 2 }; f()",
+		"if (true) { let x = 5; } x * 2",
+		"if (false) { 1 }",
+		"let a = 1.5; let b = a * 2; [b, b / 0.5, -b]",
+		"let x = 1 / 0; x",
+		`{"k" + "ey": 1 + 1}["key"]`,
+		`let limit = 10; try { if (limit > 5) { throw "too " + "big" } 1 } catch (e) { e["message"] }`,
+	}
+
+	for _, input := range inputs {
+		expected := evaluator.Eval(parse(t, input), object.NewEnvironment())
+		got := evaluator.Eval(Optimize(parse(t, input)), object.NewEnvironment())
+
+		if expected.Inspect() != got.Inspect() {
+			t.Errorf("optimizing %q changed its result. expected=%s, got=%s", input, expected.Inspect(), got.Inspect())
+		}
+	}
+}
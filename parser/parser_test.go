@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"example/sawan/goInterpreter/ast"
@@ -759,15 +760,23 @@ func TestParsingHashLiteralStringKeys(t *testing.T) {
 		"three": 3,
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 		}
 
 		expectedValue := expected[literal.String()]
 
-		testIntegerLiteral(t, value, expectedValue)
+		testIntegerLiteral(t, pair.Value, expectedValue)
+	}
+
+	order := []string{}
+	for _, pair := range hash.Pairs {
+		order = append(order, pair.Key.String())
+	}
+	if strings.Join(order, ",") != "one,two,three" {
+		t.Errorf("hash.Pairs not in source order. got=%v", order)
 	}
 }
 
@@ -822,11 +831,11 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 			continue
 		}
 
@@ -837,6 +846,306 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 			continue
 		}
 
-		testFunc(value)
+		testFunc(pair.Value)
+	}
+}
+
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	input := "18446744073709551616;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserError(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Big == nil || literal.Big.String() != "18446744073709551616" {
+		t.Errorf("literal.Big wrong. got=%v", literal.Big)
+	}
+}
+
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"myArray[1:2]", "(myArray[1:2])"},
+		{"myArray[:2]", "(myArray[:2])"},
+		{"myArray[1:]", "(myArray[1:])"},
+		{"myArray[:]", "(myArray[:])"},
+		{"myArray[-1:a + 1]", "(myArray[(-1):(a + 1)])"},
+		{"a[1:][0]", "((a[1:])[0])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserError(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.SliceExpression); !ok && tt.input != "a[1:][0]" {
+			t.Fatalf("exp not *ast.SliceExpression. got=%T", stmt.Expression)
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "3.25;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserError(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != 3.25 {
+		t.Errorf("literal.Value not %f. got=%f", 3.25, literal.Value)
+	}
+
+	if literal.TokenLiteral() != "3.25" {
+		t.Errorf("literal.TokenLiteral not %s. got=%s", "3.25", literal.TokenLiteral())
+	}
+}
+
+func TestImportAndExport(t *testing.T) {
+	input := `export let x = import "lib/util.mk";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserError(t, p)
+
+	export, ok := program.Statements[0].(*ast.ExportStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ExportStatement. got=%T", program.Statements[0])
+	}
+
+	if !testLetStatement(t, export.Statement, "x") {
+		return
+	}
+
+	imp, ok := export.Statement.Value.(*ast.ImportExpression)
+	if !ok {
+		t.Fatalf("value not *ast.ImportExpression. got=%T", export.Statement.Value)
+	}
+
+	if imp.Path.Value != "lib/util.mk" {
+		t.Errorf("imp.Path.Value not %q. got=%q", "lib/util.mk", imp.Path.Value)
+	}
+
+	if program.String() != `export let x = import "lib/util.mk";` {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserError(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d", len(macro.Parameters))
+	}
+
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statements. got=%d", len(macro.Body.Statements))
+	}
+
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T", macro.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestFailedLetStatementIsDropped(t *testing.T) {
+	l := lexer.New(`let = 5; let x = 1;`)
+	p := New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected parser errors")
+	}
+
+	for i, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok && let == nil {
+			t.Errorf("program.Statements[%d] is a nil *ast.LetStatement", i)
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let xs: [string] = [];", "let xs: [string] = [];"},
+		{"let h: {string: [int]} = {};", "let h: {string: [int]} = {};"},
+		{"fn(a: int, b): bool { a + 1 }", "fn(a: int, b): bool(a + 1)"},
+		{"fn(f: fn(int, string): float, g: fn(), h: fn) { f }", "fn(f: fn(int, string): float, g: fn(), h: fn)f"},
+		{"let f = fn(): {int: bool} { {} };", "let f = fn(): {int: bool}{};"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	fn := New(lexer.New("fn(a: int, b): bool { a }")).ParseProgram().Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if fn.Parameters[0].Type.String() != "int" || fn.Parameters[1].Type != nil || fn.ReturnType.String() != "bool" {
+		t.Errorf("wrong annotations: %v, %v, %v", fn.Parameters[0].Type, fn.Parameters[1].Type, fn.ReturnType)
+	}
+}
+
+func TestTypeAnnotationErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: = 5;", "expected a type, got = instead"},
+		{"let x: [int = 5;", "expected next token to be ], got = instead"},
+		{"let h: {string} = {};", "expected next token to be :, got } instead"},
+		{"fn(a: 1) { a }", "expected a type, got INT instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected first=%q, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	var out strings.Builder
+	p := New(lexer.New("-a + b * f(1); !"))
+	p.SetTrace(&out)
+	p.ParseProgram()
+
+	expected := `BEGIN parseExpression LOWEST at 1:1 - "-"
+	prefix parsePrefixExpression
+	BEGIN parseExpression PREFIX at 1:2 IDENT "a"
+		prefix parseIdentifier
+	END parseExpression PREFIX: a
+	infix parseInfixExpression for + (SUM > LOWEST)
+	BEGIN parseExpression SUM at 1:6 IDENT "b"
+		prefix parseIdentifier
+		infix parseInfixExpression for * (PRODUCT > SUM)
+		BEGIN parseExpression PRODUCT at 1:10 IDENT "f"
+			prefix parseIdentifier
+			infix parseCallExpression for ( (CALL > PRODUCT)
+			BEGIN parseExpression LOWEST at 1:12 INT "1"
+				prefix parseIntegerLiteral
+			END parseExpression LOWEST: 1
+		END parseExpression PRODUCT: f(1)
+	END parseExpression SUM: (b * f(1))
+END parseExpression LOWEST: ((-a) + (b * f(1)))
+BEGIN parseExpression LOWEST at 1:16 ! "!"
+	prefix parsePrefixExpression
+	BEGIN parseExpression PREFIX at 1:17 EOF ""
+	END parseExpression PREFIX: <nil>
+END parseExpression LOWEST: <incomplete>
+`
+	if out.String() != expected {
+		t.Errorf("wrong trace.\nexpected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestTryAndThrow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`throw "oops";`, `throw oops;`},
+		{`throw error("oops")`, `throw error(oops);`},
+		{"try { f() } catch (e) { e }", "try f() catch (e) e"},
+		{"try { f() } finally { g() }", "try f() finally g()"},
+		{"let x = try { f() } catch (err) { 0 } finally { g() };", "let x = try f() catch (err) 0 finally g();"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	stmt := New(lexer.New("try { 1 } catch (e) { 2 }")).ParseProgram().Statements[0].(*ast.ExpressionStatement)
+	try, ok := stmt.Expression.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.TryExpression. got=%T", stmt.Expression)
+	}
+	if try.Param.Value != "e" || len(try.Block.Statements) != 1 || len(try.Catch.Statements) != 1 || try.Finally != nil {
+		t.Errorf("wrong try expression: %+v", try)
+	}
+}
+
+func TestTryErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { 1 }", "expected catch or finally after try block, got EOF instead"},
+		{"try { 1 } catch { 2 }", "expected next token to be (, got { instead"},
+		{"try { 1 } catch (1) { 2 }", "expected next token to be IDENT, got INT instead"},
+		{"try 1 catch (e) { 2 }", "expected next token to be {, got INT instead"},
+		{"throw;", "no prefix parse function for ; found"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected first=%q, got=%v", tt.input, tt.expected, p.Errors())
+		}
 	}
 }
@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/token"
+)
+
+var precedenceNames = map[int]string{
+	LOWEST:      "LOWEST",
+	EQUALS:      "EQUALS",
+	LESSGREATER: "LESSGREATER",
+	SUM:         "SUM",
+	PRODUCT:     "PRODUCT",
+	PREFIX:      "PREFIX",
+	CALL:        "CALL",
+	INDEX:       "INDEX",
+}
+
+/*
+Logs how the parser works through expressions, for debugging changes to the
+grammar: every call of parseExpression with the precedence it binds at, the
+prefix and infix parse functions it calls and the expression it returns.
+
+depth: How many calls of parseExpression are running, to indent by
+*/
+type tracer struct {
+	out   io.Writer
+	depth int
+}
+
+// Makes the parser log how it parses expressions to w. nil turns it off.
+func (p *Parser) SetTrace(w io.Writer) {
+	p.tracer = nil
+	if w != nil {
+		p.tracer = &tracer{out: w}
+	}
+}
+
+func (t *tracer) print(format string, a ...interface{}) {
+	fmt.Fprintf(t.out, "%s%s\n", strings.Repeat("\t", t.depth), fmt.Sprintf(format, a...))
+}
+
+func (t *tracer) begin(tok token.Token, precedence int) {
+	t.print("BEGIN parseExpression %s at %d:%d %s %q", precedenceNames[precedence], tok.Line, tok.Column, tok.Type, tok.Literal)
+	t.depth++
+}
+
+func (t *tracer) end(precedence int, exp ast.Expression) {
+	t.depth--
+	t.print("END parseExpression %s: %s", precedenceNames[precedence], describe(exp))
+}
+
+// Expressions with syntax errors are missing parts, which String does not
+// expect.
+func describe(exp ast.Expression) (s string) {
+	if exp == nil || reflect.ValueOf(exp).IsNil() {
+		return "<nil>"
+	}
+	defer func() {
+		if recover() != nil {
+			s = "<incomplete>"
+		}
+	}()
+	return exp.String()
+}
+
+func (t *tracer) prefix(fn prefixParseFn) {
+	t.print("prefix %s", funcName(fn))
+}
+
+// Logs an infix parse function called because the precedence of the
+// operator is higher than the one the expression binds at.
+func (t *tracer) infix(fn infixParseFn, operator token.Token, precedence int) {
+	t.print("infix %s for %s (%s > %s)", funcName(fn), operator.Literal, precedenceNames[Precedence(operator.Type)], precedenceNames[precedence])
+}
+
+// Returns the name of the parse function fn, a method value of Parser.
+func funcName(fn interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
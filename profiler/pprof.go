@@ -0,0 +1,164 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// Field numbers of the messages of profile.proto, the format go tool pprof
+// reads.
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID        = 1
+	functionName      = 2
+	functionFilename  = 4
+	functionStartLine = 5
+)
+
+// Writes the profile in the gzipped protocol buffer format of pprof, with
+// the calls and the time of every stack as its values. Every line of a
+// function is a location of its own.
+func (p *Profile) WritePprof(w io.Writer) error {
+	strings := map[string]int64{}
+	table := []string{}
+	str := func(s string) int64 {
+		if i, ok := strings[s]; ok {
+			return i
+		}
+		strings[s] = int64(len(table))
+		table = append(table, s)
+		return strings[s]
+	}
+	str("")
+
+	var b protobuf
+	for _, t := range [][2]string{{"calls", "count"}, {"time", "nanoseconds"}} {
+		var vt protobuf
+		vt.int(valueTypeType, str(t[0]))
+		vt.int(valueTypeUnit, str(t[1]))
+		b.message(profileSampleType, vt)
+	}
+
+	locations := map[Location]uint64{}
+	var locs protobuf
+	for _, s := range p.Samples {
+		ids := []uint64{}
+		for _, loc := range s.Stack {
+			id, ok := locations[loc]
+			if !ok {
+				id = uint64(len(locations) + 1)
+				locations[loc] = id
+
+				var line, l protobuf
+				line.uint(lineFunctionID, loc.Function.id)
+				line.int(lineLine, int64(loc.Line))
+				l.uint(locationID, id)
+				l.message(locationLine, line)
+				locs.message(profileLocation, l)
+			}
+			ids = append(ids, id)
+		}
+
+		var sample protobuf
+		sample.packedUints(sampleLocationID, ids)
+		sample.packedInts(sampleValue, []int64{s.Calls, int64(s.Time)})
+		b.message(profileSample, sample)
+	}
+	b = append(b, locs...)
+
+	for _, fn := range p.Functions {
+		var f protobuf
+		f.uint(functionID, fn.id)
+		f.int(functionName, str(fn.Name))
+		f.int(functionFilename, str(p.Path))
+		f.int(functionStartLine, int64(fn.Line))
+		b.message(profileFunction, f)
+	}
+
+	for _, s := range table {
+		b.bytes(profileStringTable, []byte(s))
+	}
+	b.int(profileTimeNanos, p.Start.UnixNano())
+	b.int(profileDurationNanos, int64(p.Duration))
+
+	z := gzip.NewWriter(w)
+	if _, err := z.Write(b); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// Just enough of the protocol buffer encoding for profiles: varints and
+// length delimited fields.
+type protobuf []byte
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		*b = append(*b, byte(x)|0x80)
+		x >>= 7
+	}
+	*b = append(*b, byte(x))
+}
+
+func (b *protobuf) tag(field, wire int) {
+	b.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (b *protobuf) uint(field int, x uint64) {
+	b.tag(field, wireVarint)
+	b.varint(x)
+}
+
+func (b *protobuf) int(field int, x int64) {
+	b.uint(field, uint64(x))
+}
+
+func (b *protobuf) bytes(field int, data []byte) {
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+func (b *protobuf) message(field int, m protobuf) {
+	b.bytes(field, m)
+}
+
+func (b *protobuf) packedUints(field int, xs []uint64) {
+	var packed protobuf
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	b.bytes(field, packed)
+}
+
+func (b *protobuf) packedInts(field int, xs []int64) {
+	var packed protobuf
+	for _, x := range xs {
+		packed.varint(uint64(x))
+	}
+	b.bytes(field, packed)
+}
@@ -0,0 +1,198 @@
+// Package profiler measures where Monkey programs spend their time, by
+// function and by source line.
+package profiler
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/object"
+)
+
+/*
+A function of the profiled program.
+
+Name: The name it was called by. A function only ever called without one,
+like a callback of map, is called fn.
+Line: The line its body starts on
+*/
+type Function struct {
+	Name string
+	Line int
+
+	id uint64
+}
+
+// A line of a function.
+type Location struct {
+	Function *Function
+	Line     int
+}
+
+/*
+The time spent in one stack of locations.
+
+Stack: Innermost location first
+Calls: How many calls started with this stack
+*/
+type Sample struct {
+	Stack []Location
+	Calls int64
+	Time  time.Duration
+}
+
+type frame struct {
+	fn   *Function
+	line int
+}
+
+/*
+Collects samples while a program runs, through the hooks of the evaluator.
+Every hook charges the time since the one before to the stack of locations
+running in between, so the time spent in builtins goes to the line calling
+them.
+
+main: The pseudo function running the top level of the program
+functions: Functions by their body, which is the same for every closure of
+them
+key: Scratch space for the key of the current stack in samples
+*/
+type Profiler struct {
+	path string
+	now  func() time.Time
+
+	start time.Time
+	last  time.Time
+	stack []frame
+
+	main      *Function
+	functions map[*ast.BlockStatement]*Function
+	samples   map[string]*Sample
+	hits      map[Location]int64
+	key       []byte
+}
+
+// Starts profiling the programs the evaluator runs. path is the script the
+// profile is for, named in reports.
+func Start(path string) *Profiler {
+	p := newProfiler(path, time.Now)
+	evaluator.SetDebugger(p)
+	return p
+}
+
+func newProfiler(path string, now func() time.Time) *Profiler {
+	p := &Profiler{
+		path:      path,
+		now:       now,
+		main:      &Function{Name: "main", Line: 1, id: 1},
+		functions: map[*ast.BlockStatement]*Function{},
+		samples:   map[string]*Sample{},
+		hits:      map[Location]int64{},
+	}
+	p.start = now()
+	p.last = p.start
+	p.stack = []frame{{fn: p.main, line: 1}}
+	return p
+}
+
+// Stops profiling and returns what was measured.
+func (p *Profiler) Stop() *Profile {
+	p.checkpoint()
+	evaluator.SetDebugger(nil)
+
+	profile := &Profile{Path: p.path, Start: p.start, Duration: p.last.Sub(p.start), Hits: p.hits}
+	for _, s := range p.samples {
+		profile.Samples = append(profile.Samples, s)
+	}
+	profile.Functions = append(profile.Functions, p.main)
+	for _, fn := range p.functions {
+		profile.Functions = append(profile.Functions, fn)
+	}
+	sort.Slice(profile.Functions, func(i, j int) bool { return profile.Functions[i].id < profile.Functions[j].id })
+	sort.Slice(profile.Samples, func(i, j int) bool { return stackLess(profile.Samples[i].Stack, profile.Samples[j].Stack) })
+	return profile
+}
+
+func (p *Profiler) Statement(stmt ast.Statement, env *object.Environment) {
+	p.checkpoint()
+	top := &p.stack[len(p.stack)-1]
+	top.line = ast.TokenOf(stmt).Line
+	p.hits[Location{top.fn, top.line}]++
+}
+
+func (p *Profiler) Call(call *ast.CallExpression, fn *object.Function, env *object.Environment, tail bool) {
+	p.checkpoint()
+
+	f := p.function(call, fn)
+	if tail {
+		p.stack = p.stack[:len(p.stack)-1]
+	}
+	p.stack = append(p.stack, frame{fn: f, line: f.Line})
+	p.sample().Calls++
+}
+
+func (p *Profiler) Return(fn *object.Function) {
+	p.checkpoint()
+	p.stack = p.stack[:len(p.stack)-1]
+}
+
+func (p *Profiler) function(call *ast.CallExpression, fn *object.Function) *Function {
+	name := evaluator.CallName(call)
+	f, ok := p.functions[fn.Body]
+	if !ok {
+		f = &Function{Name: name, Line: fn.Body.Token.Line, id: uint64(len(p.functions) + 2)}
+		p.functions[fn.Body] = f
+	}
+	// a name is better than none
+	if f.Name == "fn" {
+		f.Name = name
+	}
+	return f
+}
+
+// Charges the time since the last checkpoint to the current stack.
+func (p *Profiler) checkpoint() {
+	now := p.now()
+	if elapsed := now.Sub(p.last); elapsed > 0 {
+		p.sample().Time += elapsed
+	}
+	p.last = now
+}
+
+// Returns the sample of the current stack.
+func (p *Profiler) sample() *Sample {
+	p.key = p.key[:0]
+	for _, f := range p.stack {
+		p.key = strconv.AppendUint(p.key, f.fn.id, 10)
+		p.key = append(p.key, ':')
+		p.key = strconv.AppendInt(p.key, int64(f.line), 10)
+		p.key = append(p.key, ' ')
+	}
+
+	if s, ok := p.samples[string(p.key)]; ok {
+		return s
+	}
+	s := &Sample{}
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		s.Stack = append(s.Stack, Location{p.stack[i].fn, p.stack[i].line})
+	}
+	p.samples[string(p.key)] = s
+	return s
+}
+
+// Orders stacks from the outermost location in, for reports that do not
+// depend on the order of maps.
+func stackLess(a, b []Location) bool {
+	for i, j := len(a)-1, len(b)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if a[i].Function.id != b[j].Function.id {
+			return a[i].Function.id < b[j].Function.id
+		}
+		if a[i].Line != b[j].Line {
+			return a[i].Line < b[j].Line
+		}
+	}
+	return len(a) < len(b)
+}
@@ -0,0 +1,254 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/object"
+	"example/sawan/goInterpreter/parser"
+)
+
+const sumSource = `let sq = fn(x) {
+  x * x
+};
+let sum = fn(n) {
+  if (n == 0) { return 0; }
+  sq(n) + sum(n - 1)
+};
+let squares = map([1, 2], fn(x) { sq(x) });
+sum(2);
+`
+
+// Profiles source with a clock that moves on a millisecond every time it is
+// read, so every hook takes as long.
+func profile(t *testing.T, source string) *Profile {
+	t.Helper()
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prof := newProfiler("sum.mk", func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	})
+	evaluator.SetDebugger(prof)
+	evaluator.Eval(program, object.NewEnvironment())
+	return prof.Stop()
+}
+
+func TestFunctionStats(t *testing.T) {
+	profile := profile(t, sumSource)
+	stats := profile.FunctionStats()
+
+	tests := []struct {
+		name  string
+		line  int
+		calls int64
+	}{
+		{"main", 1, 0},
+		{"fn", 8, 2},
+		{"sq", 1, 4},
+		{"sum", 4, 3},
+	}
+
+	if len(profile.Functions) != len(tests) {
+		t.Fatalf("wrong number of functions. want=%d, got=%d", len(tests), len(profile.Functions))
+	}
+	var flat time.Duration
+	for i, tt := range tests {
+		fn := profile.Functions[i]
+		if fn.Name != tt.name || fn.Line != tt.line {
+			t.Errorf("functions[%d] wrong. want=%s:%d, got=%s:%d", i, tt.name, tt.line, fn.Name, fn.Line)
+		}
+		if stats[fn].Count != tt.calls {
+			t.Errorf("%s called wrong number of times. want=%d, got=%d", tt.name, tt.calls, stats[fn].Count)
+		}
+		flat += stats[fn].Flat
+	}
+
+	if flat != profile.Duration {
+		t.Errorf("flat times do not add up. want=%s, got=%s", profile.Duration, flat)
+	}
+	if main := stats[profile.Functions[0]]; main.Cum != profile.Duration {
+		t.Errorf("main does not include everything. want=%s, got=%s", profile.Duration, main.Cum)
+	}
+	// the recursion must not count twice
+	if sum := stats[profile.Functions[3]]; sum.Cum > profile.Duration {
+		t.Errorf("sum takes longer than the program: %s", sum.Cum)
+	}
+}
+
+func TestLineStats(t *testing.T) {
+	profile := profile(t, sumSource)
+	stats := profile.LineStats()
+
+	hits := map[string]int64{}
+	for loc, s := range stats {
+		hits[loc.Function.Name+":"+string(rune('0'+loc.Line))] = s.Count
+	}
+	want := map[string]int64{
+		"main:1": 1, "main:4": 1, "main:8": 1, "main:9": 1,
+		"sq:2": 4, "fn:8": 2, "sum:5": 4, "sum:6": 2,
+	}
+	for line, count := range want {
+		if hits[line] != count {
+			t.Errorf("wrong hits for %s. want=%d, got=%d", line, count, hits[line])
+		}
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	var out bytes.Buffer
+	if err := profile(t, sumSource).WriteTable(&out); err != nil {
+		t.Fatalf("WriteTable failed: %s", err)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[0], "Profile of sum.mk: ") {
+		t.Errorf("wrong title. got=%q", lines[0])
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "flat flat% sum% cum cum% calls function" {
+		t.Errorf("wrong header. got=%q", lines[2])
+	}
+	for _, want := range []string{"  sq sum.mk:1", "  sum sum.mk:4", "  sum.mk:6 sum", "  sum.mk:2 sq"} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("table is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	// the last row of each table accounts for all the time
+	for _, row := range []string{lines[6], lines[len(lines)-2]} {
+		if fields := strings.Fields(row); len(fields) < 3 || fields[2] != "100.00%" {
+			t.Errorf("sum%% does not end at 100%%. got=%q", row)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{1500 * time.Millisecond, "1.50s"},
+		{2500 * time.Microsecond, "2.50ms"},
+		{1234 * time.Nanosecond, "1.23us"},
+		{12, "12ns"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%d) wrong. want=%q, got=%q", tt.d, tt.want, got)
+		}
+	}
+}
+
+// Reads the fields of a protocol buffer message, the ones with varints as
+// numbers and the others as bytes.
+func fields(t *testing.T, b []byte) map[int][]interface{} {
+	t.Helper()
+
+	varint := func() uint64 {
+		var x uint64
+		for shift := 0; ; shift += 7 {
+			if len(b) == 0 {
+				t.Fatalf("truncated varint")
+			}
+			c := b[0]
+			b = b[1:]
+			x |= uint64(c&0x7f) << shift
+			if c < 0x80 {
+				return x
+			}
+		}
+	}
+
+	fields := map[int][]interface{}{}
+	for len(b) > 0 {
+		tag := varint()
+		field := int(tag >> 3)
+		switch tag & 7 {
+		case wireVarint:
+			fields[field] = append(fields[field], varint())
+		case wireBytes:
+			n := varint()
+			fields[field] = append(fields[field], b[:n])
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+	}
+	return fields
+}
+
+func TestWritePprof(t *testing.T) {
+	profile := profile(t, sumSource)
+
+	var out bytes.Buffer
+	if err := profile.WritePprof(&out); err != nil {
+		t.Fatalf("WritePprof failed: %s", err)
+	}
+	z, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatalf("output is not gzipped: %s", err)
+	}
+	data, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatalf("output is not gzipped: %s", err)
+	}
+
+	p := fields(t, data)
+	table := []string{}
+	for _, s := range p[profileStringTable] {
+		table = append(table, string(s.([]byte)))
+	}
+	if table[0] != "" {
+		t.Errorf("string table does not start with the empty string. got=%q", table[0])
+	}
+
+	sampleTypes := []string{}
+	for _, st := range p[profileSampleType] {
+		vt := fields(t, st.([]byte))
+		sampleTypes = append(sampleTypes, table[vt[valueTypeType][0].(uint64)]+"/"+table[vt[valueTypeUnit][0].(uint64)])
+	}
+	if strings.Join(sampleTypes, " ") != "calls/count time/nanoseconds" {
+		t.Errorf("wrong sample types. got=%v", sampleTypes)
+	}
+
+	names := []string{}
+	for _, f := range p[profileFunction] {
+		fn := fields(t, f.([]byte))
+		names = append(names, table[fn[functionName][0].(uint64)])
+		if file := table[fn[functionFilename][0].(uint64)]; file != "sum.mk" {
+			t.Errorf("wrong file name. got=%q", file)
+		}
+	}
+	if strings.Join(names, " ") != "main fn sq sum" {
+		t.Errorf("wrong functions. got=%v", names)
+	}
+
+	if len(p[profileSample]) != len(profile.Samples) {
+		t.Errorf("wrong number of samples. want=%d, got=%d", len(profile.Samples), len(p[profileSample]))
+	}
+	locations := 0
+	for _, s := range profile.LineStats() {
+		if s.Cum > 0 {
+			locations++
+		}
+	}
+	if len(p[profileLocation]) != locations {
+		t.Errorf("wrong number of locations. want=%d, got=%d", locations, len(p[profileLocation]))
+	}
+	if d := p[profileDurationNanos][0].(uint64); time.Duration(d) != profile.Duration {
+		t.Errorf("wrong duration. want=%d, got=%d", profile.Duration, d)
+	}
+}
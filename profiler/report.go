@@ -0,0 +1,176 @@
+package profiler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+/*
+What a Profiler measured.
+
+Path: The script profiled
+Functions: Every function called, main first
+Hits: How often the statements on each line ran
+*/
+type Profile struct {
+	Path      string
+	Start     time.Time
+	Duration  time.Duration
+	Functions []*Function
+	Samples   []*Sample
+	Hits      map[Location]int64
+}
+
+/*
+The totals of a function or line.
+
+Flat: Time spent in it, without the calls it made
+Cum: Time spent in it, with the calls it made
+Count: How often the function was called, or the statements of the line ran
+*/
+type Stats struct {
+	Flat  time.Duration
+	Cum   time.Duration
+	Count int64
+}
+
+// Returns the totals of every function. A recursive call only counts once
+// towards the cumulative time.
+func (p *Profile) FunctionStats() map[*Function]*Stats {
+	stats := map[*Function]*Stats{}
+	get := func(fn *Function) *Stats {
+		if stats[fn] == nil {
+			stats[fn] = &Stats{}
+		}
+		return stats[fn]
+	}
+
+	for _, s := range p.Samples {
+		top := get(s.Stack[0].Function)
+		top.Flat += s.Time
+		top.Count += s.Calls
+
+		seen := map[*Function]bool{}
+		for _, loc := range s.Stack {
+			if !seen[loc.Function] {
+				seen[loc.Function] = true
+				get(loc.Function).Cum += s.Time
+			}
+		}
+	}
+	return stats
+}
+
+// Returns the totals of every line that ran.
+func (p *Profile) LineStats() map[Location]*Stats {
+	stats := map[Location]*Stats{}
+	get := func(loc Location) *Stats {
+		if stats[loc] == nil {
+			stats[loc] = &Stats{}
+		}
+		return stats[loc]
+	}
+
+	for loc, hits := range p.Hits {
+		get(loc).Count = hits
+	}
+	for _, s := range p.Samples {
+		get(s.Stack[0]).Flat += s.Time
+
+		seen := map[Location]bool{}
+		for _, loc := range s.Stack {
+			if !seen[loc] {
+				seen[loc] = true
+				get(loc).Cum += s.Time
+			}
+		}
+	}
+	return stats
+}
+
+// Writes the functions and then the lines of the profile as tables, the
+// ones taking most time without their calls first.
+func (p *Profile) WriteTable(w io.Writer) error {
+	var out bytes.Buffer
+	var total time.Duration
+	for _, s := range p.Samples {
+		total += s.Time
+	}
+	fmt.Fprintf(&out, "Profile of %s: %s total\n\n", p.Path, formatDuration(total))
+
+	type row struct {
+		label string
+		stats *Stats
+	}
+	writeRows := func(rows []row, count, label string) {
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i].stats, rows[j].stats
+			if a.Flat != b.Flat {
+				return a.Flat > b.Flat
+			}
+			return a.Cum > b.Cum
+		})
+
+		fmt.Fprintf(&out, "%10s %7s %7s %10s %7s %9s  %s\n", "flat", "flat%", "sum%", "cum", "cum%", count, label)
+		var sum time.Duration
+		for _, r := range rows {
+			sum += r.stats.Flat
+			fmt.Fprintf(&out, "%10s %7s %7s %10s %7s %9d  %s\n",
+				formatDuration(r.stats.Flat), percent(r.stats.Flat, total), percent(sum, total),
+				formatDuration(r.stats.Cum), percent(r.stats.Cum, total), r.stats.Count, r.label)
+		}
+	}
+
+	functions := []row{}
+	stats := p.FunctionStats()
+	for _, fn := range p.Functions {
+		if s, ok := stats[fn]; ok {
+			functions = append(functions, row{fmt.Sprintf("%s %s:%d", fn.Name, p.Path, fn.Line), s})
+		}
+	}
+	writeRows(functions, "calls", "function")
+	fmt.Fprintln(&out)
+
+	lineStats := p.LineStats()
+	locations := []Location{}
+	for loc := range lineStats {
+		locations = append(locations, loc)
+	}
+	// lines with the same times stay in source order
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].Line != locations[j].Line {
+			return locations[i].Line < locations[j].Line
+		}
+		return locations[i].Function.id < locations[j].Function.id
+	})
+	lines := []row{}
+	for _, loc := range locations {
+		lines = append(lines, row{fmt.Sprintf("%s:%d %s", p.Path, loc.Line, loc.Function.Name), lineStats[loc]})
+	}
+	writeRows(lines, "hits", "line")
+
+	_, err := out.WriteTo(w)
+	return err
+}
+
+func percent(d, total time.Duration) string {
+	if total == 0 {
+		return "0.00%"
+	}
+	return fmt.Sprintf("%.2f%%", 100*float64(d)/float64(total))
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return fmt.Sprintf("%.2fs", d.Seconds())
+	case d >= time.Millisecond:
+		return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+	case d >= time.Microsecond:
+		return fmt.Sprintf("%.2fus", float64(d)/float64(time.Microsecond))
+	}
+	return fmt.Sprintf("%dns", d.Nanoseconds())
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/format"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/object"
+	"example/sawan/goInterpreter/optimizer"
+	"example/sawan/goInterpreter/parser"
+	"example/sawan/goInterpreter/profiler"
+)
+
+/*
+How run treats a script.
+
+optimize: Run the program through the optimizer before evaluating it
+dumpAST: Print the program that would be evaluated instead of running it
+trace: Log every node evaluated to stderr
+traceFunctions: Only log calls of the functions with these names
+traceParser: Log how the parser parses expressions to stderr
+cpuProfile: File to write a pprof profile of the run to
+profile: Print a profile of the run to stderr
+*/
+type runOptions struct {
+	optimize       bool
+	dumpAST        bool
+	trace          bool
+	traceFunctions []string
+	traceParser    bool
+	cpuProfile     string
+	profile        bool
+}
+
+// Evaluates the script at path and returns the exit status. Parser and
+// runtime errors are reported on stderr.
+func run(path string, opts runOptions) int {
+	var parserTrace io.Writer
+	if opts.traceParser {
+		parserTrace = os.Stderr
+	}
+	program, err := load(path, parserTrace)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if opts.optimize {
+		program = optimizer.Optimize(program)
+	}
+	if opts.dumpAST {
+		fmt.Print(format.Program(program, nil))
+		return 0
+	}
+
+	if opts.trace {
+		evaluator.SetTrace(os.Stderr, opts.traceFunctions...)
+	}
+	var prof *profiler.Profiler
+	if opts.cpuProfile != "" || opts.profile {
+		prof = profiler.Start(path)
+	}
+	evaluated := evaluator.Eval(program, object.NewEnvironment())
+
+	status := 0
+	if errObj, ok := evaluated.(*object.Error); ok {
+		printError(path, errObj)
+		status = 1
+	}
+	if prof != nil && !writeProfile(prof.Stop(), opts) {
+		status = 1
+	}
+	return status
+}
+
+// Reports an error nothing caught, where it was raised when that is known,
+// with the calls it returned from, innermost first.
+func printError(path string, err *object.Error) {
+	if err.Line > 0 {
+		fmt.Fprintf(os.Stderr, "%s:%d:%d: %s\n", path, err.Line, err.Column, err.Message)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Message)
+	}
+	for _, frame := range err.Stack {
+		fmt.Fprintf(os.Stderr, "    at %s\n", frame)
+	}
+}
+
+// Writes the profile where opts ask for it and reports whether that worked.
+func writeProfile(profile *profiler.Profile, opts runOptions) bool {
+	if opts.profile {
+		profile.WriteTable(os.Stderr)
+	}
+	return opts.cpuProfile == "" || writeReport(opts.cpuProfile, profile.WritePprof)
+}
+
+// Creates the file at path and writes a report to it with write. Errors are
+// reported on stderr; the result tells whether there were none.
+func writeReport(path string, write func(io.Writer) error) bool {
+	f, err := os.Create(path)
+	if err == nil {
+		err = write(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	return true
+}
+
+// Reads the script at path and expands its macros. The error lists every
+// parser error, one per line. The parser logs how it parses expressions to
+// parserTrace unless it is nil.
+func load(path string, parserTrace io.Writer) (*ast.Program, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	l := lexer.New(string(source))
+	p := parser.New(l)
+	p.SetTrace(parserTrace)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		lines := []string{}
+		for _, e := range p.ParseErrors() {
+			lines = append(lines, fmt.Sprintf("%s:%d:%d: %s", path, e.Token.Line, e.Token.Column, e.Message))
+		}
+		return nil, errors.New(strings.Join(lines, "\n"))
+	}
+
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, expandErr := evaluator.ExpandMacros(program, macroEnv)
+	if expandErr != nil {
+		return nil, fmt.Errorf("%s: %s", path, expandErr.Message)
+	}
+	return expanded.(*ast.Program), nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"example/sawan/goInterpreter/coverage"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/testrunner"
+)
+
+// Implements `monkey test`: runs the test_* functions of the *_test.mk files
+// given, or found below the directories given, each in an environment of
+// its own. With -cover it also reports which statements and branches of the
+// files, and of the modules they import, ran.
+func testCommand(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: monkey test [flags] [file_test.mk | directory ...]\n\n")
+		fmt.Fprintf(os.Stderr, "Runs the tests below the current directory when no files are given.\n\n")
+		flags.PrintDefaults()
+	}
+	verbose := flags.Bool("v", false, "list every test run, not only the ones that fail")
+	runPattern := flags.String("run", "", "only run the tests whose names match `regexp`")
+	junit := flags.String("junit", "", "write the results to `file` as JUnit XML")
+	cover := flags.Bool("cover", false, "print how much of each file and module ran")
+	coverProfile := flags.String("coverprofile", "", "write the coverage to `file` in the LCOV format; implies -cover")
+	coverHTML := flags.String("coverhtml", "", "write the source annotated with its coverage to `file` as HTML; implies -cover")
+	searchPath := flags.String("path", "", "directories searched by import, as for running scripts")
+	strictIndex := flags.Bool("strict-index", false, "make indexes and slice bounds outside of arrays and strings errors, as for running scripts")
+	flags.Parse(args)
+
+	if *strictIndex {
+		evaluator.SetOutOfRange(evaluator.OutOfRangeError)
+	}
+
+	var filter *regexp.Regexp
+	if *runPattern != "" {
+		var err error
+		if filter, err = regexp.Compile(*runPattern); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -run: %s\n", err)
+			return 2
+		}
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := testrunner.Discover(paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var profile *coverage.Profile
+	if *cover || *coverProfile != "" || *coverHTML != "" {
+		profile = coverage.New()
+		evaluator.SetCoverage(profile)
+		defer evaluator.SetCoverage(nil)
+	}
+
+	status := 0
+	suites := []testrunner.Suite{}
+	for _, path := range files {
+		// every test gets modules of its own too
+		reset := func() {
+			evaluator.SetModuleLoader(&evaluator.FileLoader{SearchPath: importPath(filepath.Dir(path), *searchPath)})
+		}
+		suite := testrunner.Suite{Path: path, Start: time.Now()}
+
+		reset()
+		program, err := load(path, nil)
+		if err != nil {
+			suite.Err = err
+			suites = append(suites, suite)
+			fmt.Printf("FAIL %s\n%s\n", path, indent(err.Error()))
+			status = 1
+			continue
+		}
+		if profile != nil {
+			source, _ := os.ReadFile(path)
+			profile.Add(path, string(source), program)
+		}
+
+		for _, name := range testrunner.Tests(program) {
+			if filter != nil && !filter.MatchString(name) {
+				continue
+			}
+			reset()
+			result := testrunner.Run(program, name)
+			suite.Results = append(suite.Results, result)
+
+			if result.Failure != nil {
+				fmt.Printf("--- FAIL: %s (%s)\n%s\n", name, result.Duration.Round(time.Microsecond), indent(result.Describe(path)))
+			} else if *verbose {
+				fmt.Printf("--- PASS: %s (%s)\n", name, result.Duration.Round(time.Microsecond))
+			}
+		}
+		suites = append(suites, suite)
+
+		elapsed := suite.Duration().Round(time.Microsecond)
+		switch failed := suite.Failed(); {
+		case len(suite.Results) == 0:
+			fmt.Printf("?    %s\tno tests\n", path)
+		case failed > 0:
+			fmt.Printf("FAIL %s\t%d of %d tests failed (%s)\n", path, failed, len(suite.Results), elapsed)
+			status = 1
+		default:
+			fmt.Printf("ok   %s\t%d tests (%s)\n", path, len(suite.Results), elapsed)
+		}
+	}
+
+	if *junit != "" && !writeReport(*junit, func(w io.Writer) error { return testrunner.WriteJUnit(w, suites) }) {
+		status = 1
+	}
+
+	if profile == nil {
+		return status
+	}
+	fmt.Println()
+	profile.WriteSummary(os.Stdout)
+	if *coverProfile != "" && !writeReport(*coverProfile, profile.WriteLCOV) {
+		status = 1
+	}
+	if *coverHTML != "" && !writeReport(*coverHTML, profile.WriteHTML) {
+		status = 1
+	}
+	return status
+}
+
+// Indents every line of s by four spaces, to set it apart from the test it
+// belongs to.
+func indent(s string) string {
+	return "    " + strings.ReplaceAll(s, "\n", "\n    ")
+}
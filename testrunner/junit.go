@@ -0,0 +1,149 @@
+package testrunner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/*
+The tests of one file.
+
+Err: Why the file could not be loaded, in which case it has no results
+*/
+type Suite struct {
+	Path    string
+	Start   time.Time
+	Results []Result
+	Err     error
+}
+
+// Returns how many tests of the suite failed.
+func (s Suite) Failed() int {
+	failed := 0
+	for _, r := range s.Results {
+		if r.Failure != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Returns how long the tests of the suite took together.
+func (s Suite) Duration() time.Duration {
+	var d time.Duration
+	for _, r := range s.Results {
+		d += r.Duration
+	}
+	return d
+}
+
+// Describes why the test failed, prefixed with path and, for failed
+// assertions and thrown errors, the position in it.
+func (r Result) Describe(path string) string {
+	if r.Failure.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", path, r.Failure.Line, r.Failure.Column, r.Failure.Message)
+	}
+	return fmt.Sprintf("%s: %s", path, r.Failure.Message)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.6f", d.Seconds())
+}
+
+// Writes the suites as a JUnit XML report. Failed assertions are failures
+// and other errors are errors, typed by their kind. A file that could not be loaded is
+// reported as an error of a test named after the file.
+func WriteJUnit(w io.Writer, suites []Suite) error {
+	report := junitSuites{}
+	var total time.Duration
+
+	for _, s := range suites {
+		suite := junitSuite{
+			Name:      s.Path,
+			Time:      seconds(s.Duration()),
+			Timestamp: s.Start.Format("2006-01-02T15:04:05"),
+		}
+		if s.Err != nil {
+			suite.Cases = append(suite.Cases, junitCase{
+				Name:      s.Path,
+				Classname: s.Path,
+				Time:      seconds(0),
+				Error:     &junitProblem{Message: firstLine(s.Err.Error()), Type: "load", Text: s.Err.Error()},
+			})
+			suite.Errors++
+		}
+
+		for _, r := range s.Results {
+			c := junitCase{Name: r.Name, Classname: s.Path, Time: seconds(r.Duration)}
+			if r.Failure != nil {
+				problem := &junitProblem{Message: firstLine(r.Failure.Message), Type: r.Failure.Kind, Text: r.Describe(s.Path)}
+				if r.Failure.Kind == "assertion" {
+					c.Failure = problem
+					suite.Failures++
+				} else {
+					c.Error = problem
+					suite.Errors++
+				}
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		total += s.Duration()
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = seconds(total)
+
+	out, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, out)
+	return err
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+// Package testrunner finds and runs the tests of Monkey programs: the
+// functions called test_* in files called *_test.mk.
+package testrunner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/object"
+)
+
+// Returns the test files among paths, with the directories replaced by the
+// test files anywhere below them, in order. Hidden directories are skipped.
+func Discover(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		found := []string{}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), "_test.mk") {
+				found = append(found, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// Returns the names of the tests of program, the functions its top level
+// binds to names starting with test_, in order.
+func Tests(program *ast.Program) []string {
+	names := []string{}
+	for _, stmt := range program.Statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || !strings.HasPrefix(let.Name.Value, "test_") {
+			continue
+		}
+		if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+			names = append(names, let.Name.Value)
+		}
+	}
+	return names
+}
+
+/*
+The outcome of one test.
+
+Failure: Why the test failed, nil when it passed. Its kind is assertion when
+a failed assertion made it fail.
+*/
+type Result struct {
+	Name     string
+	Duration time.Duration
+	Failure  *object.Error
+}
+
+// Runs the test called name in a fresh environment: program runs first, so
+// nothing one test does is seen by the next, then the test is called
+// without arguments.
+func Run(program *ast.Program, name string) Result {
+	start := time.Now()
+	result := Result{Name: name, Failure: run(program, name)}
+	result.Duration = time.Since(start)
+	return result
+}
+
+func run(program *ast.Program, name string) *object.Error {
+	env := object.NewEnvironment()
+	if err, ok := evaluator.Eval(program, env).(*object.Error); ok {
+		return err
+	}
+
+	test, ok := env.Get(name)
+	if !ok {
+		return &object.Error{Message: "test not found: " + name, Kind: "runtime"}
+	}
+	if err, ok := evaluator.Call(test).(*object.Error); ok {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package testrunner
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/object"
+	"example/sawan/goInterpreter/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b_test.mk", "a_test.mk", "lib.mk", "sub/c_test.mk", ".git/d_test.mk"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := Discover([]string{filepath.Join(dir, "lib.mk"), dir})
+	if err != nil {
+		t.Fatalf("Discover failed: %s", err)
+	}
+	want := []string{"lib.mk", "a_test.mk", "b_test.mk", "sub/c_test.mk"}
+	for i := range want {
+		want[i] = filepath.Join(dir, want[i])
+	}
+	if strings.Join(files, " ") != strings.Join(want, " ") {
+		t.Errorf("wrong files.\nwant=%v\ngot= %v", want, files)
+	}
+
+	if _, err := Discover([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Errorf("no error for a missing path")
+	}
+}
+
+func TestTests(t *testing.T) {
+	program := parse(t, `
+let test_one = fn() { 1 };
+export let test_two = fn() { 2 };
+let test_value = 3;
+let helper = fn() { 4 };
+let test_three = fn() { 5 };
+`)
+
+	if got := strings.Join(Tests(program), " "); got != "test_one test_two test_three" {
+		t.Errorf("wrong tests. got=%q", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	stdout := evaluator.Stdout
+	evaluator.Stdout = &out
+	defer func() { evaluator.Stdout = stdout }()
+
+	program := parse(t, `
+puts("setup");
+let test_pass = fn() { assert(true) };
+let test_fail = fn() {
+  assert_eq(1 + 1, 3)
+};
+let test_crash = fn() { 1 + true };
+`)
+
+	tests := []struct {
+		name    string
+		failure string
+		line    int
+	}{
+		{"test_pass", "", 0},
+		{"test_fail", "assert_eq failed", 5},
+		{"test_crash", "type mismatch: INTEGER + BOOLEAN", 0},
+		{"test_missing", "test not found: test_missing", 0},
+	}
+
+	for _, tt := range tests {
+		result := Run(program, tt.name)
+		if result.Name != tt.name {
+			t.Errorf("wrong name. want=%s, got=%s", tt.name, result.Name)
+		}
+		if tt.failure == "" {
+			if result.Failure != nil {
+				t.Errorf("%s failed: %s", tt.name, result.Failure.Message)
+			}
+			continue
+		}
+		if result.Failure == nil {
+			t.Errorf("%s passed", tt.name)
+			continue
+		}
+		if !strings.HasPrefix(result.Failure.Message, tt.failure) || result.Failure.Line != tt.line {
+			t.Errorf("%s failed wrongly. want=%d: %s, got=%d: %s", tt.name, tt.line, tt.failure, result.Failure.Line, result.Failure.Message)
+		}
+	}
+
+	// the program runs afresh for every test
+	if got := strings.Count(out.String(), "setup"); got != len(tests) {
+		t.Errorf("program ran %d times, want %d", got, len(tests))
+	}
+
+	if result := Run(parse(t, `1 + true; let test_x = fn() { 1 };`), "test_x"); result.Failure == nil {
+		t.Errorf("test passed although its program failed")
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	start := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	suites := []Suite{
+		{
+			Path:  "math_test.mk",
+			Start: start,
+			Results: []Result{
+				{Name: "test_add", Duration: 1500 * time.Microsecond},
+				{Name: "test_sub", Duration: 2 * time.Millisecond, Failure: &object.Error{Message: "assert_eq failed\n  expected: 1", Kind: "assertion", Line: 3, Column: 5}},
+				{Name: "test_div", Duration: time.Millisecond, Failure: &object.Error{Message: "division by zero", Kind: "runtime"}},
+			},
+		},
+		{Path: "broken_test.mk", Start: start, Err: errors.New("broken_test.mk:1:1: no prefix parse function for ; found")},
+	}
+
+	var out bytes.Buffer
+	if err := WriteJUnit(&out, suites); err != nil {
+		t.Fatalf("WriteJUnit failed: %s", err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="4" failures="1" errors="2" time="0.004500">
+  <testsuite name="math_test.mk" tests="3" failures="1" errors="1" time="0.004500" timestamp="2024-05-06T07:08:09">
+    <testcase name="test_add" classname="math_test.mk" time="0.001500"></testcase>
+    <testcase name="test_sub" classname="math_test.mk" time="0.002000">
+      <failure message="assert_eq failed" type="assertion"><![CDATA[math_test.mk:3:5: assert_eq failed
+  expected: 1]]></failure>
+    </testcase>
+    <testcase name="test_div" classname="math_test.mk" time="0.001000">
+      <error message="division by zero" type="runtime"><![CDATA[math_test.mk: division by zero]]></error>
+    </testcase>
+  </testsuite>
+  <testsuite name="broken_test.mk" tests="1" failures="0" errors="1" time="0.000000" timestamp="2024-05-06T07:08:09">
+    <testcase name="broken_test.mk" classname="broken_test.mk" time="0.000000">
+      <error message="broken_test.mk:1:1: no prefix parse function for ; found" type="load"><![CDATA[broken_test.mk:1:1: no prefix parse function for ; found]]></error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if out.String() != want {
+		t.Errorf("wrong report.\nwant=\n%s\ngot=\n%s", want, out.String())
+	}
+}
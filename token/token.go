@@ -2,17 +2,38 @@ package token
 
 type TokenType string
 
+// Line and Column are 1-based and point at the first byte of the token in
+// the source. Tokens built outside the lexer leave them at zero. Length is
+// the number of bytes of a string in the source, which differs from its
+// Literal once escape sequences are replaced, and is zero for the rest.
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int
+	Column  int
+	Length  int
+}
+
+// The number of bytes the token takes up in the source. Strings the lexer
+// did not make are counted as their literal and quotes.
+func (t Token) Width() int {
+	if t.Length > 0 {
+		return t.Length
+	}
+	if t.Type == STRING {
+		return len(t.Literal) + 2
+	}
+	return len(t.Literal)
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT"
 
 	IDENT = "IDENT"
 	INT   = "INT"
+	FLOAT = "FLOAT"
 
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -42,6 +63,13 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	IMPORT   = "IMPORT"
+	MACRO    = "MACRO"
+	EXPORT   = "EXPORT"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
 
 	STRING = "STRING"
 
@@ -52,13 +80,20 @@ const (
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"true":    TRUE,
+	"false":   FALSE,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"import":  IMPORT,
+	"macro":   MACRO,
+	"export":  EXPORT,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -0,0 +1,513 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/token"
+)
+
+// Line and Column locate the token the diagnostic is about, which is Length
+// bytes long.
+type Diagnostic struct {
+	Line    int
+	Column  int
+	Length  int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Message)
+}
+
+// Checks program and returns the problems found, ordered by position.
+// Values the checker cannot follow get type Any, so unannotated code is only
+// reported when the error is certain to happen once it runs.
+func Check(program *ast.Program) []Diagnostic {
+	c := &checker{}
+	c.checkScope(newScope(nil), program.Statements)
+
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		a, b := c.diagnostics[i], c.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.diagnostics
+}
+
+// Result types of the builtins that always return the same kind of value.
+var builtinResults = map[string]Type{
+	"len":            Int,
+	"index_of":       Int,
+	"puts":           Null,
+	"assert":         Null,
+	"assert_eq":      Null,
+	"assert_error":   Null,
+	"error":          &Hash{String, Any},
+	"upper":          String,
+	"lower":          String,
+	"trim":           String,
+	"join":           String,
+	"replace":        String,
+	"repeat":         String,
+	"format":         String,
+	"json_stringify": String,
+	"contains":       Bool,
+	"starts_with":    Bool,
+	"ends_with":      Bool,
+	"has":            Bool,
+	"any":            Bool,
+	"all":            Bool,
+	"sqrt":           Float,
+	"sin":            Float,
+	"cos":            Float,
+	"tan":            Float,
+	"log":            Float,
+	"exp":            Float,
+	"split":          &Array{String},
+	"chars":          &Array{String},
+}
+
+type checker struct {
+	diagnostics []Diagnostic
+}
+
+func (c *checker) report(tok token.Token, format string, a ...interface{}) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Length:  tok.Width(),
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+/*
+The names of the program or of one function. if blocks share the scope of
+the function around them, as they share its environment.
+
+types: The type of the current binding of every name bound so far
+ever: Every type a name was bound to, joined. Functions defined in the scope
+can run after any of the bindings, so they see this one.
+result: The annotated return type of the function, nil when there is none
+functions: Function literals whose bodies are checked once the scope is
+*/
+type scope struct {
+	parent    *scope
+	types     map[string]Type
+	ever      map[string]Type
+	result    Type
+	functions []*ast.FunctionLiteral
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent, types: map[string]Type{}, ever: map[string]Type{}}
+}
+
+func (s *scope) lookup(name string) (Type, bool) {
+	if t, ok := s.types[name]; ok {
+		return t, true
+	}
+	for outer := s.parent; outer != nil; outer = outer.parent {
+		if t, ok := outer.ever[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (s *scope) bind(name string, t Type) {
+	s.types[name] = t
+	if before, ok := s.ever[name]; ok {
+		t = join(before, t)
+	}
+	s.ever[name] = t
+}
+
+// Checks statements in s, then the functions defined in them.
+func (c *checker) checkScope(s *scope, statements []ast.Statement) Type {
+	result := c.statements(statements, s)
+
+	for len(s.functions) > 0 {
+		fn := s.functions[0]
+		s.functions = s.functions[1:]
+		c.function(fn, s)
+	}
+	return result
+}
+
+func (c *checker) function(fn *ast.FunctionLiteral, outer *scope) {
+	s := newScope(outer)
+	if fn.ReturnType != nil {
+		s.result = c.annotation(fn.ReturnType)
+	}
+	for _, param := range fn.Parameters {
+		s.bind(param.Value, c.binding(param))
+	}
+	if fn.Body == nil {
+		return
+	}
+
+	result := c.checkScope(s, fn.Body.Statements)
+
+	// the value of the last statement is returned too
+	if s.result != nil && result != nil && !Assignable(result, s.result) {
+		tok := fn.Body.Token
+		if n := len(fn.Body.Statements); n > 0 {
+			tok = ast.TokenOf(fn.Body.Statements[n-1])
+		}
+		c.report(tok, "cannot use %s as %s in return", result, s.result)
+	}
+}
+
+// The declared type of a let name or parameter, Any when it has none.
+func (c *checker) binding(ident *ast.Identifier) Type {
+	if ident.Type == nil {
+		return Any
+	}
+	return c.annotation(ident.Type)
+}
+
+func (c *checker) annotation(t ast.TypeExpression) Type {
+	switch t := t.(type) {
+	case *ast.NamedType:
+		switch t.Name {
+		case "int":
+			return Int
+		case "float":
+			return Float
+		case "string":
+			return String
+		case "bool":
+			return Bool
+		case "null":
+			return Null
+		case "any":
+			return Any
+		case "array":
+			return &Array{Any}
+		case "hash":
+			return &Hash{Any, Any}
+		case "fn":
+			return &Function{Return: Any}
+		}
+		c.report(t.Token, "unknown type %s", t.Name)
+
+	case *ast.ArrayType:
+		return &Array{c.annotation(t.Element)}
+
+	case *ast.HashType:
+		return &Hash{c.annotation(t.Key), c.annotation(t.Value)}
+
+	case *ast.FunctionType:
+		fn := &Function{Params: []Type{}, Return: Any}
+		for _, param := range t.Parameters {
+			fn.Params = append(fn.Params, c.annotation(param))
+		}
+		if t.Return != nil {
+			fn.Return = c.annotation(t.Return)
+		}
+		return fn
+	}
+	return Any
+}
+
+// Checks statements run one after the other and returns the type of the
+// value of the last one. That is nil when the last one is a return, as no
+// value comes out of the block then.
+func (c *checker) statements(statements []ast.Statement, s *scope) Type {
+	var result Type = Null
+
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			c.let(stmt, s)
+			result = Any
+
+		case *ast.ExportStatement:
+			if stmt.Statement != nil {
+				c.let(stmt.Statement, s)
+			}
+			result = Any
+
+		case *ast.ReturnStatement:
+			t := c.expression(stmt.ReturnValue, s)
+			if s.result != nil && !Assignable(t, s.result) {
+				c.report(ast.TokenOf(stmt.ReturnValue), "cannot use %s as %s in return", t, s.result)
+			}
+			result = nil
+
+		case *ast.ThrowStatement:
+			c.expression(stmt.Value, s)
+			result = nil
+
+		case *ast.ExpressionStatement:
+			result = c.expression(stmt.Expression, s)
+		}
+	}
+	return result
+}
+
+func (c *checker) let(stmt *ast.LetStatement, s *scope) {
+	t := c.expression(stmt.Value, s)
+	if stmt.Name == nil {
+		return
+	}
+
+	if stmt.Name.Type != nil {
+		declared := c.annotation(stmt.Name.Type)
+		if !Assignable(t, declared) {
+			c.report(ast.TokenOf(stmt.Value), "cannot use %s as %s in let %s", t, declared, stmt.Name.Value)
+		}
+		t = declared
+	}
+	s.bind(stmt.Name.Value, t)
+}
+
+func (c *checker) expression(exp ast.Expression, s *scope) Type {
+	switch exp := exp.(type) {
+
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+
+	case *ast.Identifier:
+		if t, ok := s.lookup(exp.Value); ok {
+			return t
+		}
+
+	case *ast.PrefixExpression:
+		right := c.expression(exp.Right, s)
+		t, err := prefix(exp.Operator, right)
+		if err != "" {
+			c.report(exp.Token, "%s", err)
+		}
+		return t
+
+	case *ast.InfixExpression:
+		left := c.expression(exp.Left, s)
+		right := c.expression(exp.Right, s)
+		t, err := infix(exp.Operator, left, right)
+		if err != "" {
+			c.report(exp.Token, "%s", err)
+		}
+		return t
+
+	case *ast.IfExpression:
+		return c.ifExpression(exp, s)
+
+	case *ast.TryExpression:
+		return c.tryExpression(exp, s)
+
+	case *ast.FunctionLiteral:
+		fn := &Function{Params: []Type{}, Return: Any}
+		for _, param := range exp.Parameters {
+			fn.Params = append(fn.Params, c.binding(param))
+		}
+		if exp.ReturnType != nil {
+			fn.Return = c.annotation(exp.ReturnType)
+		}
+		s.functions = append(s.functions, exp)
+		return fn
+
+	case *ast.CallExpression:
+		return c.call(exp, s)
+
+	case *ast.ArrayLiteral:
+		var element Type
+		for _, el := range exp.Elements {
+			element = join(element, c.expression(el, s))
+		}
+		if element == nil {
+			element = Any
+		}
+		return &Array{element}
+
+	case *ast.HashLiteral:
+		var key, value Type
+		for _, pair := range exp.Pairs {
+			key = join(key, c.expression(pair.Key, s))
+			value = join(value, c.expression(pair.Value, s))
+		}
+		if key == nil {
+			key, value = Any, Any
+		}
+		return &Hash{key, value}
+
+	case *ast.IndexExpression:
+		left := c.expression(exp.Left, s)
+		t, err := index(left, c.expression(exp.Index, s))
+		if err != "" {
+			c.report(exp.Token, "%s", err)
+		}
+		return t
+
+	case *ast.SliceExpression:
+		left := c.expression(exp.Left, s)
+		c.expression(exp.Start, s)
+		c.expression(exp.End, s)
+		switch left.(type) {
+		case *Array:
+			return left
+		}
+		if left == String {
+			return String
+		}
+
+	case *ast.ImportExpression:
+		return &Hash{String, Any}
+	}
+
+	return Any
+}
+
+// The branches run in the scope around the if, so a name they bind has one
+// type after it only when both branches agree on it.
+func (c *checker) ifExpression(exp *ast.IfExpression, s *scope) Type {
+	c.expression(exp.Condition, s)
+
+	before := copyTypes(s.types)
+	consequence := c.block(exp.Consequence, s)
+	afterConsequence := s.types
+
+	s.types = copyTypes(before)
+	var alternative Type = Any
+	if exp.Alternative != nil {
+		alternative = c.block(exp.Alternative, s)
+	}
+	afterAlternative := s.types
+
+	s.types = map[string]Type{}
+	for name, t := range afterConsequence {
+		if other, ok := afterAlternative[name]; ok && identical(t, other) {
+			s.types[name] = t
+		} else {
+			s.types[name] = Any
+		}
+	}
+	for name := range afterAlternative {
+		if _, ok := s.types[name]; !ok {
+			s.types[name] = Any
+		}
+	}
+
+	// without an else the value is null when the condition is false
+	if exp.Alternative == nil {
+		return Any
+	}
+	return join(consequence, alternative)
+}
+
+// An error can cut the try block short, and the catch block after it, so a
+// name they bind may still have its type from before them, or none at all.
+func (c *checker) tryExpression(exp *ast.TryExpression, s *scope) Type {
+	before := copyTypes(s.types)
+	result := c.block(exp.Block, s)
+	if exp.Catch != nil {
+		s.bind(exp.Param.Value, &Hash{String, Any})
+		result = join(result, c.block(exp.Catch, s))
+	}
+
+	for name, t := range s.types {
+		if old, ok := before[name]; !ok || !identical(old, t) {
+			s.types[name] = Any
+		}
+	}
+
+	c.block(exp.Finally, s)
+	if result == nil {
+		return Any
+	}
+	return result
+}
+
+func (c *checker) block(block *ast.BlockStatement, s *scope) Type {
+	if block == nil {
+		return Null
+	}
+	return c.statements(block.Statements, s)
+}
+
+func copyTypes(types map[string]Type) map[string]Type {
+	copied := map[string]Type{}
+	for name, t := range types {
+		copied[name] = t
+	}
+	return copied
+}
+
+func (c *checker) call(call *ast.CallExpression, s *scope) Type {
+	ident, isIdent := call.Function.(*ast.Identifier)
+	if isIdent && ident.Value == "quote" {
+		c.quote(call, s)
+		return Any
+	}
+
+	callee := c.expression(call.Function, s)
+	args := []Type{}
+	for _, arg := range call.Arguments {
+		args = append(args, c.expression(arg, s))
+	}
+
+	if isIdent {
+		if _, bound := s.lookup(ident.Value); !bound && evaluator.IsPredeclared(ident.Value) {
+			if t, ok := builtinResults[ident.Value]; ok {
+				return t
+			}
+			return Any
+		}
+	}
+
+	switch fn := callee.(type) {
+	case *Function:
+		if fn.Params == nil {
+			return fn.Return
+		}
+		if len(args) != len(fn.Params) {
+			c.report(call.Token, "wrong number of arguments. got=%d, want=%d", len(args), len(fn.Params))
+			return fn.Return
+		}
+		for i, arg := range args {
+			if !Assignable(arg, fn.Params[i]) {
+				c.report(ast.TokenOf(call.Arguments[i]), "cannot use %s as %s in argument %d to %s",
+					arg, fn.Params[i], i+1, call.Function.String())
+			}
+		}
+		return fn.Return
+
+	case *Basic:
+		if fn == Any {
+			return Any
+		}
+	}
+
+	c.report(call.Token, "not a function: %s", runtimeType(callee))
+	return Any
+}
+
+// Quoted code is not run, apart from the arguments of unquote.
+func (c *checker) quote(call *ast.CallExpression, s *scope) {
+	for _, arg := range call.Arguments {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			unquote, ok := node.(*ast.CallExpression)
+			if !ok {
+				return true
+			}
+			if ident, ok := unquote.Function.(*ast.Identifier); !ok || ident.Value != "unquote" {
+				return true
+			}
+			for _, arg := range unquote.Arguments {
+				c.expression(arg, s)
+			}
+			return false
+		})
+	}
+}
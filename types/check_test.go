@@ -0,0 +1,146 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"example/sawan/goInterpreter/ast"
+	"example/sawan/goInterpreter/evaluator"
+	"example/sawan/goInterpreter/lexer"
+	"example/sawan/goInterpreter/object"
+	"example/sawan/goInterpreter/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`"a" - 1`, []string{"1:5: type mismatch: STRING - INTEGER"}},
+		{`"a" * "b"`, []string{"1:5: unknown operator: STRING * STRING"}},
+		{`true + false`, []string{"1:6: unknown operator: BOOLEAN + BOOLEAN"}},
+		{`-"a"; !"a"`, []string{"1:1: unknown operator: -STRING"}},
+		{`[1][true]; "s"[0]; {1: 2}["x"]; 5[0]`, []string{
+			"1:4: index operator not supported: ARRAY",
+			"1:34: index operator not supported: INTEGER",
+		}},
+		{`5(1)`, []string{"1:2: not a function: INTEGER"}},
+
+		// inference through lets, arrays, hashes and builtins
+		{`let x = 1.5 * 2; let y = x + "s";`, []string{"1:28: type mismatch: FLOAT + STRING"}},
+		{`let a = [1, 2]; a[0] - "s"`, []string{"1:22: type mismatch: INTEGER - STRING"}},
+		{`let h = {"a": "b"}; h["a"] - 1`, []string{"1:28: type mismatch: STRING - INTEGER"}},
+		{`len("abc") + "x"; upper("a") - 1`, []string{"1:12: type mismatch: INTEGER + STRING", "1:30: type mismatch: STRING - INTEGER"}},
+
+		// annotations
+		{`let x: int = "five";`, []string{"1:14: cannot use string as int in let x"}},
+		{`let x: int = 1.5;`, []string{"1:14: cannot use float as int in let x"}},
+		{`let x: float = 1; let xs: [float] = [1, 2]; let f = fn(y: float) { y }; f(2)`, []string{}},
+		{`let xs: [int] = ["a"]; let ys: [int] = [1, "a"];`, []string{"1:17: cannot use [string] as [int] in let xs"}},
+		{`let f = fn(a: int, b: string): bool { a }; f(1, 2); f(1)`, []string{
+			"1:39: cannot use int as bool in return",
+			"1:49: cannot use int as string in argument 2 to f",
+			"1:54: wrong number of arguments. got=1, want=2",
+		}},
+		{`let f = fn(n: int): string { if (n > 0) { return n; } "none" };`, []string{"1:50: cannot use int as string in return"}},
+		{`let f = fn(s: string) { s - 1 };`, []string{"1:27: type mismatch: STRING - INTEGER"}},
+		{`let apply = fn(f: fn(int): int, x: int): int { f(x) }; apply(fn(s: string) { s }, 1)`, []string{
+			"1:62: cannot use fn(string): any as fn(int): int in argument 1 to apply",
+		}},
+		{`let x: number = 1;`, []string{"1:8: unknown type number"}},
+
+		// closures see every type a name has had
+		{`let x = 1; let f = fn() { x - 1 }; let x = "s";`, []string{}},
+		{`let x = "s"; let f = fn() { x - 1 };`, []string{"1:31: type mismatch: STRING - INTEGER"}},
+
+		// errors
+		{`try { 1 } catch (e) { e - 1 }`, []string{"1:25: type mismatch: HASH - INTEGER"}},
+		{`let x = try { 1 } catch (e) { 2 }; x + "s"`, []string{"1:38: type mismatch: INTEGER + STRING"}},
+		{`let x = 1; try { let x = "s"; f() } catch (e) { 0 } x - 1`, []string{}},
+		{`let f = fn(n: int): int { if (n < 0) { throw error("negative") } n }; throw 1 - "a"`, []string{"1:79: type mismatch: INTEGER - STRING"}},
+	}
+
+	for _, tt := range tests {
+		diagnostics := Check(parse(t, tt.input))
+
+		got := []string{}
+		for _, d := range diagnostics {
+			got = append(got, d.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("wrong diagnostics for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// Code the checker cannot follow must not be reported.
+func TestCheckUnknownTypes(t *testing.T) {
+	inputs := []string{
+		`let f = fn(a, b) { a + b }; f(1, 2); f("a", "b");`,
+		`let x = 1; if (c) { let x = "s"; } x - 1;`,
+		`let x = if (c) { 1 } else { "s" }; x - 1;`,
+		`let x = if (c) { 1 }; x + 1;`,
+		`let g = fn(x) { x }; g(1) + "s";`,
+		`let m = import "lib.mk"; m["f"](1) - 2;`,
+		`quote(1 - "a" + unquote(2 + 3));`,
+		`let h = {}; h["a"] + 1; [][0] - 1;`,
+		`let f = fn(n: int): int { if (n < 2) { return n; } f(n - 1) + f(n - 2) };`,
+		`let f = fn(): fn(int): int { fn(x) { x } }; f()(1) + 1;`,
+	}
+
+	for _, input := range inputs {
+		if diagnostics := Check(parse(t, input)); len(diagnostics) != 0 {
+			t.Errorf("unexpected diagnostics for %q: %v", input, diagnostics)
+		}
+	}
+}
+
+// The checker must find exactly the errors the evaluator gives for operators
+// applied to values of each type.
+func TestOperatorsMatchEvaluator(t *testing.T) {
+	values := []string{"2", "1.5", `"s"`, "true", "[1]", `{"a": 1}`, "fn() { 1 }"}
+	operators := []string{"+", "-", "*", "/", "<", ">", "==", "!="}
+
+	for _, left := range values {
+		for _, right := range values {
+			for _, op := range operators {
+				assertSameError(t, "("+left+") "+op+" ("+right+")")
+			}
+		}
+		assertSameError(t, "-("+left+")")
+		assertSameError(t, "!("+left+")")
+		assertSameError(t, "("+left+")[0]")
+	}
+}
+
+func assertSameError(t *testing.T, input string) {
+	t.Helper()
+
+	var runtimeErr string
+	if err, ok := evaluator.Eval(parse(t, input), object.NewEnvironment()).(*object.Error); ok {
+		runtimeErr = err.Message
+	}
+
+	var checkErr string
+	if diagnostics := Check(parse(t, input)); len(diagnostics) > 0 {
+		checkErr = diagnostics[0].Message
+	}
+
+	// out of range and missing keys depend on the values, not their types
+	if strings.HasPrefix(runtimeErr, "index out of range") {
+		runtimeErr = ""
+	}
+	if runtimeErr != checkErr {
+		t.Errorf("%s: evaluator gives %q, checker %q", input, runtimeErr, checkErr)
+	}
+}
@@ -0,0 +1,214 @@
+// Package types checks Monkey programs for operations that fail at run time
+// because of the types of their operands, using the optional annotations of
+// lets, parameters and functions and what can be inferred without them.
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"example/sawan/goInterpreter/object"
+)
+
+// The type of a value as far as the checker can tell. Any stands for values
+// it knows nothing about and goes with every other type.
+type Type interface {
+	String() string
+}
+
+// Types without parts. runtime is what the Type method of their values
+// returns, used in the same error messages as the evaluator's.
+type Basic struct {
+	name    string
+	runtime object.ObjectType
+}
+
+func (b *Basic) String() string { return b.name }
+
+var (
+	Int    = &Basic{"int", object.INTEGER_OBJ}
+	Float  = &Basic{"float", object.FLOAT_OBJ}
+	String = &Basic{"string", object.STRING_OBJ}
+	Bool   = &Basic{"bool", object.BOOLEAN_OBJ}
+	Null   = &Basic{"null", object.NULL_OBJ}
+	Any    = &Basic{"any", ""}
+)
+
+type Array struct {
+	Element Type
+}
+
+func (a *Array) String() string { return "[" + a.Element.String() + "]" }
+
+type Hash struct {
+	Key   Type
+	Value Type
+}
+
+func (h *Hash) String() string { return "{" + h.Key.String() + ": " + h.Value.String() + "}" }
+
+// Params is nil for functions whose parameters are not known.
+type Function struct {
+	Params []Type
+	Return Type
+}
+
+func (f *Function) String() string {
+	if f.Params == nil {
+		return "fn"
+	}
+
+	params := []string{}
+	for _, p := range f.Params {
+		params = append(params, p.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + "): " + f.Return.String()
+}
+
+// The type name the evaluator uses in errors about values of type t.
+func runtimeType(t Type) object.ObjectType {
+	switch t := t.(type) {
+	case *Basic:
+		return t.runtime
+	case *Array:
+		return object.ARRAY_OBJ
+	case *Hash:
+		return object.HASH_OBJ
+	case *Function:
+		return object.FUNCTION_OBJ
+	}
+	return ""
+}
+
+func identical(a, b Type) bool {
+	return a.String() == b.String()
+}
+
+// Reports whether a value of type value can be used where target is
+// expected. Integers go where floats do, as the evaluator takes both
+// wherever it takes floats.
+func Assignable(value, target Type) bool {
+	if value == Any || target == Any {
+		return true
+	}
+
+	switch target := target.(type) {
+	case *Array:
+		v, ok := value.(*Array)
+		return ok && Assignable(v.Element, target.Element)
+
+	case *Hash:
+		v, ok := value.(*Hash)
+		return ok && Assignable(v.Key, target.Key) && Assignable(v.Value, target.Value)
+
+	case *Function:
+		v, ok := value.(*Function)
+		if !ok {
+			return false
+		}
+		if v.Params == nil || target.Params == nil {
+			return true
+		}
+		if len(v.Params) != len(target.Params) {
+			return false
+		}
+		for i := range v.Params {
+			if !Assignable(target.Params[i], v.Params[i]) {
+				return false
+			}
+		}
+		return Assignable(v.Return, target.Return)
+	}
+
+	return value == target || value == Int && target == Float
+}
+
+// The type of a value that is one of a or b. A nil type stands for no value
+// at all, as for a block that always returns.
+func join(a, b Type) Type {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case identical(a, b):
+		return a
+	}
+	return Any
+}
+
+func isNumeric(t Type) bool {
+	return t == Int || t == Float
+}
+
+// The result of an infix operator, following the rules of the evaluator's
+// evalInfixExpression. Returns the error the evaluator would give when the
+// operands can never work together.
+func infix(operator string, left, right Type) (Type, string) {
+	comparison := operator == "<" || operator == ">" || operator == "==" || operator == "!="
+
+	if left == Any || right == Any {
+		if comparison {
+			return Bool, ""
+		}
+		return Any, ""
+	}
+
+	switch {
+	case left == Int && right == Int:
+		if comparison {
+			return Bool, ""
+		}
+		return Int, ""
+	case isNumeric(left) && isNumeric(right):
+		if comparison {
+			return Bool, ""
+		}
+		return Float, ""
+	case left == String && right == String:
+		if comparison {
+			return Bool, ""
+		}
+		if operator == "+" {
+			return String, ""
+		}
+
+	case operator == "==" || operator == "!=":
+		return Bool, ""
+	case runtimeType(left) != runtimeType(right):
+		return Any, fmt.Sprintf("type mismatch: %s %s %s", runtimeType(left), operator, runtimeType(right))
+	}
+
+	return Any, fmt.Sprintf("unknown operator: %s %s %s", runtimeType(left), operator, runtimeType(right))
+}
+
+// The result of a prefix operator, following evalPrefixExpression.
+func prefix(operator string, right Type) (Type, string) {
+	switch {
+	case operator == "!":
+		return Bool, ""
+	case right == Any || right == Int || right == Float:
+		return right, ""
+	}
+	return Any, fmt.Sprintf("unknown operator: -%s", runtimeType(right))
+}
+
+// The result of indexing left with index, following evalIndexExpression.
+func index(left, index Type) (Type, string) {
+	switch left := left.(type) {
+	case *Array:
+		if index == Int || index == Any {
+			return left.Element, ""
+		}
+	case *Hash:
+		return left.Value, ""
+	}
+
+	switch {
+	case left == Any:
+		return Any, ""
+	case left == String && (index == Int || index == Any):
+		return String, ""
+	}
+	return Any, fmt.Sprintf("index operator not supported: %s", runtimeType(left))
+}